@@ -0,0 +1,79 @@
+// Command kanthorkv-server serves a KanthorKV database directory over the network.
+//
+//	kanthorkv-server -pg 127.0.0.1:5432 -http 127.0.0.1:8080 ./data
+//	psql -h 127.0.0.1 -p 5432
+//	curl -d '{"sql": "select a from t"}' http://127.0.0.1:8080/sql
+//
+// An empty address disables its listener.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kanthorlabs/kanthorkv"
+	"github.com/kanthorlabs/kanthorkv/server/httpapi"
+	"github.com/kanthorlabs/kanthorkv/server/pgwire"
+)
+
+func main() {
+	opts := kanthorkv.DefaultOptions()
+	flag.IntVar(&opts.BlockSize, "block-size", opts.BlockSize, "size of each disk block in bytes")
+	flag.IntVar(&opts.NumBuffers, "buffers", opts.NumBuffers, "number of pages in the buffer pool")
+	flag.DurationVar(&opts.BufferTimeout, "buffer-timeout", opts.BufferTimeout, "how long to wait for an unpinned buffer")
+	flag.DurationVar(&opts.LockTimeout, "lock-timeout", opts.LockTimeout, "how long to wait for a lock")
+	pgaddr := flag.String("pg", "127.0.0.1:5432", "address of the PostgreSQL wire protocol listener")
+	httpaddr := flag.String("http", "", "address of the HTTP/JSON API listener")
+	idleTimeout := flag.Duration("tx-idle-timeout", httpapi.DEFAULT_TX_IDLE_TIMEOUT, "how long an HTTP transaction may stay unused before it is rolled back")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <dir>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := kanthorkv.Open(flag.Arg(0), opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *pgaddr == "" && *httpaddr == "" {
+		fmt.Fprintln(os.Stderr, "nothing to serve, set -pg or -http")
+		os.Exit(2)
+	}
+
+	errs := make(chan error, 2)
+	pg := pgwire.NewServer(db)
+	if *pgaddr != "" {
+		go func() { errs <- pg.ListenAndServe(*pgaddr) }()
+	}
+	api := httpapi.NewServer(db, *idleTimeout)
+	hs := &http.Server{Addr: *httpaddr, Handler: api}
+	if *httpaddr != "" {
+		go func() { errs <- hs.ListenAndServe() }()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	var failure error
+	select {
+	case <-signals:
+	case failure = <-errs:
+	}
+
+	if err := errors.Join(failure, pg.Close(), hs.Close(), api.Close(), db.Close()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,57 @@
+// Command kanthorkv is an interactive SQL shell for a KanthorKV database directory.
+//
+//	kanthorkv ./data
+//	kanthorkv ./data < fixtures.sql
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kanthorlabs/kanthorkv"
+)
+
+func main() {
+	opts := kanthorkv.DefaultOptions()
+	flag.IntVar(&opts.BlockSize, "block-size", opts.BlockSize, "size of each disk block in bytes")
+	flag.IntVar(&opts.NumBuffers, "buffers", opts.NumBuffers, "number of pages in the buffer pool")
+	flag.DurationVar(&opts.BufferTimeout, "buffer-timeout", opts.BufferTimeout, "how long to wait for an unpinned buffer")
+	flag.DurationVar(&opts.LockTimeout, "lock-timeout", opts.LockTimeout, "how long to wait for a lock")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <dir>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := kanthorkv.Open(flag.Arg(0), opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	sh := NewShell(db, os.Stdout, isTerminal(os.Stdin))
+	failures := sh.Run(os.Stdin)
+
+	if err := db.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// a script that did not run cleanly must be noticed by whoever seeds the fixtures
+	if failures > 0 && !sh.interactive {
+		os.Exit(1)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
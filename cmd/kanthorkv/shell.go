@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kanthorlabs/kanthorkv"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// catalogs are the tables the metadata manager keeps for itself
+var catalogs = []string{"tblcat", "fldcat", "viewcat", "idxcat"}
+
+var errQuit = errors.New("quit")
+
+// NewShell creates a shell that writes results to out.
+// An interactive shell prints prompts, a non interactive one is running a script.
+func NewShell(db *kanthorkv.DB, out io.Writer, interactive bool) *Shell {
+	return &Shell{db: db, out: out, interactive: interactive}
+}
+
+// Shell reads SQL statements terminated by ; and meta-commands starting with a dot.
+// Every statement runs in its own transaction.
+type Shell struct {
+	db          *kanthorkv.DB
+	out         io.Writer
+	interactive bool
+}
+
+// Run executes everything read from in and returns the number of statements that failed.
+func (sh *Shell) Run(in io.Reader) int {
+	failures := 0
+	scanner := bufio.NewScanner(in)
+	var pending strings.Builder
+
+	sh.prompt(pending.Len() == 0)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if pending.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			sh.prompt(true)
+			continue
+		}
+
+		if pending.Len() == 0 && strings.HasPrefix(trimmed, ".") {
+			err := sh.meta(trimmed)
+			if errors.Is(err, errQuit) {
+				return failures
+			}
+			if err != nil {
+				sh.printErr(err)
+				failures++
+			}
+			sh.prompt(true)
+			continue
+		}
+
+		pending.WriteString(line)
+		pending.WriteByte('\n')
+
+		stmts, rest := parser.SplitStatements(pending.String())
+		for _, stmt := range stmts {
+			if err := sh.execute(stmt); err != nil {
+				sh.printErr(err)
+				failures++
+			}
+		}
+		pending.Reset()
+		if strings.TrimSpace(rest) != "" {
+			pending.WriteString(rest)
+		}
+		sh.prompt(pending.Len() == 0)
+	}
+
+	if err := scanner.Err(); err != nil {
+		sh.printErr(err)
+		failures++
+	}
+	// the last statement of a script does not need its terminator
+	if stmt := strings.TrimSpace(pending.String()); stmt != "" {
+		if err := sh.execute(stmt); err != nil {
+			sh.printErr(err)
+			failures++
+		}
+	}
+	return failures
+}
+
+func (sh *Shell) prompt(fresh bool) {
+	if !sh.interactive {
+		return
+	}
+	if fresh {
+		fmt.Fprint(sh.out, "kanthorkv> ")
+	} else {
+		fmt.Fprint(sh.out, "      ...> ")
+	}
+}
+
+func (sh *Shell) printErr(err error) {
+	fmt.Fprintf(sh.out, "error: %v\n", err)
+}
+
+func (sh *Shell) meta(line string) error {
+	args := strings.Fields(line)
+	switch args[0] {
+	case ".quit", ".exit":
+		return errQuit
+	case ".help":
+		fmt.Fprintln(sh.out, ".tables              list tables")
+		fmt.Fprintln(sh.out, ".views               list views and their definitions")
+		fmt.Fprintln(sh.out, ".schema <table>      show the fields of a table")
+		fmt.Fprintln(sh.out, ".indexes [table]     list indexes, optionally of one table")
+		fmt.Fprintln(sh.out, ".quit                exit the shell")
+		return nil
+	case ".tables":
+		return sh.tables()
+	case ".views":
+		return sh.query("select viewname, viewdef from viewcat")
+	case ".schema":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: .schema <table>")
+		}
+		return sh.schema(args[1])
+	case ".indexes":
+		if len(args) == 2 {
+			return sh.indexes(args[1])
+		}
+		return sh.query("select indexname, tablename, fieldname, indextype from idxcat")
+	}
+	return fmt.Errorf("unknown command %s, try .help", args[0])
+}
+
+func (sh *Shell) tables() error {
+	return sh.inTx(func(t transaction.Transaction) error {
+		s, err := sh.db.Query(t, "select tblname from tblcat")
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0)
+		for s.Next() {
+			tblname, err := s.GetString("tblname")
+			if err != nil {
+				return errors.Join(err, s.Close())
+			}
+			if !slices.Contains(catalogs, tblname) {
+				rows = append(rows, []string{tblname})
+			}
+		}
+		if err := s.Close(); err != nil {
+			return err
+		}
+		sh.printTable([]string{"tblname"}, rows)
+		return nil
+	})
+}
+
+func (sh *Shell) schema(tblname string) error {
+	return sh.inTx(func(t transaction.Transaction) error {
+		layout, err := sh.db.Metadata().GetLayout(tblname, t)
+		if err != nil {
+			return err
+		}
+
+		sch := layout.Schema()
+		rows := make([][]string, 0, len(sch.Fields()))
+		for _, fldname := range sch.Fields() {
+			typ := sch.Type(fldname).String()
+			if sch.Type(fldname) == record.StringField {
+				typ = fmt.Sprintf("%s(%d)", typ, sch.Length(fldname))
+			}
+			rows = append(rows, []string{fldname, typ})
+		}
+		sh.printTable([]string{"field", "type"}, rows)
+		return nil
+	})
+}
+
+// indexes lists the indexes of a table from the catalog, the table name never becomes SQL.
+func (sh *Shell) indexes(tblname string) error {
+	return sh.inTx(func(t transaction.Transaction) error {
+		// an unknown table is an error, as for .schema
+		if _, err := sh.db.Metadata().GetLayout(tblname, t); err != nil {
+			return err
+		}
+		indexes, err := sh.db.Metadata().GetIndexes(tblname, t)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(indexes))
+		for _, ii := range indexes {
+			rows = append(rows, []string{ii.IndexName(), tblname, ii.FieldName(), ii.IndexType()})
+		}
+		sh.printTable([]string{"indexname", "tablename", "fieldname", "indextype"}, rows)
+		return nil
+	})
+}
+
+func (sh *Shell) execute(sql string) error {
+	if parser.IsQuery(sql) {
+		return sh.query(sql)
+	}
+
+	return sh.inTx(func(t transaction.Transaction) error {
+		affected, err := sh.db.Planner().ExecuteUpdate(sql, t)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(sh.out, "%d record(s) affected\n", affected)
+		return nil
+	})
+}
+
+func (sh *Shell) query(sql string) error {
+	return sh.inTx(func(t transaction.Transaction) error {
+		p, err := sh.db.Planner().CreateQueryPlan(sql, t)
+		if err != nil {
+			return err
+		}
+		s, err := p.Open()
+		if err != nil {
+			return err
+		}
+
+		sch := p.Schema()
+		rows := make([][]string, 0)
+		for s.Next() {
+			row := make([]string, len(sch.Fields()))
+			for i, fldname := range sch.Fields() {
+				val, err := s.GetVal(fldname)
+				if err != nil {
+					return errors.Join(err, s.Close())
+				}
+				row[i] = display(val, sch.Type(fldname))
+			}
+			rows = append(rows, row)
+		}
+		// a scan that stopped on an error returns it when closed, nothing is printed then
+		if err := s.Close(); err != nil {
+			return err
+		}
+		sh.printTable(sch.Fields(), rows)
+		return nil
+	})
+}
+
+// inTx commits the transaction when fn succeeds and rolls it back otherwise.
+func (sh *Shell) inTx(fn func(t transaction.Transaction) error) error {
+	t, err := sh.db.NewTx()
+	if err != nil {
+		return err
+	}
+	if err := fn(t); err != nil {
+		return errors.Join(err, t.Rollback())
+	}
+	return t.Commit()
+}
+
+func (sh *Shell) printTable(header []string, rows [][]string) {
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	line := func(cells []string) {
+		padded := make([]string, len(cells))
+		for i, cell := range cells {
+			padded[i] = cell + strings.Repeat(" ", widths[i]-len(cell))
+		}
+		fmt.Fprintln(sh.out, strings.TrimRight(" "+strings.Join(padded, " | "), " "))
+	}
+
+	line(header)
+	separators := make([]string, len(widths))
+	for i, w := range widths {
+		separators[i] = strings.Repeat("-", w+2)
+	}
+	fmt.Fprintln(sh.out, strings.Join(separators, "+"))
+	for _, row := range rows {
+		line(row)
+	}
+	fmt.Fprintf(sh.out, "(%d rows)\n", len(rows))
+}
+
+func display(val record.Constant, typ record.FieldType) string {
+	if val.IsNull() {
+		return "NULL"
+	}
+	if typ == record.IntegerField {
+		return strconv.Itoa(val.AsInt())
+	}
+	return val.AsString()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShell_Script(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kanthorkv-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := kanthorkv.Open(dir, kanthorkv.Options{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	script := `
+-- seed the fixtures
+create table student (sid int, sname varchar(10));
+insert into student (sid, sname) values (1, 'joe'); insert into student (sid, sname)
+  values (2, 'amy');
+create index sididx on student (sid);
+select sid, sname
+  from student
+  where sid = 2;
+.tables
+.schema student
+.indexes
+.quit
+select sid from student;
+`
+	var out strings.Builder
+	sh := NewShell(db, &out, false)
+	require.Equal(t, 0, sh.Run(strings.NewReader(script)))
+
+	expected := ` sid | sname
+-----+-------
+ 2   | amy
+(1 rows)
+`
+	require.Contains(t, out.String(), expected)
+	require.Contains(t, out.String(), " student\n")
+	require.NotContains(t, out.String(), " tblcat\n")
+	require.Contains(t, out.String(), " sname | VARCHAR(10)\n")
+	// nothing runs after .quit, so the index list is the last output
+	require.True(t, strings.HasSuffix(out.String(), " sididx    | student   | sid       | hash\n(1 rows)\n"))
+}
+
+func TestShell_Indexes(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kanthorkv-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := kanthorkv.Open(dir, kanthorkv.Options{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	script := `
+create table student (sid int, sname varchar(10));
+create table dept (did int, dname varchar(10));
+create index sididx on student (sid);
+create index snameidx on student (sname) using btree;
+create index dididx on dept (did);
+`
+	var out strings.Builder
+	sh := NewShell(db, &out, false)
+	require.Equal(t, 0, sh.Run(strings.NewReader(script)))
+
+	out.Reset()
+	require.Equal(t, 0, sh.Run(strings.NewReader(".indexes student\n")))
+	expected := ` indexname | tablename | fieldname | indextype
+-----------+-----------+-----------+-----------
+ sididx    | student   | sid       | hash
+ snameidx  | student   | sname     | btree
+(2 rows)
+`
+	require.Equal(t, expected, out.String())
+
+	// the table name is looked up in the catalog, it is never pasted into SQL
+	out.Reset()
+	require.Equal(t, 2, sh.Run(strings.NewReader(".indexes x'or'a'='a\n.indexes nosuchtable\n")))
+	require.NotContains(t, out.String(), "dididx")
+	require.Equal(t, 2, strings.Count(out.String(), "TABLE_NOT_FOUND"))
+}
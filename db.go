@@ -0,0 +1,151 @@
+package kanthorkv
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/kanthorlabs/kanthorkv/buffer"
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/log"
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/plan"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx"
+	"github.com/kanthorlabs/kanthorkv/tx/concurrency"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// catalogfile is the table catalog; an empty one means a brand new database
+const catalogfile = "tblcat.tbl"
+
+// Open opens the database stored in dirname, creating the directory if needed.
+// A new database gets its catalog tables created,
+// an existing one is recovered from the log before it is used.
+func Open(dirname string, opts Options) (*DB, error) {
+	opts = opts.withDefaults()
+
+	fm, err := file.NewFileManager(dirname, opts.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := open(dirname, fm, opts)
+	if err != nil {
+		return nil, errors.Join(err, fm.Close())
+	}
+	return db, nil
+}
+
+func open(dirname string, fm file.FileManager, opts Options) (*DB, error) {
+	catalogsize, err := fm.Length(catalogfile)
+	if err != nil {
+		return nil, err
+	}
+	isNew := catalogsize == 0
+
+	lm, err := log.NewLogManager(fm, LOG_FILE)
+	if err != nil {
+		return nil, err
+	}
+	bm, err := buffer.NewBufferManager(fm, lm, opts.NumBuffers, opts.BufferTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{
+		dirname: dirname,
+		fm:      fm,
+		lm:      lm,
+		bm:      bm,
+		lt:      concurrency.NewLockTableWithTimeout(opts.LockTimeout),
+	}
+
+	t, err := db.NewTx()
+	if err != nil {
+		return nil, err
+	}
+	if !isNew {
+		if err := t.Recover(); err != nil {
+			return nil, errors.Join(err, t.Rollback())
+		}
+	}
+	mdm, err := metadata.NewMetadataMgr(isNew, t)
+	if err != nil {
+		return nil, errors.Join(err, t.Rollback())
+	}
+	if err := t.Commit(); err != nil {
+		return nil, err
+	}
+
+	db.mdm = mdm
+	db.planner = plan.NewPlanner(plan.NewDPQueryPlanner(mdm, opts.MaxJoinTables), plan.NewIndexUpdatePlanner(mdm))
+	return db, nil
+}
+
+// DB is an open database directory with every subsystem wired together.
+// It is safe to start transactions from multiple goroutines,
+// but each transaction must only be used by one goroutine at a time.
+type DB struct {
+	dirname string
+	fm      file.FileManager
+	lm      log.LogManager
+	bm      buffer.BufferManager
+	lt      *concurrency.LockTable
+	mdm     *metadata.MetadataMgr
+	planner *plan.Planner
+
+	closed atomic.Bool
+}
+
+// NewTx starts a new transaction.
+func (db *DB) NewTx() (transaction.Transaction, error) {
+	if db.closed.Load() {
+		return nil, ErrDBClosed(db.dirname)
+	}
+	return tx.NewTransaction(db.fm, db.lm, db.bm, db.lt)
+}
+
+// Query plans and opens a SQL select statement inside the transaction.
+// The returned scan is positioned before its first record and must be closed by the caller.
+func (db *DB) Query(t transaction.Transaction, sql string) (record.Scan, error) {
+	if db.closed.Load() {
+		return nil, ErrDBClosed(db.dirname)
+	}
+	p, err := db.planner.CreateQueryPlan(sql, t)
+	if err != nil {
+		return nil, err
+	}
+	return p.Open()
+}
+
+// Exec executes a SQL insert, delete, update or create statement inside the transaction,
+// returning the number of affected records.
+func (db *DB) Exec(t transaction.Transaction, sql string) (int, error) {
+	if db.closed.Load() {
+		return 0, ErrDBClosed(db.dirname)
+	}
+	return db.planner.ExecuteUpdate(sql, t)
+}
+
+// Planner returns the planner that executes SQL statements.
+func (db *DB) Planner() *plan.Planner {
+	return db.planner
+}
+
+// Metadata returns the catalog of tables, views and indexes.
+func (db *DB) Metadata() *metadata.MetadataMgr {
+	return db.mdm
+}
+
+// Close releases the files of the database without flushing its buffers.
+// Commit and Rollback already write the pages of their transaction,
+// so only changes of unfinished transactions can be left in memory.
+// Transactions must be committed or rolled back before closing,
+// anything left uncommitted is undone by the recovery of the next Open.
+// That recovery runs after a clean Close too, reading the log back to the checkpoint of the previous Open.
+func (db *DB) Close() error {
+	if !db.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	return db.fm.Close()
+}
@@ -0,0 +1,939 @@
+package kanthorkv
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/plan"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_ExecAndQuery(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := Open(dir, Options{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.NewTx()
+	require.NoError(t, err)
+
+	_, err = db.Exec(tx, "create table student (sid int, sname varchar(10))")
+	require.NoError(t, err)
+
+	names := make(map[int]string)
+	for i := range 50 {
+		names[i] = fk.RandomStringWithLength(8)
+		sql := fmt.Sprintf("insert into student (sid, sname) values (%d, '%s')", i, names[i])
+		affected, err := db.Exec(tx, sql)
+		require.NoError(t, err)
+		require.Equal(t, 1, affected)
+	}
+	require.NoError(t, tx.Commit())
+
+	tx, err = db.NewTx()
+	require.NoError(t, err)
+	defer tx.Commit()
+
+	s, err := db.Query(tx, "select sid, sname from student where sid = 7")
+	require.NoError(t, err)
+	require.True(t, s.Next())
+	sname, err := s.GetString("sname")
+	require.NoError(t, err)
+	require.Equal(t, names[7], sname)
+	require.False(t, s.Next())
+	require.NoError(t, s.Close())
+
+	affected, err := db.Exec(tx, "delete from student where sid = 8")
+	require.NoError(t, err)
+	require.Equal(t, 1, affected)
+
+	affected, err = db.Exec(tx, "update student set sname = 'updated' where sid = 9")
+	require.NoError(t, err)
+	require.Equal(t, 1, affected)
+
+	s, err = db.Query(tx, "select sid, sname from student")
+	require.NoError(t, err)
+	count := 0
+	for s.Next() {
+		sid, err := s.GetInt("sid")
+		require.NoError(t, err)
+		require.NotEqual(t, 8, sid)
+		if sid == 9 {
+			sname, err := s.GetString("sname")
+			require.NoError(t, err)
+			require.Equal(t, "updated", sname)
+		}
+		count++
+	}
+	require.NoError(t, s.Close())
+	require.Equal(t, 49, count)
+}
+
+func TestDB_Reopen(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := Open(dir, Options{})
+	require.NoError(t, err)
+
+	tx, err := db.NewTx()
+	require.NoError(t, err)
+	_, err = db.Exec(tx, "create table dept (did int, dname varchar(10))")
+	require.NoError(t, err)
+	_, err = db.Exec(tx, "insert into dept (did, dname) values (1, 'compsci')")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	// this transaction is never committed, so the recovery must undo it
+	tx, err = db.NewTx()
+	require.NoError(t, err)
+	_, err = db.Exec(tx, "update dept set dname = 'math' where did = 1")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = Open(dir, Options{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err = db.NewTx()
+	require.NoError(t, err)
+	defer tx.Commit()
+
+	s, err := db.Query(tx, "select dname from dept where did = 1")
+	require.NoError(t, err)
+	require.True(t, s.Next())
+	dname, err := s.GetString("dname")
+	require.NoError(t, err)
+	require.Equal(t, "compsci", dname)
+	require.NoError(t, s.Close())
+}
+
+func TestDB_Rollback(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := Open(dir, Options{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.NewTx()
+	require.NoError(t, err)
+	_, err = db.Exec(tx, "create table course (cid int, title varchar(20))")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	tx, err = db.NewTx()
+	require.NoError(t, err)
+	_, err = db.Exec(tx, "insert into course (cid, title) values (12, 'db systems')")
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	tx, err = db.NewTx()
+	require.NoError(t, err)
+	defer tx.Commit()
+
+	s, err := db.Query(tx, "select cid from course")
+	require.NoError(t, err)
+	require.False(t, s.Next())
+	require.NoError(t, s.Close())
+}
+
+func TestDB_Closed(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := Open(dir, Options{})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = db.NewTx()
+	require.ErrorContains(t, err, "CLOSED")
+}
+
+func TestDB_CreateIndexUsing(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	exec(t, db, tx,
+		"create table student (sid int, sname varchar(10))",
+		"create index sididx on student (sid) using btree",
+		"create index snameidx on student (sname)",
+	)
+
+	_, err := db.Exec(tx, "create index bad on student (sid) using bitmap")
+	require.ErrorContains(t, err, "UNKNOWN_INDEX_TYPE")
+	_, err = db.Exec(tx, "create index bad on student (missing)")
+	require.ErrorContains(t, err, "FIELD_NOT_FOUND")
+	_, err = db.Exec(tx, "create index sididx on student (sname)")
+	require.ErrorContains(t, err, "INDEX_EXISTS")
+
+	// a field can have several indexes, the query planners search its B-tree
+	exec(t, db, tx, "create index sidhash on student (sid) using hash")
+	all, err := db.Metadata().GetIndexes("student", tx)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+
+	indexes, err := db.Metadata().GetIndexInfo("student", tx)
+	require.NoError(t, err)
+	require.Equal(t, index.TYPE_BTREE, indexes["sid"].IndexType())
+	require.Equal(t, index.TYPE_HASH, indexes["sname"].IndexType())
+
+	idx, err := indexes["sid"].Open()
+	require.NoError(t, err)
+	require.IsType(t, &index.BTreeIndex{}, idx)
+	defer idx.Close()
+
+	for i := range 300 {
+		val := record.NewIntConstant(i % 100)
+		require.NoError(t, idx.Insert(&val, &record.RID{Blknum: i, Slot: 0}))
+	}
+	key := record.NewIntConstant(42)
+	require.NoError(t, idx.BeforeFirst(&key))
+	blocks := make([]int, 0)
+	for idx.Next() {
+		rid, err := idx.GetDataRID()
+		require.NoError(t, err)
+		blocks = append(blocks, rid.Blknum)
+	}
+	require.ElementsMatch(t, []int{42, 142, 242}, blocks)
+}
+
+func TestDB_IndexMaintenance(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	exec(t, db, tx,
+		"create table student (sid int, sname varchar(10), major int)",
+		"create index sididx on student (sid) using btree",
+		"create index snameidx on student (sname)",
+		"create index majoridx on student (major) using exthash",
+	)
+
+	for i := range 100 {
+		exec(t, db, tx, fmt.Sprintf("insert into student (sid, sname, major) values (%d, 'n%d', %d)", i, i, i%10))
+	}
+	affected, err := db.Exec(tx, "delete from student where major = 3")
+	require.NoError(t, err)
+	require.Equal(t, 10, affected)
+	affected, err = db.Exec(tx, "update student set sid = 1000 where sname = 'n7'")
+	require.NoError(t, err)
+	require.Equal(t, 1, affected)
+
+	indexes, err := db.Metadata().GetIndexInfo("student", tx)
+	require.NoError(t, err)
+	// lookup returns the rids the index holds for key, each checked against the table
+	lookup := func(fldname string, key record.Constant) []record.RID {
+		idx, err := indexes[fldname].Open()
+		require.NoError(t, err)
+		defer idx.Close()
+
+		layout, err := db.Metadata().GetLayout("student", tx)
+		require.NoError(t, err)
+		ts, err := record.NewTableScan(tx, "student", layout)
+		require.NoError(t, err)
+		defer ts.Close()
+
+		rids := make([]record.RID, 0)
+		require.NoError(t, idx.BeforeFirst(&key))
+		for idx.Next() {
+			rid, err := idx.GetDataRID()
+			require.NoError(t, err)
+			require.NoError(t, ts.MoveToRid(*rid))
+			val, err := ts.GetVal(fldname)
+			require.NoError(t, err)
+			require.Equal(t, key, val)
+			rids = append(rids, *rid)
+		}
+		return rids
+	}
+
+	require.Len(t, lookup("sid", record.NewIntConstant(5)), 1)
+	require.Len(t, lookup("sid", record.NewIntConstant(3)), 0)
+	require.Len(t, lookup("sid", record.NewIntConstant(7)), 0)
+	require.Equal(t, lookup("sid", record.NewIntConstant(1000)), lookup("sname", record.NewStringConstant("n7")))
+	require.Len(t, lookup("sname", record.NewStringConstant("n13")), 0)
+	require.Len(t, lookup("major", record.NewIntConstant(3)), 0)
+	require.Len(t, lookup("major", record.NewIntConstant(4)), 10)
+}
+
+func TestDB_CreateIndexBackfill(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	for _, idxtype := range index.Types {
+		// a field has a single index, every type gets a table of its own
+		tblname, idxname := "s"+idxtype, "sid"+idxtype
+		exec(t, db, tx, fmt.Sprintf("create table %s (sid int, sname varchar(10))", tblname))
+		for i := range 200 {
+			exec(t, db, tx, fmt.Sprintf("insert into %s (sid, sname) values (%d, 'n%d')", tblname, i%50, i))
+		}
+
+		affected, err := db.Exec(tx, fmt.Sprintf("create index %s on %s (sid) using %s", idxname, tblname, idxtype))
+		require.NoError(t, err)
+		require.Equal(t, 200, affected)
+
+		indexes, err := db.Metadata().GetIndexInfo(tblname, tx)
+		require.NoError(t, err)
+		require.Equal(t, idxname, indexes["sid"].IndexName())
+		idx, err := indexes["sid"].Open()
+		require.NoError(t, err)
+
+		key := record.NewIntConstant(42)
+		require.NoError(t, idx.BeforeFirst(&key))
+		count := 0
+		for idx.Next() {
+			count++
+		}
+		require.Equal(t, 4, count, idxtype)
+		require.NoError(t, idx.Close())
+	}
+}
+
+func TestDB_IndexInsertSubset(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	exec(t, db, tx,
+		"create table student (sid int, sname varchar(10), major int)",
+		"create index majoridx on student (major) using btree",
+		"create index snameidx on student (sname)",
+	)
+	exec(t, db, tx,
+		"insert into student (sid) values (1)",
+		"insert into student (sid, sname, major) values (2, 'n2', 3)",
+	)
+
+	// the fields left out of the insert are indexed with the values they read back as
+	require.Equal(t, []string{"1"}, queryRows(t, db, tx, "select sid from student where major = 0"))
+	require.Equal(t, []string{"1"}, queryRows(t, db, tx, "select sid from student where sname = ''"))
+	require.Equal(t, []string{"2"}, queryRows(t, db, tx, "select sid from student where major = 3"))
+}
+
+func TestDB_IndexSelect(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	exec(t, db, tx,
+		"create table student (sid int, sname varchar(10), major int)",
+		"create index sididx on student (sid) using btree",
+	)
+	for i := range 100 {
+		exec(t, db, tx, fmt.Sprintf("insert into student (sid, sname, major) values (%d, 'n%d', %d)", i%20, i, i%3))
+	}
+
+	// a record the index does not know about is only visible to a full scan
+	insertUnindexed(t, db, tx, "student", map[string]record.Constant{
+		"sid": record.NewIntConstant(7), "sname": record.NewStringConstant("hidden"), "major": record.NewIntConstant(1),
+	})
+
+	require.ElementsMatch(t, []string{"'n7'", "'n27'", "'n47'", "'n67'", "'n87'"}, queryRows(t, db, tx, "select sname from student where sid = 7"))
+	require.ElementsMatch(t, []string{"'n7'", "'n67'"}, queryRows(t, db, tx, "select sname from student where 7 = sid and major = 1"))
+	require.Contains(t, queryRows(t, db, tx, "select sname from student where major = 1"), "'hidden'")
+	require.Empty(t, queryRows(t, db, tx, "select sname from student where sid = 'n7'"))
+}
+
+func TestDB_IndexJoin(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	majors := make([]int, 0)
+	for sid := range 50 {
+		majors = append(majors, sid%5)
+	}
+	createStudentDept(t, db, tx, majors, []int{0, 1, 2, 3})
+	exec(t, db, tx, "create index dididx on dept (did)")
+
+	// a department the index does not know about is never joined
+	insertUnindexed(t, db, tx, "dept", map[string]record.Constant{"did": record.NewIntConstant(4), "dname": record.NewStringConstant("hidden")})
+
+	student, err := plan.NewTablePlan("student", tx, db.Metadata())
+	require.NoError(t, err)
+	dept, err := plan.NewTablePlan("dept", tx, db.Metadata())
+	require.NoError(t, err)
+	indexes, err := db.Metadata().GetIndexInfo("dept", tx)
+	require.NoError(t, err)
+	s, err := plan.NewIndexJoinPlan(student, dept, indexes["did"], "majorid").Open()
+	require.NoError(t, err)
+	expected := make([]string, 0)
+	for sid, major := range majors {
+		if major < 4 {
+			expected = append(expected, fmt.Sprintf("'s%02d' 'd%d'", sid, major))
+		}
+	}
+	require.ElementsMatch(t, expected, readRows(t, s, "sname", "dname"))
+	require.NoError(t, s.Close())
+
+	require.Empty(t, queryRows(t, db, tx, "select sname, dname from student, dept where majorid = did and sid = 100"))
+}
+
+func TestDB_MultiTableQuery(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	majors := make([]int, 0)
+	for sid := range 30 {
+		majors = append(majors, sid%3)
+	}
+	createStudentDept(t, db, tx, majors, []int{0, 1, 2})
+	exec(t, db, tx,
+		"create table course (cid int, title varchar(10), deptid int)",
+		"create table empty (eid int)",
+		"create index deptididx on course (deptid) using btree",
+		"create view mathstudent as select sid, sname from student where majorid = 1",
+	)
+	for i := range 12 {
+		exec(t, db, tx, fmt.Sprintf("insert into course (cid, title, deptid) values (%d, 'c%d', %d)", i, i, i%3))
+	}
+
+	got := queryRows(t, db, tx, "select sname, dname, title from course, student, dept where majorid = did and deptid = did and sid = 4")
+	require.ElementsMatch(t, []string{"'s04' 'd1' 'c1'", "'s04' 'd1' 'c4'", "'s04' 'd1' 'c7'", "'s04' 'd1' 'c10'"}, got)
+
+	got = queryRows(t, db, tx, "select sname, dname from dept, mathstudent where did = 2")
+	require.Len(t, got, 10)
+	require.Contains(t, got, "'s01' 'd2'")
+
+	require.Empty(t, queryRows(t, db, tx, "select sname from empty, student"))
+	require.Empty(t, queryRows(t, db, tx, "select sname from student, empty"))
+}
+
+func TestDB_JoinOrder(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	majors := make([]int, 0)
+	for sid := range 20 {
+		majors = append(majors, sid%4)
+	}
+	createStudentDept(t, db, tx, majors, []int{0, 1, 2, 3})
+	exec(t, db, tx,
+		"create table course (cid int, title varchar(10), deptid int)",
+		"create table enroll (eid int, studentid int, courseid int)",
+		"create index courseididx on enroll (courseid) using btree",
+		"create index sididx on student (sid)",
+	)
+	for i := range 8 {
+		exec(t, db, tx, fmt.Sprintf("insert into course (cid, title, deptid) values (%d, 'c%d', %d)", i, i, i%4))
+	}
+	for i := range 60 {
+		exec(t, db, tx, fmt.Sprintf("insert into enroll (eid, studentid, courseid) values (%d, %d, %d)", i, i%20, i%8))
+	}
+
+	// students 2, 6, 10, 14 and 18 take 3 courses each
+	sql := "select sname, title, dname from enroll, dept, student, course where sid = studentid and courseid = cid and deptid = did and majorid = 2"
+	expected := planRows(t, tx, plan.NewBasicQueryPlanner(db.Metadata()), sql)
+	require.Len(t, expected, 15)
+	for _, qp := range append(queryPlanners(db), plan.NewDPQueryPlanner(db.Metadata(), 3)) {
+		require.ElementsMatch(t, expected, planRows(t, tx, qp, sql))
+	}
+}
+
+func TestDB_OrderBy(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	exec(t, db, tx,
+		"create table student (sid int, sname varchar(10), major int)",
+		"create index sididx on student (sid) using btree",
+	)
+	// 17 and 50 are coprime, so the sids are a shuffle of 0..49
+	for i := range 50 {
+		sid := i * 17 % 50
+		exec(t, db, tx, fmt.Sprintf("insert into student (sid, sname, major) values (%d, 'n%d', %d)", sid, sid, sid%3))
+	}
+	exec(t, db, tx, "create view seniors as select sid, sname from student where major = 2 order by sid desc")
+
+	// a record the index does not know about is only visible when the records are sorted
+	insertUnindexed(t, db, tx, "student", map[string]record.Constant{
+		"sid": record.NewIntConstant(100), "sname": record.NewStringConstant("hidden"), "major": record.NewIntConstant(1),
+	})
+
+	sorted := func(major int, desc bool) []string {
+		names := make([]string, 0)
+		for i := range 50 {
+			sid := i
+			if desc {
+				sid = 49 - i
+			}
+			if major < 0 || sid%3 == major {
+				names = append(names, fmt.Sprintf("'n%d'", sid))
+			}
+		}
+		return names
+	}
+
+	// the B-tree index provides the ascending order
+	require.Equal(t, sorted(-1, false), queryRows(t, db, tx, "select sname from student order by sid"))
+	require.Equal(t, sorted(0, false), queryRows(t, db, tx, "select sname from student where major = 0 order by sid asc"))
+	require.Equal(t, append([]string{"'hidden'"}, sorted(-1, true)...), queryRows(t, db, tx, "select sname from student order by sid desc"))
+	require.Equal(t, sorted(2, true), queryRows(t, db, tx, "select sname from seniors"))
+
+	// ties on major are broken by sid, in its own direction
+	expected := make([]string, 0)
+	for _, major := range []int{2, 1, 0} {
+		for _, name := range sorted(major, false) {
+			expected = append(expected, name)
+			if major == 1 && name == "'n49'" {
+				expected = append(expected, "'hidden'")
+			}
+		}
+	}
+	require.Equal(t, expected, queryRows(t, db, tx, "select sname from student order by major desc, sid"))
+
+	_, err := db.Query(tx, "select sname from student order by nosuchfield")
+	require.Error(t, err)
+}
+
+func TestDB_GroupBy(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	exec(t, db, tx, "create table enroll (eid int, studentid int, grade int, sname varchar(10))")
+	counts, sums, maxnames := map[int]int{}, map[int]int{}, map[int]string{}
+	for i := range 60 {
+		studentid, grade, sname := i%7, fk.IntBetween(0, 100), fmt.Sprintf("n%02d", fk.IntBetween(0, 99))
+		counts[studentid]++
+		sums[studentid] += grade
+		maxnames[studentid] = max(maxnames[studentid], sname)
+		exec(t, db, tx, fmt.Sprintf("insert into enroll (eid, studentid, grade, sname) values (%d, %d, %d, '%s')", i, studentid, grade, sname))
+	}
+
+	expected := make([]string, 0)
+	for studentid := range 7 {
+		expected = append(expected, fmt.Sprintf("%d %d %d %d '%s'",
+			studentid, counts[studentid], sums[studentid], sums[studentid]/counts[studentid], maxnames[studentid]))
+	}
+	sql := "select studentid, count(*), sum(grade), avg(grade), max(sname) from enroll group by studentid order by studentid"
+	require.Equal(t, expected, queryRows(t, db, tx, sql))
+
+	// HAVING filters on an aggregate that is not selected
+	require.Equal(t, []string{"4", "5", "6"}, queryRows(t, db, tx, "select studentid from enroll where grade = grade group by studentid having count(eid) = 8"))
+
+	// without GROUP BY, every record is in one group
+	require.Equal(t, []string{"60 6"}, queryRows(t, db, tx, "select count(*), max(studentid) from enroll"))
+	// even when no record is left, while GROUP BY makes no group of none
+	require.Equal(t, []string{"0 0 NULL NULL NULL NULL"},
+		queryRows(t, db, tx, "select count(*), count(grade), sum(grade), avg(grade), min(grade), max(sname) from enroll where eid = 100"))
+	require.Empty(t, queryRows(t, db, tx, "select studentid, count(*) from enroll where eid = 100 group by studentid"))
+
+	// the groups come out in the order of an aggregate
+	s, err := db.Query(tx, "select studentid, sum(grade) from enroll group by studentid order by sum(grade) desc")
+	require.NoError(t, err)
+	prev := -1
+	for s.Next() {
+		sum, err := s.GetInt("sum(grade)")
+		require.NoError(t, err)
+		if prev >= 0 {
+			require.LessOrEqual(t, sum, prev)
+		}
+		prev = sum
+	}
+	require.NoError(t, s.Close())
+
+	for _, sql := range []string{
+		"select eid, count(*) from enroll group by studentid",
+		"select studentid from enroll where count(*) = 1 group by studentid",
+		"select studentid from enroll having studentid = 1",
+		"select sum(sname) from enroll",
+		"select median(grade) from enroll",
+	} {
+		_, err := db.Query(tx, sql)
+		require.Error(t, err, sql)
+	}
+}
+
+func TestDB_Predicates(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	majors := make([]int, 0)
+	for range 40 {
+		majors = append(majors, fk.IntBetween(0, 5))
+	}
+	createStudentDept(t, db, tx, majors, []int{0, 1, 2, 3, 4, 5})
+	exec(t, db, tx, "create index majoridx on student (majorid)")
+
+	filter := func(keep func(sid, major int) bool) []string {
+		names := make([]string, 0)
+		for sid, major := range majors {
+			if keep(sid, major) {
+				names = append(names, fmt.Sprintf("'s%02d'", sid))
+			}
+		}
+		return names
+	}
+
+	tests := []struct {
+		where string
+		keep  func(sid, major int) bool
+	}{
+		{"sid < 10", func(sid, _ int) bool { return sid < 10 }},
+		{"10 <= sid and sid <= 20", func(sid, _ int) bool { return 10 <= sid && sid <= 20 }},
+		{"sid > 30 or sid < 5", func(sid, _ int) bool { return sid > 30 || sid < 5 }},
+		{"sid >= 35 or majorid = 2 and sid < 20", func(sid, major int) bool { return sid >= 35 || major == 2 && sid < 20 }},
+		{"(sid >= 35 or majorid = 2) and sid < 37", func(sid, major int) bool { return (sid >= 35 || major == 2) && sid < 37 }},
+		{"not sid <> 3 or not (majorid != 1 and sname > 's20')", func(sid, major int) bool {
+			return sid == 3 || !(major != 1 && fmt.Sprintf("s%02d", sid) > "s20")
+		}},
+		// the index on majorid is usable on the conjunctive part only
+		{"majorid = 4 and (sid < 10 or sid > 30)", func(sid, major int) bool { return major == 4 && (sid < 10 || sid > 30) }},
+		{"majorid = 4 or sid = 0", func(sid, major int) bool { return major == 4 || sid == 0 }},
+		{"sname < 10", func(int, int) bool { return false }},
+	}
+	for _, tt := range tests {
+		require.ElementsMatch(t, filter(tt.keep), queryRows(t, db, tx, "select sname from student where "+tt.where), tt.where)
+	}
+
+	// the selection on each table is pushed down, the join term links them
+	sql := "select sname from student, dept where majorid = did and (dname = 'd1' or dname = 'd3') and not sid >= 20"
+	require.ElementsMatch(t, filter(func(sid, major int) bool { return (major == 1 || major == 3) && sid < 20 }), queryRows(t, db, tx, sql))
+}
+
+func TestDB_Expressions(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	exec(t, db, tx, "create table emp (id int, name varchar(10), salary int, bonus int)")
+	type emp struct {
+		id, salary, bonus int
+		name              string
+	}
+	emps := make([]emp, 0)
+	for id := range 20 {
+		e := emp{id: id, name: fk.Person().FirstName(), salary: fk.IntBetween(100, 999), bonus: fk.IntBetween(-50, 50)}
+		e.name = e.name[:min(len(e.name), 10)]
+		emps = append(emps, e)
+		exec(t, db, tx, fmt.Sprintf("insert into emp (id, name, salary, bonus) values (%d, '%s', %d, %d)", e.id, e.name, e.salary, e.bonus))
+	}
+
+	sql := "select id, salary + bonus * 2 as total, upper(name) || '!' as shout, length(name), substr(name, 2, 2), " +
+		"abs(bonus), -id, (salary - bonus) / 3, salary % 7 from emp where (salary + bonus) % 2 = 0 order by total desc, id"
+	p, err := db.Planner().CreateQueryPlan(sql, tx)
+	require.NoError(t, err)
+	sch := p.Schema()
+	require.Equal(t, []string{"id", "total", "shout", "length(name)", "substr(name, 2, 2)", "abs(bonus)", "-id", "(salary - bonus) / 3", "salary % 7"}, sch.Fields())
+	require.Equal(t, record.StringField, sch.Type("shout"))
+	require.Equal(t, 11, sch.Length("shout"))
+	require.Equal(t, record.IntegerField, sch.Type("length(name)"))
+	require.Equal(t, 10, sch.Length("substr(name, 2, 2)"))
+
+	expected := make([]string, 0)
+	for _, e := range emps {
+		if (e.salary+e.bonus)%2 != 0 {
+			continue
+		}
+		runes := []rune(e.name)
+		expected = append(expected, fmt.Sprintf("%d %d %s! %d %s %d %d %d %d", e.id, e.salary+e.bonus*2, strings.ToUpper(e.name), len(runes),
+			string(runes[1:min(3, len(runes))]), max(e.bonus, -e.bonus), -e.id, (e.salary-e.bonus)/3, e.salary%7))
+	}
+
+	s, err := p.Open()
+	require.NoError(t, err)
+	rows := make([]string, 0)
+	totals := make([]int, 0)
+	for s.Next() {
+		row := make([]string, 0)
+		for _, fldname := range sch.Fields() {
+			val, err := s.GetVal(fldname)
+			require.NoError(t, err)
+			if val.Type() == record.StringField {
+				row = append(row, val.AsString())
+			} else {
+				row = append(row, fmt.Sprint(val.AsInt()))
+			}
+		}
+		total, err := s.GetInt("total")
+		require.NoError(t, err)
+		totals = append(totals, total)
+		rows = append(rows, strings.Join(row, " "))
+	}
+	require.NoError(t, s.Close())
+	require.ElementsMatch(t, expected, rows)
+	require.True(t, slices.IsSortedFunc(totals, func(a, b int) int { return b - a }))
+
+	// the computed column of a view is a field of the view
+	exec(t, db, tx, "create view pay as select id, salary + bonus as income from emp")
+	rich := 0
+	for _, e := range emps {
+		if e.salary+e.bonus > 500 {
+			rich++
+		}
+	}
+	require.Len(t, queryRows(t, db, tx, "select id from pay where income > 500"), rich)
+	require.Len(t, queryRows(t, db, tx, "select id from emp where -(salary + bonus) < -500"), rich)
+
+	// UPDATE SET takes an expression on the modified record
+	n, err := db.Exec(tx, "update emp set salary = salary * 2 + bonus where id < 5")
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	exec(t, db, tx, "update emp set name = coalesce(upper(substr(name, 1, 1)), '') || lower(substr(name, 2, 9))")
+	s, err = db.Query(tx, "select id, salary, name from emp")
+	require.NoError(t, err)
+	for s.Next() {
+		id, err := s.GetInt("id")
+		require.NoError(t, err)
+		e := emps[id]
+		salary, err := s.GetInt("salary")
+		require.NoError(t, err)
+		if id < 5 {
+			require.Equal(t, e.salary*2+e.bonus, salary)
+		} else {
+			require.Equal(t, e.salary, salary)
+		}
+		name, err := s.GetString("name")
+		require.NoError(t, err)
+		runes := []rune(e.name)
+		require.Equal(t, strings.ToUpper(string(runes[:1]))+strings.ToLower(string(runes[1:])), name)
+	}
+	require.NoError(t, s.Close())
+
+	_, err = db.Exec(tx, "update emp set salary = name")
+	require.Error(t, err)
+	_, err = db.Query(tx, "select name + 1 from emp")
+	require.Error(t, err)
+	s, err = db.Query(tx, "select salary / (id - id) from emp")
+	require.NoError(t, err)
+	require.True(t, s.Next())
+	_, err = s.GetVal("salary / (id - id)")
+	require.ErrorContains(t, err, "division by zero")
+	require.NoError(t, s.Close())
+}
+
+func TestDB_OuterJoin(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	// majors 0 to 5 have a dept, 6 and 7 do not, depts 8 and 9 have no student
+	majors := make([]int, 0)
+	for range 30 {
+		majors = append(majors, fk.IntBetween(0, 7))
+	}
+	createStudentDept(t, db, tx, majors, []int{0, 1, 2, 3, 4, 5, 8, 9})
+	hasDept := func(did int) bool { return did <= 5 || did >= 8 }
+
+	left := make([]string, 0)
+	inner := make([]string, 0)
+	for sid := range 30 {
+		dname := "NULL"
+		if hasDept(majors[sid]) {
+			dname = fmt.Sprintf("'d%d'", majors[sid])
+			inner = append(inner, fmt.Sprintf("'s%02d' %s", sid, dname))
+		}
+		left = append(left, fmt.Sprintf("'s%02d' %s", sid, dname))
+	}
+	require.ElementsMatch(t, left, queryRows(t, db, tx, "select sname, dname from student left join dept on majorid = did", "sname", "dname"))
+	require.ElementsMatch(t, left, queryRows(t, db, tx, "select sname, dname from dept right outer join student on majorid = did", "sname", "dname"))
+	require.ElementsMatch(t, inner, queryRows(t, db, tx, "select sname, dname from student join dept on majorid = did", "sname", "dname"))
+	require.ElementsMatch(t, inner, queryRows(t, db, tx, "select sname, dname from student inner join dept on did = majorid where sid >= 0", "sname", "dname"))
+
+	// the WHERE clause filters the joined records, the ON condition only decides which ones are padded
+	filtered := make([]string, 0)
+	padded := make([]string, 0)
+	for sid := range 30 {
+		if majors[sid] == 1 {
+			filtered = append(filtered, fmt.Sprintf("'s%02d' 'd1'", sid))
+		}
+		dname := "NULL"
+		if hasDept(majors[sid]) && majors[sid] != 1 {
+			dname = fmt.Sprintf("'d%d'", majors[sid])
+		}
+		padded = append(padded, fmt.Sprintf("'s%02d' %s", sid, dname))
+	}
+	require.ElementsMatch(t, filtered, queryRows(t, db, tx, "select sname, dname from student left join dept on majorid = did where dname = 'd1'", "sname", "dname"))
+	require.ElementsMatch(t, padded, queryRows(t, db, tx, "select sname, dname from student left join dept on majorid = did and dname <> 'd1'", "sname", "dname"))
+	// a comparison with NULL is unknown, and so is its negation
+	require.Len(t, queryRows(t, db, tx, "select sname from student left join dept on majorid = did where not dname = 'd1'", "sname"), len(inner)-len(filtered))
+
+	// the padded records go through sorting and grouping, which store them in temporary tables
+	counts := make(map[int]int)
+	for _, major := range majors {
+		counts[major]++
+	}
+	expected := make([]string, 0)
+	for _, did := range []int{0, 1, 2, 3, 4, 5, 8, 9} {
+		expected = append(expected, fmt.Sprintf("'d%d' %d", did, counts[did]))
+	}
+	sql := "select dname, count(sid) from dept left join student on did = majorid group by dname order by dname"
+	require.Equal(t, expected, queryRows(t, db, tx, sql, "dname", "count(sid)"))
+
+	// reading a NULL aggregate as an integer gives zero
+	s, err := db.Query(tx, "select dname, sum(sid) from dept left join student on did = majorid where did = 9 group by dname")
+	require.NoError(t, err)
+	require.True(t, s.Next())
+	val, err := s.GetVal("sum(sid)")
+	require.NoError(t, err)
+	require.True(t, val.IsNull())
+	sum, err := s.GetInt("sum(sid)")
+	require.NoError(t, err)
+	require.Zero(t, sum)
+	require.NoError(t, s.Close())
+
+	sorted := queryRows(t, db, tx, "select sname, coalesce(dname, 'none') as d from student left join dept on majorid = did order by dname, sname", "d")
+	require.Len(t, sorted, 30)
+	// NULL sorts first
+	for i, d := range sorted {
+		require.Equal(t, i < len(left)-len(inner), d == "'none'")
+	}
+
+	// joins chain from left to right, an outer join can be listed with other tables
+	exec(t, db, tx, "create table course (cid int, deptid int)")
+	for cid := range 4 {
+		exec(t, db, tx, fmt.Sprintf("insert into course (cid, deptid) values (%d, %d)", cid, cid*3))
+	}
+	got := queryRows(t, db, tx, "select did, cid from course right join dept on deptid = did join student on majorid = did where sid = 0", "did", "cid")
+	if !hasDept(majors[0]) {
+		require.Empty(t, got)
+	} else if majors[0]%3 == 0 && majors[0] <= 9 {
+		require.Equal(t, []string{fmt.Sprintf("%d %d", majors[0], majors[0]/3)}, got)
+	} else {
+		require.Equal(t, []string{fmt.Sprintf("%d NULL", majors[0])}, got)
+	}
+	require.Len(t, queryRows(t, db, tx, "select cid, sid from course left join dept on deptid = did, student where sid < 3", "cid", "sid"), 12)
+
+	// a view keeps its joins
+	exec(t, db, tx, "create view enrolled as select sname, dname from student left join dept on majorid = did")
+	require.ElementsMatch(t, left, queryRows(t, db, tx, "select sname, dname from enrolled", "sname", "dname"))
+}
+
+func TestDB_Aliases(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	exec(t, db, tx,
+		"create table emp (id int, name varchar(10), boss int, deptid int)",
+		"create table dept (id int, name varchar(10))",
+		"create index empididx on emp (id)",
+	)
+	// employee i reports to employee i / 2, employee 0 to itself
+	for i := range 20 {
+		exec(t, db, tx, fmt.Sprintf("insert into emp (id, name, boss, deptid) values (%d, 'e%d', %d, %d)", i, i, i/2, i%3))
+	}
+	for i := range 4 {
+		exec(t, db, tx, fmt.Sprintf("insert into dept (id, name) values (%d, 'd%d')", i, i))
+	}
+
+	planners := queryPlanners(db)
+
+	// a self-join reads the same table under two aliases, through the index of either one
+	bosses := make([]string, 0)
+	for i := 1; i < 20; i++ {
+		bosses = append(bosses, fmt.Sprintf("'e%d' 'e%d'", i, i/2))
+	}
+	for _, qp := range planners {
+		require.ElementsMatch(t, bosses, planRows(t, tx, qp, "select e1.name, e2.name from emp e1, emp as e2 where e1.boss = e2.id and e1.id > 0"))
+		require.ElementsMatch(t, bosses, planRows(t, tx, qp, "select e.name, b.name as boss from emp e join emp b on b.id = e.boss where e.id <> b.id"))
+		require.Equal(t, []string{"'e13' 'e6' 'e3'"}, planRows(t, tx, qp, "select e.name, b.name, bb.name from emp e, emp b, emp bb where e.id = 13 and b.id = e.boss and bb.id = b.boss"))
+	}
+
+	// the tables have id and name, the other fields need no qualifier
+	expected := make([]string, 0)
+	for i := range 20 {
+		expected = append(expected, fmt.Sprintf("'e%d' 'd%d'", i, i%3))
+	}
+	for _, qp := range planners {
+		require.ElementsMatch(t, expected, planRows(t, tx, qp, "select emp.name, d.name from emp, dept d where deptid = d.id"))
+		require.ElementsMatch(t, expected, planRows(t, tx, qp, "select e.name, dept.name from emp e left join dept on e.deptid = dept.id"))
+	}
+	got := planRows(t, tx, planners[2], "select d.name, count(e.id) from dept d left join emp e on deptid = d.id group by d.name order by d.name")
+	require.Equal(t, []string{"'d0' 7", "'d1' 7", "'d2' 6", "'d3' 0"}, got)
+	got = planRows(t, tx, planners[2], "select e1.name as name from emp e1, emp e2 where e2.id = 0 and e1.id < 3 order by name desc")
+	require.Equal(t, []string{"'e2'", "'e1'", "'e0'"}, got)
+
+	// a view is renamed like a table, its columns are the fields of the select list
+	exec(t, db, tx, "create view managers as select e.name, b.name as boss, b.id from emp e, emp b where e.boss = b.id and e.id > 0")
+	for _, qp := range planners {
+		require.Len(t, planRows(t, tx, qp, "select m.boss, e.name from managers m, emp e where m.id = e.boss and e.id = 3"), 2)
+		require.ElementsMatch(t, bosses, planRows(t, tx, qp, "select name, boss from managers"))
+	}
+
+	for _, sql := range []string{
+		"select name from emp e1, emp e2",
+		"select e1.name from emp e1, emp e2 where id = 1",
+		"select e.id from emp e, dept d order by name",
+		"select x.name from emp e",
+		"select e.salary from emp e",
+		"select emp.name from emp e",
+		"select e.name from emp e, emp e",
+		"select d.name, sum(id) from dept d, emp e group by d.name",
+	} {
+		_, err := db.Query(tx, sql)
+		require.Error(t, err, sql)
+	}
+
+	// single table statements accept the name of their table too
+	n, err := db.Exec(tx, "update emp set name = upper(emp.name) where emp.id = 1")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	n, err = db.Exec(tx, "delete from emp where emp.name = 'E1'")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, err = db.Exec(tx, "delete from emp where e.id = 1")
+	require.Error(t, err)
+}
+
+func TestDB_Limit(t *testing.T) {
+	db, tx := testdb(t, Options{})
+
+	exec(t, db, tx,
+		"create table nums (id int, grp int, name varchar(10))",
+		"create index ididx on nums (id) using btree",
+	)
+	// 37 and 200 are coprime, so the ids are a shuffle of 0..199
+	for i := range 200 {
+		id := i * 37 % 200
+		exec(t, db, tx, fmt.Sprintf("insert into nums (id, grp, name) values (%d, %d, 'n%d')", id, id%7, id))
+	}
+
+	planners := queryPlanners(db)
+
+	for _, qp := range planners {
+		// a page of the top-N records is the same page of the fully sorted records
+		all := planRows(t, tx, qp, "select id, grp from nums order by grp desc, id")
+		require.Len(t, all, 200)
+		require.Equal(t, all[:5], planRows(t, tx, qp, "select id, grp from nums order by grp desc, id limit 5"))
+		require.Equal(t, all[30:42], planRows(t, tx, qp, "select id, grp from nums order by grp desc, id limit 12 offset 30"))
+		require.Equal(t, all[195:], planRows(t, tx, qp, "select id, grp from nums order by grp desc, id limit 10 offset 195"))
+		require.Equal(t, all[190:], planRows(t, tx, qp, "select id, grp from nums order by grp desc, id offset 190"))
+		require.Empty(t, planRows(t, tx, qp, "select id, grp from nums order by grp desc, id limit 10 offset 200"))
+		require.Empty(t, planRows(t, tx, qp, "select id, grp from nums order by grp desc, id limit 0"))
+
+		// without ORDER BY, any records of the table will do
+		got := planRows(t, tx, qp, "select name from nums where grp = 3 limit 4 offset 2")
+		require.Len(t, got, 4)
+		require.Subset(t, planRows(t, tx, qp, "select name from nums where grp = 3"), got)
+
+		// the B-tree index provides the order, the scan stops after the last record of the page
+		require.Equal(t, []string{"'n2'", "'n3'", "'n4'"}, planRows(t, tx, qp, "select name from nums order by id limit 3 offset 2"))
+
+		// the groups are limited after they are computed
+		got = planRows(t, tx, qp, "select grp, count(id) as n from nums group by grp order by n desc, grp limit 3")
+		require.Equal(t, []string{"0 29", "1 29", "2 29"}, got)
+	}
+
+	p, err := db.Planner().CreateQueryPlan("select id from nums order by grp limit 5", tx)
+	require.NoError(t, err)
+	require.IsType(t, &plan.LimitPlan{}, p)
+	require.Equal(t, 5, p.RecordsOutput())
+
+	// a view keeps its LIMIT, the query on it is limited again
+	exec(t, db, tx, "create view last10 as select id, name from nums order by id desc limit 10")
+	for _, qp := range planners {
+		require.Equal(t, []string{"'n190'", "'n191'"}, planRows(t, tx, qp, "select name from last10 order by id limit 2"))
+		require.Len(t, planRows(t, tx, qp, "select name from last10 offset 4"), 6)
+	}
+}
+
+func TestDB_LimitSortFallback(t *testing.T) {
+	// the few free buffers cannot hold the first 500 records, which are sorted on disk instead
+	db, tx := testdb(t, Options{BlockSize: 400, NumBuffers: 6})
+
+	exec(t, db, tx, "create table nums (id int, grp int)")
+	for i := range 600 {
+		exec(t, db, tx, fmt.Sprintf("insert into nums (id, grp) values (%d, %d)", i, i%13))
+	}
+
+	ids := make([]int, 600)
+	for i := range ids {
+		ids[i] = i
+	}
+	slices.SortStableFunc(ids, func(a, b int) int { return a%13 - b%13 })
+	expected := make([]string, 0, len(ids))
+	for _, id := range ids {
+		expected = append(expected, fmt.Sprint(id))
+	}
+
+	require.Equal(t, expected[:10], queryRows(t, db, tx, "select id from nums order by grp, id limit 10"))
+	require.Equal(t, expected[50:550], queryRows(t, db, tx, "select id from nums order by grp, id limit 500 offset 50"))
+}
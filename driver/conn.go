@@ -0,0 +1,76 @@
+package driver
+
+import (
+	sqldriver "database/sql/driver"
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+var _ sqldriver.Conn = (*conn)(nil)
+
+// conn runs every statement in autocommit mode
+// unless a transaction has been started with Begin.
+type conn struct {
+	name string
+	db   *kanthorkv.DB
+	tx   *txn
+}
+
+func (c *conn) Prepare(query string) (sqldriver.Stmt, error) {
+	return &stmt{c: c, query: query, numInput: countPlaceholders(query)}, nil
+}
+
+func (c *conn) Close() error {
+	var err error
+	if c.tx != nil {
+		err = c.tx.Rollback()
+	}
+	return errors.Join(err, release(c.name))
+}
+
+func (c *conn) Begin() (sqldriver.Tx, error) {
+	if c.tx != nil {
+		return nil, ErrTxInProgress()
+	}
+	t, err := c.db.NewTx()
+	if err != nil {
+		return nil, err
+	}
+	c.tx = &txn{c: c, t: t}
+	return c.tx, nil
+}
+
+// transaction returns the transaction a statement must run in.
+// autocommit reports whether the caller owns it and has to finish it.
+func (c *conn) transaction() (t transaction.Transaction, autocommit bool, err error) {
+	if c.tx != nil {
+		return c.tx.t, false, nil
+	}
+	t, err = c.db.NewTx()
+	return t, true, err
+}
+
+var _ sqldriver.Tx = (*txn)(nil)
+
+type txn struct {
+	c *conn
+	t transaction.Transaction
+}
+
+func (tx *txn) Commit() error {
+	if tx.c.tx != tx {
+		return ErrTxDone()
+	}
+	tx.c.tx = nil
+	return tx.t.Commit()
+}
+
+func (tx *txn) Rollback() error {
+	if tx.c.tx != tx {
+		return ErrTxDone()
+	}
+	tx.c.tx = nil
+	return tx.t.Rollback()
+}
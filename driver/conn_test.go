@@ -0,0 +1,145 @@
+package driver
+
+import (
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriver_ExecAndQuery(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open(DRIVER_NAME, dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("create table student (sid int, sname varchar(10))")
+	require.NoError(t, err)
+
+	names := make([]string, 20)
+	for i := range names {
+		names[i] = fk.RandomStringWithLength(8)
+		res, err := db.Exec("insert into student (sid, sname) values (?, ?)", i, names[i])
+		require.NoError(t, err)
+		affected, err := res.RowsAffected()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), affected)
+	}
+
+	var sname string
+	require.NoError(t, db.QueryRow("select sname from student where sid = ?", 5).Scan(&sname))
+	require.Equal(t, names[5], sname)
+
+	rows, err := db.Query("select sid, sname from student")
+	require.NoError(t, err)
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+	require.Equal(t, []string{"sid", "sname"}, columns)
+
+	count := 0
+	for rows.Next() {
+		var sid int
+		require.NoError(t, rows.Scan(&sid, &sname))
+		require.Equal(t, names[sid], sname)
+		count++
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	require.Equal(t, len(names), count)
+
+	res, err := db.Exec("delete from student where sid = ?", 3)
+	require.NoError(t, err)
+	affected, err := res.RowsAffected()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), affected)
+}
+
+func TestDriver_Tx(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open(DRIVER_NAME, dir)
+	require.NoError(t, err)
+	defer db.Close()
+	// a single connection keeps the test away from lock waits between connections
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("create table dept (did int, dname varchar(10))")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec("insert into dept (did, dname) values (1, 'compsci')")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	tx, err = db.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec("insert into dept (did, dname) values (2, 'math')")
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	var count int
+	rows, err := db.Query("select did from dept")
+	require.NoError(t, err)
+	for rows.Next() {
+		count++
+	}
+	require.NoError(t, rows.Close())
+	require.Equal(t, 1, count)
+}
+
+func TestBind(t *testing.T) {
+	sql, err := bind("select a from t where b = ? and c = '?'", []sqldriver.Value{int64(1)})
+	require.NoError(t, err)
+	require.Equal(t, "select a from t where b = 1 and c = '?'", sql)
+
+	_, err = bind("select a from t where b = ?", nil)
+	require.ErrorContains(t, err, "ARGS_MISMATCH")
+
+	// a quote is written twice, the quoted text around it does not hide the next placeholder
+	sql, err = bind("select a from t where b = ? and c = ?", []sqldriver.Value{"it's '?'", int64(-2147483647)})
+	require.NoError(t, err)
+	require.Equal(t, "select a from t where b = 'it''s ''?''' and c = -2147483647", sql)
+
+	// an int constant is an int32
+	for _, v := range []int64{math.MaxInt32 + 1, math.MinInt32, math.MinInt64} {
+		_, err = bind("select a from t where b = ?", []sqldriver.Value{v})
+		require.ErrorContains(t, err, "ARG_OUT_OF_RANGE", "%d", v)
+	}
+
+	_, err = bind("select a from t where b = ?", []sqldriver.Value{1.5})
+	require.ErrorContains(t, err, "ARG_UNSUPPORTED")
+}
+
+func TestDriver_Quotes(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open(DRIVER_NAME, dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("create table student (sid int, sname varchar(10))")
+	require.NoError(t, err)
+	for i, name := range []string{"o'brien", "''", "'; x"} {
+		_, err = db.Exec("insert into student (sid, sname) values (?, ?)", i, name)
+		require.NoError(t, err)
+
+		var sid int
+		require.NoError(t, db.QueryRow("select sid from student where sname = ?", name).Scan(&sid))
+		require.Equal(t, i, sid)
+	}
+
+	// a view keeps its query as SQL, the quoted constant reads back the same
+	_, err = db.Exec("create view irish as select sid from student where sname = ?", "o'brien")
+	require.NoError(t, err)
+	var sid int
+	require.NoError(t, db.QueryRow("select sid from irish").Scan(&sid))
+	require.Equal(t, 0, sid)
+}
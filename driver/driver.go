@@ -0,0 +1,91 @@
+// Package driver registers KanthorKV as a database/sql driver named "kanthorkv".
+// The data source name is the database directory:
+//
+//	db, err := sql.Open("kanthorkv", "./data")
+package driver
+
+import (
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"path/filepath"
+	"sync"
+
+	"github.com/kanthorlabs/kanthorkv"
+)
+
+// DRIVER_NAME is the name the driver is registered with
+const DRIVER_NAME = "kanthorkv"
+
+func init() {
+	sql.Register(DRIVER_NAME, &Driver{Options: kanthorkv.Options{}})
+}
+
+var _ sqldriver.Driver = (*Driver)(nil)
+
+// Driver opens connections to database directories.
+// Connections to the same directory share one kanthorkv.DB
+// because a directory can only be opened once per process.
+type Driver struct {
+	Options kanthorkv.Options
+}
+
+func (d *Driver) Open(name string) (sqldriver.Conn, error) {
+	db, err := acquire(name, d.Options)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{name: name, db: db}, nil
+}
+
+type shared struct {
+	db   *kanthorkv.DB
+	refs int
+}
+
+var (
+	mu  sync.Mutex
+	dbs = make(map[string]*shared)
+)
+
+func acquire(name string, opts kanthorkv.Options) (*kanthorkv.DB, error) {
+	key, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if s, ok := dbs[key]; ok {
+		s.refs++
+		return s.db, nil
+	}
+
+	db, err := kanthorkv.Open(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	dbs[key] = &shared{db: db, refs: 1}
+	return db, nil
+}
+
+func release(name string) error {
+	key, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, ok := dbs[key]
+	if !ok {
+		return nil
+	}
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+	delete(dbs, key)
+	return s.db.Close()
+}
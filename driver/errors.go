@@ -0,0 +1,55 @@
+package driver
+
+import (
+	"fmt"
+	"strings"
+)
+
+var basename = "KANTHORKV.DRIVER"
+
+func Errf(err string, args ...string) error {
+	return fmt.Errorf("%s.%s: %s", basename, err, strings.Join(args, " | "))
+}
+
+func ErrTxInProgress() error {
+	return Errf("CONN.TX_IN_PROGRESS")
+}
+
+func ErrTxDone() error {
+	return Errf("TX.DONE")
+}
+
+func ErrArgsMismatch(expected, actual int) error {
+	args := []string{
+		fmt.Sprintf("expected=%d", expected),
+		fmt.Sprintf("actual=%d", actual),
+	}
+	return Errf("STMT.ARGS_MISMATCH", args...)
+}
+
+func ErrArgUnsupported(ordinal int, value any) error {
+	args := []string{
+		fmt.Sprintf("ordinal=%d", ordinal),
+		fmt.Sprintf("type=%T", value),
+	}
+	return Errf("STMT.ARG_UNSUPPORTED", args...)
+}
+
+func ErrArgOutOfRange(ordinal int, value int64) error {
+	args := []string{
+		fmt.Sprintf("ordinal=%d", ordinal),
+		fmt.Sprintf("value=%d", value),
+	}
+	return Errf("STMT.ARG_OUT_OF_RANGE", args...)
+}
+
+func ErrNotAQuery(sql string) error {
+	args := []string{
+		fmt.Sprintf("sql=%s", sql),
+	}
+	return Errf("STMT.NOT_A_QUERY", args...)
+}
+
+func ErrLastInsertIdUnsupported() error {
+	return Errf("RESULT.LAST_INSERT_ID_UNSUPPORTED")
+}
@@ -0,0 +1,83 @@
+package driver
+
+import (
+	sqldriver "database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+var _ sqldriver.Rows = (*rows)(nil)
+var _ sqldriver.RowsColumnTypeDatabaseTypeName = (*rows)(nil)
+var _ sqldriver.RowsColumnTypeScanType = (*rows)(nil)
+
+func newRows(p query.Plan) (*rows, error) {
+	s, err := p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &rows{s: s, schema: p.Schema()}, nil
+}
+
+// rows reads the records of an opened scan.
+// t is only set in autocommit mode, it is committed when the rows are closed.
+type rows struct {
+	s      record.Scan
+	schema *record.Schema
+	t      transaction.Transaction
+}
+
+func (r *rows) Columns() []string {
+	return r.schema.Fields()
+}
+
+func (r *rows) Close() error {
+	err := r.s.Close()
+	if r.t == nil {
+		return err
+	}
+
+	t := r.t
+	r.t = nil
+	if err != nil {
+		return errors.Join(err, t.Rollback())
+	}
+	return t.Commit()
+}
+
+func (r *rows) Next(dest []sqldriver.Value) error {
+	if !r.s.Next() {
+		return io.EOF
+	}
+
+	for i, fldname := range r.schema.Fields() {
+		val, err := r.s.GetVal(fldname)
+		if err != nil {
+			return err
+		}
+		switch {
+		case val.IsNull():
+			dest[i] = nil
+		case r.schema.Type(fldname) == record.IntegerField:
+			dest[i] = int64(val.AsInt())
+		default:
+			dest[i] = val.AsString()
+		}
+	}
+	return nil
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.schema.Type(r.schema.Fields()[index]).String()
+}
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if r.schema.Type(r.schema.Fields()[index]) == record.IntegerField {
+		return reflect.TypeOf(int64(0))
+	}
+	return reflect.TypeOf("")
+}
@@ -0,0 +1,167 @@
+package driver
+
+import (
+	sqldriver "database/sql/driver"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ sqldriver.Stmt = (*stmt)(nil)
+
+// stmt binds its arguments by replacing each ? placeholder with a SQL literal,
+// because the planner only accepts complete statements.
+type stmt struct {
+	c        *conn
+	query    string
+	numInput int
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return s.numInput
+}
+
+func (s *stmt) Exec(args []sqldriver.Value) (sqldriver.Result, error) {
+	sql, err := bind(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	t, autocommit, err := s.c.transaction()
+	if err != nil {
+		return nil, err
+	}
+	affected, err := s.c.db.Exec(t, sql)
+	if !autocommit {
+		if err != nil {
+			return nil, err
+		}
+		return result(affected), nil
+	}
+
+	if err != nil {
+		return nil, errors.Join(err, t.Rollback())
+	}
+	if err := t.Commit(); err != nil {
+		return nil, err
+	}
+	return result(affected), nil
+}
+
+func (s *stmt) Query(args []sqldriver.Value) (sqldriver.Rows, error) {
+	sql, err := bind(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	if !parser.IsQuery(sql) {
+		return nil, ErrNotAQuery(sql)
+	}
+
+	t, autocommit, err := s.c.transaction()
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.c.db.Planner().CreateQueryPlan(sql, t)
+	if err == nil {
+		var r *rows
+		if r, err = newRows(p); err == nil {
+			if autocommit {
+				r.t = t
+			}
+			return r, nil
+		}
+	}
+
+	if autocommit {
+		return nil, errors.Join(err, t.Rollback())
+	}
+	return nil, err
+}
+
+var _ sqldriver.Result = result(0)
+
+type result int
+
+func (r result) LastInsertId() (int64, error) {
+	return 0, ErrLastInsertIdUnsupported()
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
+
+func countPlaceholders(query string) int {
+	count, quoted := 0, false
+	for i := 0; i < len(query); i++ {
+		switch query[i] {
+		case '\'':
+			quoted = !quoted
+		case '?':
+			if !quoted {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func bind(query string, args []sqldriver.Value) (string, error) {
+	if expected := countPlaceholders(query); expected != len(args) {
+		return "", ErrArgsMismatch(expected, len(args))
+	}
+	if len(args) == 0 {
+		return query, nil
+	}
+
+	var sb strings.Builder
+	quoted, ordinal := false, 0
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		if ch == '\'' {
+			quoted = !quoted
+		}
+		if ch != '?' || quoted {
+			sb.WriteByte(ch)
+			continue
+		}
+
+		literal, err := toLiteral(ordinal+1, args[ordinal])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(literal)
+		ordinal++
+	}
+	return sb.String(), nil
+}
+
+// toLiteral renders an argument the way the lexer reads constants.
+// An int constant is an int32 whose sign the parser applies afterwards, so math.MinInt32 cannot be bound.
+func toLiteral(ordinal int, value sqldriver.Value) (string, error) {
+	switch v := value.(type) {
+	case int64:
+		if v < -math.MaxInt32 || v > math.MaxInt32 {
+			return "", ErrArgOutOfRange(ordinal, v)
+		}
+		return strconv.FormatInt(v, 10), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case string:
+		return record.NewStringConstant(v).String(), nil
+	case []byte:
+		return toLiteral(ordinal, string(v))
+	default:
+		return "", ErrArgUnsupported(ordinal, v)
+	}
+}
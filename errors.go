@@ -0,0 +1,19 @@
+package kanthorkv
+
+import (
+	"fmt"
+	"strings"
+)
+
+var basename = "KANTHORKV.DB"
+
+func Errf(err string, args ...string) error {
+	return fmt.Errorf("%s.%s: %s", basename, err, strings.Join(args, " | "))
+}
+
+func ErrDBClosed(dirname string) error {
+	args := []string{
+		fmt.Sprintf("dirname=%s", dirname),
+	}
+	return Errf("CLOSED", args...)
+}
@@ -5,6 +5,9 @@ import (
 	"hash/fnv"
 )
 
+// END_OF_FILE is the block number of the dummy block used to lock the end of a file
+const END_OF_FILE = -1
+
 // BlockId represents a specific block in a specific file
 type BlockId struct {
 	filename string
@@ -17,7 +20,7 @@ func NewBlockId(filename string, blknum int) *BlockId {
 		panic(ErrBlockIdFilenameEmpty())
 	}
 
-	if blknum < 0 {
+	if blknum < 0 && blknum != END_OF_FILE {
 		panic(ErrBlockIdInvalidBlockNumber(blknum))
 	}
 
@@ -199,6 +199,14 @@ func ErrFMUnlockUnknowFile(filename string, err error) error {
 	return Errf("FILE_MANAGER.UNLOCK", args...)
 }
 
+func ErrFMClose(filename string, err error) error {
+	args := []string{
+		fmt.Sprintf("filename=%s", filename),
+		fmt.Sprintf("err=%v", err),
+	}
+	return Errf("FILE_MANAGER.CLOSE", args...)
+}
+
 func ErrFMFinalize(filename string, err error) error {
 	args := []string{
 		fmt.Sprintf("filename=%s", filename),
@@ -17,6 +17,7 @@ type FileManager interface {
 	Append(filename string) (*BlockId, error)
 	Length(filename string) (int, error)
 	BlockSize() int
+	Close() error
 }
 
 var _ FileManager = (*localfm)(nil)
@@ -49,7 +50,7 @@ func NewFileManager(dirname string, blksize int) (FileManager, error) {
 			continue
 		}
 
-		if err = os.Remove(file.Name()); err != nil {
+		if err = os.Remove(path.Join(dirname, file.Name())); err != nil {
 			return nil, ErrFMDelTempFile(dirname, file.Name(), err)
 		}
 	}
@@ -83,12 +84,9 @@ func (fm localfm) Read(blk *BlockId, page *Page) error {
 		return err
 	}
 
+	// positional reads are safe to share the same file between goroutines
 	pos := blk.Number() * fm.blksize
-	if _, err := f.Seek(int64(pos), 0); err != nil {
-		return ErrFMReadSeek(fm.dirname, blk.Filename(), pos, err)
-	}
-
-	if _, err := f.Read(page.buffer); err != nil {
+	if _, err := f.ReadAt(page.buffer, int64(pos)); err != nil {
 		return ErrFMRead(fm.dirname, blk.Filename(), pos, err)
 	}
 
@@ -102,11 +100,7 @@ func (fm localfm) Write(blk *BlockId, page *Page) error {
 	}
 
 	pos := blk.Number() * fm.blksize
-	if _, err := f.Seek(int64(pos), 0); err != nil {
-		return ErrFMWriteSeek(fm.dirname, blk.Filename(), pos, err)
-	}
-
-	if _, err := f.Write(page.buffer); err != nil {
+	if _, err := f.WriteAt(page.buffer, int64(pos)); err != nil {
 		return ErrFMWrite(fm.dirname, blk.Filename(), pos, err)
 	}
 
@@ -129,11 +123,7 @@ func (fm localfm) Append(filename string) (*BlockId, error) {
 
 	bytes := make([]byte, fm.blksize)
 	pos := blk.Number() * fm.blksize
-	if _, err := f.Seek(int64(pos), 0); err != nil {
-		return nil, ErrFMAppendSeek(fm.dirname, filename, pos, err)
-	}
-
-	if _, err := f.Write(bytes); err != nil {
+	if _, err := f.WriteAt(bytes, int64(pos)); err != nil {
 		return nil, ErrFMAppend(fm.dirname, filename, pos, err)
 	}
 
@@ -157,6 +147,20 @@ func (fm localfm) BlockSize() int {
 	return fm.blksize
 }
 
+func (fm localfm) Close() error {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	var err error
+	for filename, f := range fm.files {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = ErrFMClose(filename, cerr)
+		}
+		delete(fm.files, filename)
+	}
+	return err
+}
+
 func (fm localfm) open(filename string) (*os.File, error) {
 	fm.mu.RLock()
 	f, ok := fm.files[filename]
@@ -186,6 +190,9 @@ func (fm localfm) open(filename string) (*os.File, error) {
 }
 
 func (fm *localfm) finalize() {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
 	for filename, f := range fm.files {
 		if err := f.Close(); err != nil {
 			log.Println(ErrFMFinalize(filename, err).Error())
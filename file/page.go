@@ -27,7 +27,7 @@ func (p *Page) Int(offset int) int {
 	// Read bytes and convert to int - this handles both positive and negative values correctly.
 	// as the bit pattern is preserved in the conversion from uint to int.
 	bytes := p.buffer[offset : INT_SIZE+offset]
-	return int(binary.LittleEndian.Uint32(bytes))
+	return int(int32(binary.LittleEndian.Uint32(bytes)))
 }
 
 func (p *Page) SetInt(offset int, value int) error {
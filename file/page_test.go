@@ -0,0 +1,16 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPage_Int(t *testing.T) {
+	p := NewPage(BLOCK_SIZE)
+
+	for i, val := range []int{0, 345, -1, -345, 1<<31 - 1, -1 << 31} {
+		require.NoError(t, p.SetInt(i*INT_SIZE, val))
+		require.Equal(t, val, p.Int(i*INT_SIZE))
+	}
+}
@@ -0,0 +1,179 @@
+package index
+
+import (
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// DirEntry points a directory at the block holding the keys from DataVal on.
+type DirEntry struct {
+	DataVal  record.Constant
+	BlockNum int
+}
+
+func NewBTreeDir(tx transaction.Transaction, blk *file.BlockId, layout *record.Layout) (*BTreeDir, error) {
+	contents, err := NewBTPage(tx, blk, layout)
+	if err != nil {
+		return nil, err
+	}
+	return &BTreeDir{
+		tx:       tx,
+		layout:   layout,
+		contents: contents,
+		filename: blk.Filename(),
+	}, nil
+}
+
+// BTreeDir is a directory block of a B-tree.
+// Its flag is the level of the block: 0 means its children are leaves.
+type BTreeDir struct {
+	tx       transaction.Transaction
+	layout   *record.Layout
+	contents *BTPage
+	filename string
+}
+
+func (d *BTreeDir) Close() error {
+	return d.contents.Close()
+}
+
+// Search walks down the directory and returns the number of the leaf block that may hold searchkey.
+func (d *BTreeDir) Search(searchkey *record.Constant) (int, error) {
+	childblk, err := d.findChildBlock(searchkey)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		flag, err := d.contents.GetFlag()
+		if err != nil {
+			return 0, err
+		}
+		if flag == 0 {
+			return childblk.Number(), nil
+		}
+
+		if err := d.contents.Close(); err != nil {
+			return 0, err
+		}
+		if d.contents, err = NewBTPage(d.tx, childblk, d.layout); err != nil {
+			return 0, err
+		}
+		if childblk, err = d.findChildBlock(searchkey); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// MakeNewRoot is called on the root after it was split.
+// The old records move to a new block so the root stays at block 0,
+// then the root gets one entry for that block and one for the split.
+func (d *BTreeDir) MakeNewRoot(e *DirEntry) error {
+	firstval, err := d.contents.GetDataVal(0)
+	if err != nil {
+		return err
+	}
+	level, err := d.contents.GetFlag()
+	if err != nil {
+		return err
+	}
+	newblk, err := d.contents.Split(0, level)
+	if err != nil {
+		return err
+	}
+	if _, err := d.insertEntry(&DirEntry{DataVal: firstval, BlockNum: newblk.Number()}); err != nil {
+		return err
+	}
+	if _, err := d.insertEntry(e); err != nil {
+		return err
+	}
+	return d.contents.SetFlag(level + 1)
+}
+
+// Insert adds the entry of a split child somewhere below this block.
+// It returns the entry of this block's own split, nil if it did not split.
+func (d *BTreeDir) Insert(e *DirEntry) (*DirEntry, error) {
+	flag, err := d.contents.GetFlag()
+	if err != nil {
+		return nil, err
+	}
+	if flag == 0 {
+		return d.insertEntry(e)
+	}
+
+	childblk, err := d.findChildBlock(&e.DataVal)
+	if err != nil {
+		return nil, err
+	}
+	child, err := NewBTreeDir(d.tx, childblk, d.layout)
+	if err != nil {
+		return nil, err
+	}
+	myentry, err := child.Insert(e)
+	if err := child.Close(); err != nil {
+		return nil, err
+	}
+	if err != nil || myentry == nil {
+		return nil, err
+	}
+	return d.insertEntry(myentry)
+}
+
+func (d *BTreeDir) insertEntry(e *DirEntry) (*DirEntry, error) {
+	slot, err := d.contents.FindSlotBefore(&e.DataVal)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.contents.InsertDir(slot+1, e.DataVal, e.BlockNum); err != nil {
+		return nil, err
+	}
+	full, err := d.contents.IsFull()
+	if err != nil || !full {
+		return nil, err
+	}
+
+	level, err := d.contents.GetFlag()
+	if err != nil {
+		return nil, err
+	}
+	numrecs, err := d.contents.GetNumRecs()
+	if err != nil {
+		return nil, err
+	}
+	splitpos := numrecs / 2
+	splitval, err := d.contents.GetDataVal(splitpos)
+	if err != nil {
+		return nil, err
+	}
+	newblk, err := d.contents.Split(splitpos, level)
+	if err != nil {
+		return nil, err
+	}
+	return &DirEntry{DataVal: splitval, BlockNum: newblk.Number()}, nil
+}
+
+func (d *BTreeDir) findChildBlock(searchkey *record.Constant) (*file.BlockId, error) {
+	slot, err := d.contents.FindSlotBefore(searchkey)
+	if err != nil {
+		return nil, err
+	}
+	numrecs, err := d.contents.GetNumRecs()
+	if err != nil {
+		return nil, err
+	}
+	// an entry equal to the key starts the block that holds it
+	if slot+1 < numrecs {
+		val, err := d.contents.GetDataVal(slot + 1)
+		if err != nil {
+			return nil, err
+		}
+		if val.Equal(*searchkey) {
+			slot++
+		}
+	}
+	blknum, err := d.contents.GetChildNum(slot)
+	if err != nil {
+		return nil, err
+	}
+	return file.NewBlockId(d.filename, blknum), nil
+}
@@ -0,0 +1,188 @@
+package index
+
+import (
+	"errors"
+	"math"
+
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+var _ Index = (*BTreeIndex)(nil)
+
+// NewBTreeIndex opens the B-tree idxName, creating its files on first use.
+// The leaf layout must have the dataval, block and id fields.
+func NewBTreeIndex(tx transaction.Transaction, idxName string, leafLayout *record.Layout) (*BTreeIndex, error) {
+	bti := &BTreeIndex{
+		tx:         tx,
+		leafLayout: leafLayout,
+		leaffile:   idxName + "leaf.tbl",
+	}
+
+	size, err := tx.Size(bti.leaffile)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		if err := bti.create(bti.leaffile, leafLayout, -1, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	dirsch := record.NewSchema()
+	dirsch.Add("block", leafLayout.Schema())
+	dirsch.Add("dataval", leafLayout.Schema())
+	bti.dirLayout = record.NewLayoutOfSchema(dirsch)
+	dirfile := idxName + "dir.tbl"
+	bti.rootblk = file.NewBlockId(dirfile, 0)
+
+	size, err = tx.Size(dirfile)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		// the root starts with one entry pointing every key at the first leaf
+		minval := record.NewStringConstant("")
+		if dirsch.Type("dataval") == record.IntegerField {
+			minval = record.NewIntConstant(math.MinInt32)
+		}
+		if err := bti.create(dirfile, bti.dirLayout, 0, &DirEntry{DataVal: minval, BlockNum: 0}); err != nil {
+			return nil, err
+		}
+	}
+
+	return bti, nil
+}
+
+// BTreeIndex is a B+tree: directory blocks in one file route a key to a leaf block in another.
+// Besides the lookups of Index, it can walk its records in ascending order of dataval.
+type BTreeIndex struct {
+	tx         transaction.Transaction
+	dirLayout  *record.Layout
+	leafLayout *record.Layout
+	leaffile   string
+	rootblk    *file.BlockId
+	leaf       *BTreeLeaf
+	err        error
+}
+
+// SearchCost is the height of the tree, the directory blocks are assumed to hold rpb records.
+func (bti *BTreeIndex) SearchCost(numblocks, rpb int) int {
+	if numblocks <= 1 || rpb <= 1 {
+		return 1
+	}
+	return 1 + int(math.Log(float64(numblocks))/math.Log(float64(rpb)))
+}
+
+func (bti *BTreeIndex) BeforeFirst(searchkey *record.Constant) error {
+	if err := bti.Close(); err != nil {
+		return err
+	}
+	bti.err = nil
+
+	root, err := NewBTreeDir(bti.tx, bti.rootblk, bti.dirLayout)
+	if err != nil {
+		return err
+	}
+	blknum, err := root.Search(searchkey)
+	if err := errors.Join(err, root.Close()); err != nil {
+		return err
+	}
+
+	leafblk := file.NewBlockId(bti.leaffile, blknum)
+	bti.leaf, err = NewBTreeLeaf(bti.tx, leafblk, bti.leafLayout, searchkey)
+	return err
+}
+
+func (bti *BTreeIndex) Next() bool {
+	if bti.leaf == nil {
+		return false
+	}
+	ok, err := bti.leaf.Next()
+	if err != nil {
+		bti.err = err
+		return false
+	}
+	return ok
+}
+
+// Err returns the error that stopped Next, if any.
+func (bti *BTreeIndex) Err() error {
+	return bti.err
+}
+
+// NextInOrder moves to the next record in ascending order of dataval, whatever its value.
+// After BeforeFirst it walks the records from the search key on to the end of the index.
+func (bti *BTreeIndex) NextInOrder() (bool, error) {
+	if bti.leaf == nil {
+		return false, nil
+	}
+	return bti.leaf.NextInOrder()
+}
+
+// GetDataVal returns the dataval stored in the current index record.
+func (bti *BTreeIndex) GetDataVal() (record.Constant, error) {
+	return bti.leaf.GetDataVal()
+}
+
+func (bti *BTreeIndex) GetDataRID() (*record.RID, error) {
+	return bti.leaf.GetDataRID()
+}
+
+func (bti *BTreeIndex) Insert(dataval *record.Constant, datarid *record.RID) error {
+	if err := bti.BeforeFirst(dataval); err != nil {
+		return err
+	}
+	e, err := bti.leaf.Insert(datarid)
+	if err := errors.Join(err, bti.Close()); err != nil || e == nil {
+		return err
+	}
+
+	root, err := NewBTreeDir(bti.tx, bti.rootblk, bti.dirLayout)
+	if err != nil {
+		return err
+	}
+	e2, err := root.Insert(e)
+	if err == nil && e2 != nil {
+		err = root.MakeNewRoot(e2)
+	}
+	return errors.Join(err, root.Close())
+}
+
+func (bti *BTreeIndex) Delete(dataval *record.Constant, datarid *record.RID) error {
+	if err := bti.BeforeFirst(dataval); err != nil {
+		return err
+	}
+	return errors.Join(bti.leaf.Delete(datarid), bti.Close())
+}
+
+func (bti *BTreeIndex) Close() error {
+	if bti.leaf == nil {
+		return nil
+	}
+	err := bti.leaf.Close()
+	bti.leaf = nil
+	return err
+}
+
+// create appends the first block of a B-tree file, with e as its only record if it is not nil.
+func (bti *BTreeIndex) create(filename string, layout *record.Layout, flag int, e *DirEntry) error {
+	blk, err := bti.tx.Append(filename)
+	if err != nil {
+		return err
+	}
+	page, err := NewBTPage(bti.tx, blk, layout)
+	if err != nil {
+		return err
+	}
+	if err := page.Format(blk, flag); err != nil {
+		return errors.Join(err, page.Close())
+	}
+	if e != nil {
+		if err := page.InsertDir(0, e.DataVal, e.BlockNum); err != nil {
+			return errors.Join(err, page.Close())
+		}
+	}
+	return page.Close()
+}
@@ -0,0 +1,135 @@
+package index
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBTreeIndex_InsertAndSearch(t *testing.T) {
+	newtx, cleanup := setupTest(t)
+	defer cleanup()
+
+	tx := newtx()
+	bti, err := NewBTreeIndex(tx, "idx", testLeafLayout(record.IntegerField, 0))
+	require.NoError(t, err)
+
+	// every key appears 3 times, enough records to grow a directory with more than one level
+	keys := make([]int, 0, 1500)
+	for i := range 500 {
+		keys = append(keys, i, i, i)
+	}
+	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	for i, key := range keys {
+		val := record.NewIntConstant(key)
+		require.NoError(t, bti.Insert(&val, &record.RID{Blknum: key, Slot: i}))
+	}
+
+	for _, key := range []int{0, 1, 250, 499} {
+		val := record.NewIntConstant(key)
+		require.NoError(t, bti.BeforeFirst(&val))
+		count := 0
+		for bti.Next() {
+			rid, err := bti.GetDataRID()
+			require.NoError(t, err)
+			require.Equal(t, key, rid.Blknum)
+			count++
+		}
+		require.Equal(t, 3, count, "key %d", key)
+	}
+
+	missing := record.NewIntConstant(500)
+	require.NoError(t, bti.BeforeFirst(&missing))
+	require.False(t, bti.Next())
+
+	require.NoError(t, bti.Close())
+	require.NoError(t, tx.Commit())
+}
+
+func TestBTreeIndex_NextInOrder(t *testing.T) {
+	newtx, cleanup := setupTest(t)
+	defer cleanup()
+
+	tx := newtx()
+	bti, err := NewBTreeIndex(tx, "idx", testLeafLayout(record.StringField, 8))
+	require.NoError(t, err)
+
+	keys := make([]string, 300)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%05d", fk.IntBetween(0, 99999))
+		val := record.NewStringConstant(keys[i])
+		require.NoError(t, bti.Insert(&val, &record.RID{Blknum: i, Slot: 0}))
+	}
+	slices.Sort(keys)
+
+	from := record.NewStringConstant(keys[100])
+	require.NoError(t, bti.BeforeFirst(&from))
+	got := make([]string, 0)
+	for {
+		ok, err := bti.NextInOrder()
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+		val, err := bti.GetDataVal()
+		require.NoError(t, err)
+		got = append(got, val.AsString())
+	}
+	// duplicates of keys[100] that sort before it are included too
+	first := slices.Index(keys, keys[100])
+	require.Equal(t, keys[first:], got)
+
+	require.NoError(t, bti.Close())
+	require.NoError(t, tx.Commit())
+}
+
+func TestBTreeIndex_Delete(t *testing.T) {
+	newtx, cleanup := setupTest(t)
+	defer cleanup()
+
+	tx := newtx()
+	layout := testLeafLayout(record.IntegerField, 0)
+	bti, err := NewBTreeIndex(tx, "idx", layout)
+	require.NoError(t, err)
+
+	// a single key fills the leaf and its overflow blocks
+	val := record.NewIntConstant(7)
+	for i := range 100 {
+		require.NoError(t, bti.Insert(&val, &record.RID{Blknum: 1, Slot: i}))
+	}
+	other := record.NewIntConstant(3)
+	require.NoError(t, bti.Insert(&other, &record.RID{Blknum: 2, Slot: 0}))
+
+	// deleting the records of the leaf itself pulls the overflow records back
+	for i := range 50 {
+		require.NoError(t, bti.Delete(&val, &record.RID{Blknum: 1, Slot: i}))
+	}
+	require.NoError(t, tx.Commit())
+
+	// the index is the same for the next transaction
+	tx = newtx()
+	bti, err = NewBTreeIndex(tx, "idx", layout)
+	require.NoError(t, err)
+
+	require.NoError(t, bti.BeforeFirst(&val))
+	slots := make([]int, 0)
+	for bti.Next() {
+		rid, err := bti.GetDataRID()
+		require.NoError(t, err)
+		slots = append(slots, rid.Slot)
+	}
+	slices.Sort(slots)
+	require.Len(t, slots, 50)
+	require.Equal(t, 50, slots[0])
+
+	require.NoError(t, bti.BeforeFirst(&other))
+	require.True(t, bti.Next())
+	require.False(t, bti.Next())
+
+	require.NoError(t, bti.Close())
+	require.NoError(t, tx.Commit())
+}
@@ -0,0 +1,348 @@
+package index
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// NewBTreeLeaf opens the leaf blk positioned before the first record having searchkey.
+func NewBTreeLeaf(tx transaction.Transaction, blk *file.BlockId, layout *record.Layout, searchkey *record.Constant) (*BTreeLeaf, error) {
+	contents, err := NewBTPage(tx, blk, layout)
+	if err != nil {
+		return nil, err
+	}
+	l := &BTreeLeaf{
+		tx:        tx,
+		layout:    layout,
+		searchkey: searchkey,
+		contents:  contents,
+		filename:  blk.Filename(),
+		primary:   blk.Number(),
+	}
+	if l.currentslot, err = contents.FindSlotBefore(searchkey); err != nil {
+		return nil, errors.Join(err, contents.Close())
+	}
+	if l.next, err = contents.GetNext(); err != nil {
+		return nil, errors.Join(err, contents.Close())
+	}
+	return l, nil
+}
+
+// BTreeLeaf is a leaf block of a B-tree.
+// Its flag is the first overflow block, -1 if there is none.
+// Only a leaf whose records all have the same dataval gets overflow blocks,
+// they hold more records with that dataval.
+type BTreeLeaf struct {
+	tx          transaction.Transaction
+	layout      *record.Layout
+	searchkey   *record.Constant
+	contents    *BTPage
+	currentslot int
+	filename    string
+	// primary is the leaf the directory points at, contents may be one of its overflow blocks
+	primary int
+	// next is the right sibling of primary
+	next int
+}
+
+func (l *BTreeLeaf) Close() error {
+	return l.contents.Close()
+}
+
+// Next moves to the next record having the search key.
+func (l *BTreeLeaf) Next() (bool, error) {
+	l.currentslot++
+	numrecs, err := l.contents.GetNumRecs()
+	if err != nil {
+		return false, err
+	}
+	if l.currentslot >= numrecs {
+		return l.tryOverflow()
+	}
+	val, err := l.contents.GetDataVal(l.currentslot)
+	if err != nil {
+		return false, err
+	}
+	if val.Equal(*l.searchkey) {
+		return true, nil
+	}
+	return l.tryOverflow()
+}
+
+// NextInOrder moves to the next record whatever its dataval is,
+// following overflow blocks and then the right sibling, so records come in ascending order.
+func (l *BTreeLeaf) NextInOrder() (bool, error) {
+	l.currentslot++
+	for {
+		numrecs, err := l.contents.GetNumRecs()
+		if err != nil {
+			return false, err
+		}
+		if l.currentslot < numrecs {
+			return true, nil
+		}
+
+		flag, err := l.contents.GetFlag()
+		if err != nil {
+			return false, err
+		}
+		if flag >= 0 {
+			if err := l.moveTo(flag); err != nil {
+				return false, err
+			}
+			l.currentslot = 0
+			continue
+		}
+		if l.next < 0 {
+			return false, nil
+		}
+		if err := l.moveTo(l.next); err != nil {
+			return false, err
+		}
+		l.currentslot = 0
+		l.primary = l.contents.blk.Number()
+		if l.next, err = l.contents.GetNext(); err != nil {
+			return false, err
+		}
+	}
+}
+
+func (l *BTreeLeaf) GetDataRID() (*record.RID, error) {
+	return l.contents.GetDataRID(l.currentslot)
+}
+
+func (l *BTreeLeaf) GetDataVal() (record.Constant, error) {
+	return l.contents.GetDataVal(l.currentslot)
+}
+
+// Delete removes the record having the search key and datarid, if there is one.
+func (l *BTreeLeaf) Delete(datarid *record.RID) error {
+	for {
+		ok, err := l.Next()
+		if err != nil || !ok {
+			return err
+		}
+		rid, err := l.GetDataRID()
+		if err != nil {
+			return err
+		}
+		if rid.Equal(*datarid) {
+			if err := l.contents.Delete(l.currentslot); err != nil {
+				return err
+			}
+			return l.refill()
+		}
+	}
+}
+
+// Insert adds a record having the search key and datarid.
+// It returns the directory entry of the new block if the leaf was split, nil otherwise.
+func (l *BTreeLeaf) Insert(datarid *record.RID) (*DirEntry, error) {
+	flag, err := l.contents.GetFlag()
+	if err != nil {
+		return nil, err
+	}
+	if flag >= 0 {
+		// a leaf with overflow blocks only ever holds one dataval, a different one needs another leaf
+		firstval, err := l.contents.GetDataVal(0)
+		if err != nil {
+			return nil, err
+		}
+		if firstval.Compare(*l.searchkey) > 0 {
+			// the leaf and its overflow blocks move right, the smaller key takes its place
+			newblk, err := l.contents.Split(0, flag)
+			if err != nil {
+				return nil, err
+			}
+			if err := l.link(newblk); err != nil {
+				return nil, err
+			}
+			if err := l.contents.SetFlag(-1); err != nil {
+				return nil, err
+			}
+			l.currentslot = 0
+			if err := l.contents.InsertLeaf(l.currentslot, *l.searchkey, datarid); err != nil {
+				return nil, err
+			}
+			return &DirEntry{DataVal: firstval, BlockNum: newblk.Number()}, nil
+		}
+		if firstval.Compare(*l.searchkey) < 0 {
+			newblk, err := l.contents.AppendNew(-1)
+			if err != nil {
+				return nil, err
+			}
+			if err := l.link(newblk); err != nil {
+				return nil, err
+			}
+			newpage, err := NewBTPage(l.tx, newblk, l.layout)
+			if err != nil {
+				return nil, err
+			}
+			if err := newpage.InsertLeaf(0, *l.searchkey, datarid); err != nil {
+				return nil, errors.Join(err, newpage.Close())
+			}
+			if err := newpage.Close(); err != nil {
+				return nil, err
+			}
+			return &DirEntry{DataVal: *l.searchkey, BlockNum: newblk.Number()}, nil
+		}
+	}
+
+	l.currentslot++
+	if err := l.contents.InsertLeaf(l.currentslot, *l.searchkey, datarid); err != nil {
+		return nil, err
+	}
+	full, err := l.contents.IsFull()
+	if err != nil || !full {
+		return nil, err
+	}
+
+	// the page is full, so split it
+	numrecs, err := l.contents.GetNumRecs()
+	if err != nil {
+		return nil, err
+	}
+	firstkey, err := l.contents.GetDataVal(0)
+	if err != nil {
+		return nil, err
+	}
+	lastkey, err := l.contents.GetDataVal(numrecs - 1)
+	if err != nil {
+		return nil, err
+	}
+	if lastkey.Equal(firstkey) {
+		// create an overflow block to hold all but the first record
+		newblk, err := l.contents.Split(1, flag)
+		if err != nil {
+			return nil, err
+		}
+		return nil, l.contents.SetFlag(newblk.Number())
+	}
+
+	splitpos := numrecs / 2
+	splitkey, err := l.contents.GetDataVal(splitpos)
+	if err != nil {
+		return nil, err
+	}
+	// records having the same dataval never span two leaves
+	if splitkey.Equal(firstkey) {
+		// move right, looking for the next key
+		for splitkey.Equal(firstkey) {
+			splitpos++
+			if splitkey, err = l.contents.GetDataVal(splitpos); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// move left, looking for the first record having that key
+		for {
+			val, err := l.contents.GetDataVal(splitpos - 1)
+			if err != nil {
+				return nil, err
+			}
+			if !val.Equal(splitkey) {
+				break
+			}
+			splitpos--
+		}
+	}
+	newblk, err := l.contents.Split(splitpos, -1)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.link(newblk); err != nil {
+		return nil, err
+	}
+	return &DirEntry{DataVal: splitkey, BlockNum: newblk.Number()}, nil
+}
+
+// tryOverflow moves to the overflow block when it holds more records having the search key.
+func (l *BTreeLeaf) tryOverflow() (bool, error) {
+	flag, err := l.contents.GetFlag()
+	if err != nil || flag < 0 {
+		return false, err
+	}
+	numrecs, err := l.contents.GetNumRecs()
+	if err != nil {
+		return false, err
+	}
+	// an overflow block emptied by deletes says nothing about the key, look further
+	if numrecs > 0 {
+		firstkey, err := l.contents.GetDataVal(0)
+		if err != nil {
+			return false, err
+		}
+		if !firstkey.Equal(*l.searchkey) {
+			return false, nil
+		}
+	}
+	if err := l.moveTo(flag); err != nil {
+		return false, err
+	}
+	return l.Next()
+}
+
+// refill keeps a leaf that has overflow blocks from being empty,
+// by pulling back the records of its first overflow block.
+func (l *BTreeLeaf) refill() error {
+	if l.contents.blk.Number() != l.primary {
+		return nil
+	}
+	numrecs, err := l.contents.GetNumRecs()
+	if err != nil || numrecs > 0 {
+		return err
+	}
+	flag, err := l.contents.GetFlag()
+	if err != nil || flag < 0 {
+		return err
+	}
+
+	overflow, err := NewBTPage(l.tx, file.NewBlockId(l.filename, flag), l.layout)
+	if err != nil {
+		return err
+	}
+	defer overflow.Close()
+	next, err := overflow.GetFlag()
+	if err != nil {
+		return err
+	}
+	if err := overflow.transferRecs(0, l.contents); err != nil {
+		return err
+	}
+	return l.contents.SetFlag(next)
+}
+
+// link makes newblk the right sibling of the current leaf.
+func (l *BTreeLeaf) link(newblk *file.BlockId) error {
+	newpage, err := NewBTPage(l.tx, newblk, l.layout)
+	if err != nil {
+		return err
+	}
+	next, err := l.contents.GetNext()
+	if err != nil {
+		return errors.Join(err, newpage.Close())
+	}
+	if err := newpage.SetNext(next); err != nil {
+		return errors.Join(err, newpage.Close())
+	}
+	if err := newpage.Close(); err != nil {
+		return err
+	}
+	return l.contents.SetNext(newblk.Number())
+}
+
+func (l *BTreeLeaf) moveTo(blknum int) error {
+	if err := l.contents.Close(); err != nil {
+		return err
+	}
+	contents, err := NewBTPage(l.tx, file.NewBlockId(l.filename, blknum), l.layout)
+	if err != nil {
+		return err
+	}
+	l.contents = contents
+	l.currentslot = -1
+	return nil
+}
@@ -0,0 +1,323 @@
+package index
+
+import (
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// the header of a B-tree block is followed by its sorted records
+const (
+	// BTPAGE_FLAG holds the level of a directory block,
+	// or the overflow block of a leaf (-1 if there is none)
+	BTPAGE_FLAG = 0
+	// BTPAGE_NUMRECS holds the number of records in the block
+	BTPAGE_NUMRECS = file.INT_SIZE
+	// BTPAGE_NEXT holds the right sibling of a leaf (-1 if it is the last one)
+	BTPAGE_NEXT = 2 * file.INT_SIZE
+	// BTPAGE_HEADER_SIZE is where the first record starts
+	BTPAGE_HEADER_SIZE = 3 * file.INT_SIZE
+)
+
+// NewBTPage pins blk, it stays pinned until Close.
+func NewBTPage(tx transaction.Transaction, blk *file.BlockId, layout *record.Layout) (*BTPage, error) {
+	if err := tx.Pin(blk); err != nil {
+		return nil, err
+	}
+	return &BTPage{tx: tx, blk: blk, layout: layout}, nil
+}
+
+// BTPage is a block of a B-tree file whose records are kept sorted by dataval.
+// Directory and leaf blocks share it, only their layouts differ.
+type BTPage struct {
+	tx     transaction.Transaction
+	blk    *file.BlockId
+	layout *record.Layout
+}
+
+// FindSlotBefore returns the slot of the last record whose dataval is smaller than searchkey,
+// -1 if there is none.
+func (p *BTPage) FindSlotBefore(searchkey *record.Constant) (int, error) {
+	numrecs, err := p.GetNumRecs()
+	if err != nil {
+		return 0, err
+	}
+	slot := 0
+	for slot < numrecs {
+		val, err := p.GetDataVal(slot)
+		if err != nil {
+			return 0, err
+		}
+		if val.Compare(*searchkey) >= 0 {
+			break
+		}
+		slot++
+	}
+	return slot - 1, nil
+}
+
+func (p *BTPage) Close() error {
+	if p.blk == nil {
+		return nil
+	}
+	blk := p.blk
+	p.blk = nil
+	return p.tx.Unpin(blk)
+}
+
+// IsFull reports whether the block has no room for one more record.
+func (p *BTPage) IsFull() (bool, error) {
+	numrecs, err := p.GetNumRecs()
+	if err != nil {
+		return false, err
+	}
+	return p.slotpos(numrecs+1) >= p.tx.BlockSize(), nil
+}
+
+// Split moves the records from splitpos on to a new block with the given flag.
+func (p *BTPage) Split(splitpos, flag int) (*file.BlockId, error) {
+	newblk, err := p.AppendNew(flag)
+	if err != nil {
+		return nil, err
+	}
+	newpage, err := NewBTPage(p.tx, newblk, p.layout)
+	if err != nil {
+		return nil, err
+	}
+	defer newpage.Close()
+
+	if err := p.transferRecs(splitpos, newpage); err != nil {
+		return nil, err
+	}
+	return newblk, nil
+}
+
+func (p *BTPage) GetDataVal(slot int) (record.Constant, error) {
+	return p.getVal(slot, "dataval")
+}
+
+func (p *BTPage) GetFlag() (int, error) {
+	return p.tx.GetInt(p.blk, BTPAGE_FLAG)
+}
+
+func (p *BTPage) SetFlag(val int) error {
+	return p.tx.SetInt(p.blk, BTPAGE_FLAG, val, true)
+}
+
+func (p *BTPage) GetNext() (int, error) {
+	return p.tx.GetInt(p.blk, BTPAGE_NEXT)
+}
+
+func (p *BTPage) SetNext(blknum int) error {
+	return p.tx.SetInt(p.blk, BTPAGE_NEXT, blknum, true)
+}
+
+func (p *BTPage) GetNumRecs() (int, error) {
+	return p.tx.GetInt(p.blk, BTPAGE_NUMRECS)
+}
+
+// AppendNew appends a formatted block to the file of this page.
+func (p *BTPage) AppendNew(flag int) (*file.BlockId, error) {
+	blk, err := p.tx.Append(p.blk.Filename())
+	if err != nil {
+		return nil, err
+	}
+	if err := p.tx.Pin(blk); err != nil {
+		return nil, err
+	}
+	defer p.tx.Unpin(blk)
+
+	if err := p.Format(blk, flag); err != nil {
+		return nil, err
+	}
+	return blk, nil
+}
+
+// Format initializes a new block, the block must be pinned.
+// Nothing is logged because the block is not reachable before a logged change points to it.
+func (p *BTPage) Format(blk *file.BlockId, flag int) error {
+	if err := p.tx.SetInt(blk, BTPAGE_FLAG, flag, false); err != nil {
+		return err
+	}
+	if err := p.tx.SetInt(blk, BTPAGE_NUMRECS, 0, false); err != nil {
+		return err
+	}
+	if err := p.tx.SetInt(blk, BTPAGE_NEXT, -1, false); err != nil {
+		return err
+	}
+
+	recsize := p.layout.SlotSize()
+	for pos := BTPAGE_HEADER_SIZE; pos+recsize <= p.tx.BlockSize(); pos += recsize {
+		for _, fldname := range p.layout.Schema().Fields() {
+			offset := pos + p.layout.Offset(fldname)
+			var err error
+			if p.layout.Schema().Type(fldname) == record.IntegerField {
+				err = p.tx.SetInt(blk, offset, 0, false)
+			} else {
+				err = p.tx.SetString(blk, offset, "", false)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// methods called only by BTreeDir
+
+func (p *BTPage) GetChildNum(slot int) (int, error) {
+	return p.getInt(slot, "block")
+}
+
+func (p *BTPage) InsertDir(slot int, val record.Constant, blknum int) error {
+	if err := p.insert(slot); err != nil {
+		return err
+	}
+	if err := p.setVal(slot, "dataval", val); err != nil {
+		return err
+	}
+	return p.setInt(slot, "block", blknum)
+}
+
+// methods called only by BTreeLeaf
+
+func (p *BTPage) GetDataRID(slot int) (*record.RID, error) {
+	blknum, err := p.getInt(slot, "block")
+	if err != nil {
+		return nil, err
+	}
+	id, err := p.getInt(slot, "id")
+	if err != nil {
+		return nil, err
+	}
+	return &record.RID{Blknum: blknum, Slot: id}, nil
+}
+
+func (p *BTPage) InsertLeaf(slot int, val record.Constant, rid *record.RID) error {
+	if err := p.insert(slot); err != nil {
+		return err
+	}
+	if err := p.setVal(slot, "dataval", val); err != nil {
+		return err
+	}
+	if err := p.setInt(slot, "block", rid.Blknum); err != nil {
+		return err
+	}
+	return p.setInt(slot, "id", rid.Slot)
+}
+
+func (p *BTPage) Delete(slot int) error {
+	numrecs, err := p.GetNumRecs()
+	if err != nil {
+		return err
+	}
+	for i := slot + 1; i < numrecs; i++ {
+		if err := p.copyRecord(i, i-1); err != nil {
+			return err
+		}
+	}
+	return p.setNumRecs(numrecs - 1)
+}
+
+// private methods
+
+func (p *BTPage) getInt(slot int, fldname string) (int, error) {
+	return p.tx.GetInt(p.blk, p.fldpos(slot, fldname))
+}
+
+func (p *BTPage) getString(slot int, fldname string) (string, error) {
+	return p.tx.GetString(p.blk, p.fldpos(slot, fldname))
+}
+
+func (p *BTPage) getVal(slot int, fldname string) (record.Constant, error) {
+	if p.layout.Schema().Type(fldname) == record.IntegerField {
+		ival, err := p.getInt(slot, fldname)
+		if err != nil {
+			return record.Constant{}, err
+		}
+		return record.NewIntConstant(ival), nil
+	}
+	sval, err := p.getString(slot, fldname)
+	if err != nil {
+		return record.Constant{}, err
+	}
+	return record.NewStringConstant(sval), nil
+}
+
+func (p *BTPage) setInt(slot int, fldname string, val int) error {
+	return p.tx.SetInt(p.blk, p.fldpos(slot, fldname), val, true)
+}
+
+func (p *BTPage) setString(slot int, fldname string, val string) error {
+	return p.tx.SetString(p.blk, p.fldpos(slot, fldname), val, true)
+}
+
+func (p *BTPage) setVal(slot int, fldname string, val record.Constant) error {
+	if p.layout.Schema().Type(fldname) == record.IntegerField {
+		return p.setInt(slot, fldname, val.AsInt())
+	}
+	return p.setString(slot, fldname, val.AsString())
+}
+
+func (p *BTPage) setNumRecs(n int) error {
+	return p.tx.SetInt(p.blk, BTPAGE_NUMRECS, n, true)
+}
+
+// insert makes room for a record at slot by shifting the following records right.
+func (p *BTPage) insert(slot int) error {
+	numrecs, err := p.GetNumRecs()
+	if err != nil {
+		return err
+	}
+	for i := numrecs; i > slot; i-- {
+		if err := p.copyRecord(i-1, i); err != nil {
+			return err
+		}
+	}
+	return p.setNumRecs(numrecs + 1)
+}
+
+func (p *BTPage) copyRecord(from, to int) error {
+	for _, fldname := range p.layout.Schema().Fields() {
+		val, err := p.getVal(from, fldname)
+		if err != nil {
+			return err
+		}
+		if err := p.setVal(to, fldname, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transferRecs moves the records from slot on to the empty page dest.
+func (p *BTPage) transferRecs(slot int, dest *BTPage) error {
+	numrecs, err := p.GetNumRecs()
+	if err != nil {
+		return err
+	}
+	for i := slot; i < numrecs; i++ {
+		for _, fldname := range p.layout.Schema().Fields() {
+			val, err := p.getVal(i, fldname)
+			if err != nil {
+				return err
+			}
+			if err := dest.setVal(i-slot, fldname, val); err != nil {
+				return err
+			}
+		}
+	}
+	if err := dest.setNumRecs(numrecs - slot); err != nil {
+		return err
+	}
+	return p.setNumRecs(slot)
+}
+
+func (p *BTPage) fldpos(slot int, fldname string) int {
+	return p.slotpos(slot) + p.layout.Offset(fldname)
+}
+
+func (p *BTPage) slotpos(slot int) int {
+	return BTPAGE_HEADER_SIZE + slot*p.layout.SlotSize()
+}
@@ -1,50 +1,66 @@
 package index
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/kanthorlabs/kanthorkv/file"
 	"github.com/kanthorlabs/kanthorkv/record"
 	"github.com/kanthorlabs/kanthorkv/tx/transaction"
 )
 
 var _ Index = (*ExtendableHashIndex)(nil)
 
-// NewExtendableHashIndex creates a new extendable hash index with an initial global depth of 1.
+// the directory file keeps the global depth and the number of buckets in its first block,
+// and one (bucket, local depth) pair per directory entry in the blocks after it.
+// An entry that was never written has a local depth of 0,
+// it reads as the entry it was copied from when the directory doubled.
+const (
+	EHDIR_GLOBAL_DEPTH = 0
+	EHDIR_BUCKETS      = file.INT_SIZE
+	EHDIR_ENTRY_SIZE   = 2 * file.INT_SIZE
+	// EH_MAX_GLOBAL_DEPTH bounds the directory to 2^16 entries,
+	// a full bucket that cannot split without going deeper overflows into more blocks instead
+	EH_MAX_GLOBAL_DEPTH = 16
+)
+
+// NewExtendableHashIndex opens the index idxName, reading the header of its directory from disk.
+// A new index starts with a global depth of 1 and a bucket for each of its two entries.
 func NewExtendableHashIndex(tx transaction.Transaction, idxName string, idxLayout *record.Layout) (Index, error) {
-	// Start with global depth of 1 (2 directory entries)
-	globalDepth := 1
-	dirSize := 1 << globalDepth // 2^globalDepth
-
-	// Initialize directory with bucket pointers
-	directory := make([]int, dirSize)
-	localDepths := make([]int, dirSize)
-
-	// Initially, all directory entries point to bucket 0 with local depth 1
-	for i := 0; i < dirSize; i++ {
-		directory[i] = 0
-		localDepths[i] = 1
-	}
-
-	return &ExtendableHashIndex{
-		tx:          tx,
-		idxName:     idxName,
-		idxLayout:   idxLayout,
-		globalDepth: globalDepth,
-		directory:   directory,
-		localDepths: localDepths,
-	}, nil
+	ehi := &ExtendableHashIndex{
+		tx:        tx,
+		idxName:   idxName,
+		idxLayout: idxLayout,
+		dirfile:   idxName + "dir.tbl",
+		entries:   make(map[int]ehEntry),
+	}
+	if err := ehi.loadDirectory(); err != nil {
+		return nil, err
+	}
+	return ehi, nil
 }
 
 // ExtendableHashIndex implements an extendable hash index with dynamic directory growth.
+// Each bucket is a table file, one block long unless its records cannot be split apart.
+// The directory is kept in its own file and changed in the same transaction as the buckets,
+// so the log recovers both together.
 type ExtendableHashIndex struct {
 	tx          transaction.Transaction
 	idxName     string
 	idxLayout   *record.Layout
+	dirfile     string
 	searchKey   *record.Constant
 	ts          *record.TableScan
-	globalDepth int   // number of bits used for directory indexing
-	directory   []int // maps hash values to bucket numbers
-	localDepths []int // local depth for each directory entry
+	err         error           // the error that stopped Next
+	globalDepth int             // number of bits used for directory indexing
+	buckets     int             // number of buckets, 0 until counted for a directory that did not keep it
+	entries     map[int]ehEntry // the directory entries read so far, by directory index
+}
+
+// ehEntry is a directory entry, the bucket of its hash values and the local depth of the bucket.
+type ehEntry struct {
+	bucket int
+	depth  int
 }
 
 // SearchCost returns the estimated cost of searching for a record.
@@ -61,9 +77,12 @@ func (ehi *ExtendableHashIndex) BeforeFirst(searchkey *record.Constant) error {
 	}
 
 	ehi.searchKey = searchkey
-	bucket := ehi.getBucket(searchkey.Hash())
-	tblname := fmt.Sprintf("%s%d", ehi.idxName, bucket)
-	ts, err := record.NewTableScan(ehi.tx, tblname, ehi.idxLayout)
+	ehi.err = nil
+	e, err := ehi.entry(ehi.getDirIndex(searchkey.Hash()))
+	if err != nil {
+		return err
+	}
+	ts, err := ehi.openBucket(e.bucket)
 	if err != nil {
 		return err
 	}
@@ -79,7 +98,8 @@ func (ehi *ExtendableHashIndex) Next() bool {
 	for ehi.ts.Next() {
 		dataval, err := ehi.ts.GetVal("dataval")
 		if err != nil {
-			panic(err)
+			ehi.err = err
+			return false
 		}
 		if dataval.Equal(*ehi.searchKey) {
 			return true
@@ -88,17 +108,14 @@ func (ehi *ExtendableHashIndex) Next() bool {
 	return false
 }
 
+// Err returns the error that stopped Next, if any.
+func (ehi *ExtendableHashIndex) Err() error {
+	return ehi.err
+}
+
 // GetDataRID returns the RID value stored in the current index record.
 func (ehi *ExtendableHashIndex) GetDataRID() (*record.RID, error) {
-	blknum, err := ehi.ts.GetInt("block")
-	if err != nil {
-		return nil, err
-	}
-	slot, err := ehi.ts.GetInt("id")
-	if err != nil {
-		return nil, err
-	}
-	return &record.RID{Blknum: int(blknum), Slot: int(slot)}, nil
+	return ehi.getDataRID(ehi.ts)
 }
 
 // Insert adds an index record with the specified dataval and datarid values.
@@ -113,208 +130,160 @@ func (ehi *ExtendableHashIndex) GetDataRID() (*record.RID, error) {
 //     1b_L...b_2b_1 will point to B'.
 //   - Re-insert each record from B into the index.
 //   - Try again to insert the new record into the index.
+//
+// A record only fits into the last block of a bucket. When no split can separate it from the records there,
+// the bucket grows by another block instead, so equal keys never deepen the directory.
 func (ehi *ExtendableHashIndex) Insert(dataval *record.Constant, datarid *record.RID) error {
-	return ehi.insertWithRetry(dataval, datarid, 0)
-}
-
-// insertWithRetry handles the recursive insertion logic with bucket splitting.
-func (ehi *ExtendableHashIndex) insertWithRetry(dataval *record.Constant, datarid *record.RID, depth int) error {
-	// Prevent infinite recursion
-	const maxDepth = 10
-	if depth > maxDepth {
-		return fmt.Errorf("max recursion depth exceeded during insert")
-	}
-
-	// Step 1: Hash the record's dataval to get bucket b
-	hashVal := dataval.Hash()
-	dirIndex := ehi.getDirIndex(hashVal)
-	bucketNum := ehi.directory[dirIndex]
-	localDepth := ehi.localDepths[dirIndex]
-
-	// Open the bucket's table
-	tblname := fmt.Sprintf("%s%d", ehi.idxName, bucketNum)
-	ts, err := record.NewTableScan(ehi.tx, tblname, ehi.idxLayout)
-	if err != nil {
-		return err
-	}
-	defer ts.Close()
-
-	// Step 3a: Try to insert into bucket B
-	if err := ts.Insert(); err == nil {
-		// Record fits, insert and return
-		if err := ts.SetInt("block", datarid.Blknum); err != nil {
+	for {
+		// Step 1: Hash the record's dataval to get bucket b
+		hashVal := dataval.Hash()
+		dirIndex := ehi.getDirIndex(hashVal)
+		e, err := ehi.entry(dirIndex)
+		if err != nil {
 			return err
 		}
-		if err := ts.SetInt("id", datarid.Slot); err != nil {
+
+		// Step 3a: Insert into bucket B if the record fits, or if splitting cannot make room
+		full, splittable, err := ehi.lastBlockState(e, hashVal)
+		if err != nil {
 			return err
 		}
-		if err := ts.SetVal("dataval", *dataval); err != nil {
+		if !full || !splittable {
+			return ehi.insertInto(e.bucket, *dataval, *datarid)
+		}
+
+		// Step 3b: Record does not fit, split the bucket and try again
+		if err := ehi.split(dirIndex, e); err != nil {
 			return err
 		}
-		return nil
 	}
-
-	// Step 3b: Record does not fit, need to split the bucket
-	return ehi.splitAndInsert(dirIndex, bucketNum, localDepth, dataval, datarid, depth)
 }
 
-// splitAndInsert splits a full bucket and redistributes records.
-func (ehi *ExtendableHashIndex) splitAndInsert(dirIndex, bucketNum, localDepth int, dataval *record.Constant, datarid *record.RID, depth int) error {
-	// Check if we need to double the directory
-	if localDepth == ehi.globalDepth {
-		ehi.doubleDirectory()
+// split divides a full bucket in two on the next bit of its hash values and saves the directory.
+func (ehi *ExtendableHashIndex) split(dirIndex int, e ehEntry) error {
+	// the new half of a doubled directory reads as the old one until its entries are written
+	if e.depth == ehi.globalDepth {
+		ehi.globalDepth++
 	}
 
-	// Allocate a new bucket B'
-	newBucketNum := ehi.allocateNewBucket()
-
-	// Set the local depth of both B and B' to L+1
-	newLocalDepth := localDepth + 1
-
-	// Update directory entries
-	ehi.updateDirectoryAfterSplit(bucketNum, newBucketNum, newLocalDepth)
-
-	// Re-insert all records from the old bucket
-	if err := ehi.redistributeRecords(bucketNum); err != nil {
+	newBucket, err := ehi.allocateNewBucket()
+	if err != nil {
+		return err
+	}
+	if err := ehi.splitEntries(dirIndex, e, newBucket); err != nil {
+		return err
+	}
+	// the header goes last, a directory is never read past the entries it covers
+	if err := ehi.saveHeader(); err != nil {
 		return err
 	}
 
-	// Try again to insert the new record
-	return ehi.insertWithRetry(dataval, datarid, depth+1)
+	// Re-insert all records from the old bucket
+	return ehi.redistributeRecords(e.bucket)
 }
 
-// doubleDirectory doubles the size of the directory when global depth needs to increase.
-func (ehi *ExtendableHashIndex) doubleDirectory() {
-	ehi.globalDepth++
-	oldSize := len(ehi.directory)
-	newSize := 1 << ehi.globalDepth
-
-	newDirectory := make([]int, newSize)
-	newLocalDepths := make([]int, newSize)
-
-	// Copy existing entries and duplicate them
-	for i := range oldSize {
-		newDirectory[i] = ehi.directory[i]
-		newDirectory[i+oldSize] = ehi.directory[i]
-		newLocalDepths[i] = ehi.localDepths[i]
-		newLocalDepths[i+oldSize] = ehi.localDepths[i]
+// lastBlockState reads the records of the last block of the bucket of e.
+// It reports whether the block is full, and whether a split may separate a record with hashVal from them.
+func (ehi *ExtendableHashIndex) lastBlockState(e ehEntry, hashVal int) (bool, bool, error) {
+	ts, err := ehi.openLastBlock(e.bucket)
+	if err != nil {
+		return false, false, err
 	}
+	defer ts.Close()
 
-	ehi.directory = newDirectory
-	ehi.localDepths = newLocalDepths
+	// the records of a bucket agree on its depth bits, the directory only tells the bits below EH_MAX_GLOBAL_DEPTH apart
+	mask := 1<<EH_MAX_GLOBAL_DEPTH - 1
+	count, splittable := 0, false
+	for ts.Next() {
+		dataval, err := ts.GetVal("dataval")
+		if err != nil {
+			return false, false, err
+		}
+		count++
+		splittable = splittable || (dataval.Hash()^hashVal)&mask != 0
+	}
+	full := count >= ehi.tx.BlockSize()/ehi.idxLayout.SlotSize()
+	return full, splittable && e.depth < EH_MAX_GLOBAL_DEPTH, nil
 }
 
 // allocateNewBucket returns a new unique bucket number.
-func (ehi *ExtendableHashIndex) allocateNewBucket() int {
-	// Find the maximum bucket number and add 1
-	maxBucket := 0
-	for _, bucket := range ehi.directory {
-		if bucket > maxBucket {
-			maxBucket = bucket
+func (ehi *ExtendableHashIndex) allocateNewBucket() (int, error) {
+	if ehi.buckets == 0 {
+		// every bucket of a directory saved without its count is in one of the entries
+		for i := range 1 << ehi.globalDepth {
+			e, err := ehi.entry(i)
+			if err != nil {
+				return 0, err
+			}
+			ehi.buckets = max(ehi.buckets, e.bucket+1)
 		}
 	}
-	return maxBucket + 1
+	ehi.buckets++
+	return ehi.buckets - 1, nil
 }
 
-// updateDirectoryAfterSplit updates directory entries to point to the new bucket.
-func (ehi *ExtendableHashIndex) updateDirectoryAfterSplit(oldBucket, newBucket, newLocalDepth int) {
-	// Get the hash pattern for this directory index
-	mask := (1 << newLocalDepth) - 1
-
-	// Update all directory entries that should point to the new bucket
-	for i := 0; i < len(ehi.directory); i++ {
-		if ehi.directory[i] == oldBucket {
-			// Check if the rightmost newLocalDepth bits match the pattern
-			// that should go to the new bucket
-			if (i & mask) >= (1 << (newLocalDepth - 1)) {
-				ehi.directory[i] = newBucket
-			}
-			ehi.localDepths[i] = newLocalDepth
+// splitEntries points the entries of the bucket of e that have bit e.depth set to newBucket,
+// and deepens every entry of both buckets by one bit.
+func (ehi *ExtendableHashIndex) splitEntries(dirIndex int, e ehEntry, newBucket int) error {
+	// the entries of a bucket share its depth bits, the ones of dirIndex
+	step := 1 << e.depth
+	for i := dirIndex & (step - 1); i < 1<<ehi.globalDepth; i += step {
+		ne := ehEntry{bucket: e.bucket, depth: e.depth + 1}
+		if i&step != 0 {
+			ne.bucket = newBucket
+		}
+		if err := ehi.setEntry(i, ne); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-// redistributeRecords re-inserts all records from a bucket into the index.
+// redistributeRecords moves the records of a bucket that belong to another bucket now.
 func (ehi *ExtendableHashIndex) redistributeRecords(bucketNum int) error {
-	tblname := fmt.Sprintf("%s%d", ehi.idxName, bucketNum)
-	ts, err := record.NewTableScan(ehi.tx, tblname, ehi.idxLayout)
+	ts, err := ehi.openBucket(bucketNum)
 	if err != nil {
 		return err
 	}
-	defer ts.Close()
 
-	// Collect all records first to avoid concurrent modification
+	// Collect the records that belong to another bucket now, and remove them from this one
 	type recordEntry struct {
 		dataval record.Constant
 		rid     record.RID
+		bucket  int
 	}
-	var records []recordEntry
+	var moved []recordEntry
 
-	ts.BeforeFirst()
 	for ts.Next() {
 		dataval, err := ts.GetVal("dataval")
 		if err != nil {
-			return err
+			return errors.Join(err, ts.Close())
 		}
-		rid, err := ehi.GetDataRID()
+		e, err := ehi.entry(ehi.getDirIndex(dataval.Hash()))
 		if err != nil {
-			return err
+			return errors.Join(err, ts.Close())
 		}
-		records = append(records, recordEntry{dataval: dataval, rid: *rid})
-	}
-
-	// Clear the old bucket by deleting all records
-	ts.BeforeFirst()
-	for ts.Next() {
+		if e.bucket == bucketNum {
+			continue
+		}
+		rid, err := ehi.getDataRID(ts)
+		if err != nil {
+			return errors.Join(err, ts.Close())
+		}
+		moved = append(moved, recordEntry{dataval: dataval, rid: *rid, bucket: e.bucket})
 		if err := ts.Delete(); err != nil {
-			return err
+			return errors.Join(err, ts.Close())
 		}
 	}
+	if err := ts.Close(); err != nil {
+		return err
+	}
 
-	// Re-insert records into appropriate buckets
-	for _, rec := range records {
-		newBucketNum := ehi.getBucket(rec.dataval.Hash())
-		if newBucketNum == bucketNum {
-			// Record stays in the same bucket, insert directly
-			if err := ts.Insert(); err != nil {
-				return err
-			}
-			if err := ts.SetInt("block", rec.rid.Blknum); err != nil {
-				return err
-			}
-			if err := ts.SetInt("id", rec.rid.Slot); err != nil {
-				return err
-			}
-			if err := ts.SetVal("dataval", rec.dataval); err != nil {
-				return err
-			}
-		} else {
-			// Record goes to a different bucket
-			newTblname := fmt.Sprintf("%s%d", ehi.idxName, newBucketNum)
-			newTs, err := record.NewTableScan(ehi.tx, newTblname, ehi.idxLayout)
-			if err != nil {
-				return err
-			}
-			if err := newTs.Insert(); err != nil {
-				newTs.Close()
-				return err
-			}
-			if err := newTs.SetInt("block", rec.rid.Blknum); err != nil {
-				newTs.Close()
-				return err
-			}
-			if err := newTs.SetInt("id", rec.rid.Slot); err != nil {
-				newTs.Close()
-				return err
-			}
-			if err := newTs.SetVal("dataval", rec.dataval); err != nil {
-				newTs.Close()
-				return err
-			}
-			newTs.Close()
+	for _, rec := range moved {
+		if err := ehi.insertInto(rec.bucket, rec.dataval, rec.rid); err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
 
@@ -333,7 +302,7 @@ func (ehi *ExtendableHashIndex) Delete(dataval *record.Constant, datarid *record
 			return ehi.ts.Delete()
 		}
 	}
-	return nil
+	return ehi.err
 }
 
 // Close closes the index.
@@ -353,8 +322,184 @@ func (ehi *ExtendableHashIndex) getDirIndex(hashVal int) int {
 	return hashVal & mask
 }
 
-// getBucket returns the bucket number for a given hash value.
-func (ehi *ExtendableHashIndex) getBucket(hashVal int) int {
-	dirIndex := ehi.getDirIndex(hashVal)
-	return ehi.directory[dirIndex]
+func (ehi *ExtendableHashIndex) getDataRID(ts *record.TableScan) (*record.RID, error) {
+	blknum, err := ts.GetInt("block")
+	if err != nil {
+		return nil, err
+	}
+	slot, err := ts.GetInt("id")
+	if err != nil {
+		return nil, err
+	}
+	return &record.RID{Blknum: blknum, Slot: slot}, nil
+}
+
+func (ehi *ExtendableHashIndex) openBucket(bucketNum int) (*record.TableScan, error) {
+	tblname := fmt.Sprintf("%s%d", ehi.idxName, bucketNum)
+	return record.NewTableScan(ehi.tx, tblname, ehi.idxLayout)
+}
+
+// openLastBlock opens a bucket positioned before the records of its last block.
+func (ehi *ExtendableHashIndex) openLastBlock(bucketNum int) (*record.TableScan, error) {
+	ts, err := ehi.openBucket(bucketNum)
+	if err != nil {
+		return nil, err
+	}
+	size, err := ehi.tx.Size(fmt.Sprintf("%s%d.tbl", ehi.idxName, bucketNum))
+	if err != nil {
+		return nil, errors.Join(err, ts.Close())
+	}
+	if err := ts.MoveToRid(record.NewRID(size-1, -1)); err != nil {
+		return nil, errors.Join(err, ts.Close())
+	}
+	return ts, nil
+}
+
+// insertInto adds an index record to the last block of a bucket, or to a new block after it.
+func (ehi *ExtendableHashIndex) insertInto(bucketNum int, dataval record.Constant, datarid record.RID) error {
+	ts, err := ehi.openLastBlock(bucketNum)
+	if err != nil {
+		return err
+	}
+	if err := ts.Insert(); err != nil {
+		return errors.Join(err, ts.Close())
+	}
+	if err := ts.SetInt("block", datarid.Blknum); err != nil {
+		return errors.Join(err, ts.Close())
+	}
+	if err := ts.SetInt("id", datarid.Slot); err != nil {
+		return errors.Join(err, ts.Close())
+	}
+	if err := ts.SetVal("dataval", dataval); err != nil {
+		return errors.Join(err, ts.Close())
+	}
+	return ts.Close()
+}
+
+// loadDirectory reads the header of the directory file, creating it for a new index.
+// Entries are only read when a hash value leads to them.
+func (ehi *ExtendableHashIndex) loadDirectory() error {
+	header := file.NewBlockId(ehi.dirfile, 0)
+	size, err := ehi.tx.Size(ehi.dirfile)
+	if err != nil {
+		return err
+	}
+	if size > 0 {
+		if ehi.globalDepth, err = ehi.readInt(header, EHDIR_GLOBAL_DEPTH); err != nil {
+			return err
+		}
+		if ehi.buckets, err = ehi.readInt(header, EHDIR_BUCKETS); err != nil {
+			return err
+		}
+	}
+
+	// a missing file, or one whose creation was rolled back, holds a depth of 0
+	if ehi.globalDepth > 0 {
+		return nil
+	}
+	ehi.globalDepth, ehi.buckets = 1, 2
+	for i := range 2 {
+		if err := ehi.setEntry(i, ehEntry{bucket: i, depth: 1}); err != nil {
+			return err
+		}
+	}
+	return ehi.saveHeader()
+}
+
+// entry returns directory entry i, reading it from the directory file the first time.
+// An entry that was never written is the one of the same index without its highest bit, at one depth less.
+func (ehi *ExtendableHashIndex) entry(i int) (ehEntry, error) {
+	if e, ok := ehi.entries[i]; ok {
+		return e, nil
+	}
+
+	size, err := ehi.tx.Size(ehi.dirfile)
+	if err != nil {
+		return ehEntry{}, err
+	}
+	for depth := ehi.globalDepth; depth > 0; depth-- {
+		j := i & (1<<depth - 1)
+		e, ok := ehi.entries[j]
+		if !ok {
+			blk, offset := ehi.entryPos(j)
+			if blk.Number() >= size {
+				continue
+			}
+			if e.depth, err = ehi.readInt(blk, offset+file.INT_SIZE); err != nil {
+				return ehEntry{}, err
+			}
+			if e.depth == 0 {
+				continue
+			}
+			if e.bucket, err = ehi.readInt(blk, offset); err != nil {
+				return ehEntry{}, err
+			}
+			ehi.entries[j] = e
+		}
+		ehi.entries[i] = e
+		return e, nil
+	}
+	return ehEntry{}, fmt.Errorf("extendable hash directory %s has no entry %d", ehi.dirfile, i)
+}
+
+// setEntry writes directory entry i, growing the directory file to hold it.
+func (ehi *ExtendableHashIndex) setEntry(i int, e ehEntry) error {
+	blk, offset := ehi.entryPos(i)
+	for {
+		size, err := ehi.tx.Size(ehi.dirfile)
+		if err != nil {
+			return err
+		}
+		if size > blk.Number() {
+			break
+		}
+		if _, err := ehi.tx.Append(ehi.dirfile); err != nil {
+			return err
+		}
+	}
+
+	if err := ehi.writeInt(blk, offset, e.bucket); err != nil {
+		return err
+	}
+	if err := ehi.writeInt(blk, offset+file.INT_SIZE, e.depth); err != nil {
+		return err
+	}
+	ehi.entries[i] = e
+	return nil
+}
+
+// saveHeader writes the global depth and the number of buckets, every write is logged.
+func (ehi *ExtendableHashIndex) saveHeader() error {
+	header := file.NewBlockId(ehi.dirfile, 0)
+	if err := ehi.writeInt(header, EHDIR_BUCKETS, ehi.buckets); err != nil {
+		return err
+	}
+	return ehi.writeInt(header, EHDIR_GLOBAL_DEPTH, ehi.globalDepth)
+}
+
+// entryPos returns where directory entry i is stored, the first block only holds the header.
+func (ehi *ExtendableHashIndex) entryPos(i int) (*file.BlockId, int) {
+	perBlock := ehi.tx.BlockSize() / EHDIR_ENTRY_SIZE
+	return file.NewBlockId(ehi.dirfile, 1+i/perBlock), (i % perBlock) * EHDIR_ENTRY_SIZE
+}
+
+func (ehi *ExtendableHashIndex) readInt(blk *file.BlockId, offset int) (int, error) {
+	if err := ehi.tx.Pin(blk); err != nil {
+		return 0, err
+	}
+	defer ehi.tx.Unpin(blk)
+	return ehi.tx.GetInt(blk, offset)
+}
+
+func (ehi *ExtendableHashIndex) writeInt(blk *file.BlockId, offset, val int) error {
+	if err := ehi.tx.Pin(blk); err != nil {
+		return err
+	}
+	defer ehi.tx.Unpin(blk)
+
+	old, err := ehi.tx.GetInt(blk, offset)
+	if err != nil || old == val {
+		return err
+	}
+	return ehi.tx.SetInt(blk, offset, val, true)
 }
@@ -2,6 +2,11 @@ package index
 
 import (
 	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExtendableHashIndex_getDirIndex(t *testing.T) {
@@ -62,88 +67,248 @@ func TestExtendableHashIndex_getDirIndex(t *testing.T) {
 	}
 }
 
-func TestExtendableHashIndex_doubleDirectory(t *testing.T) {
+func TestExtendableHashIndex_entry(t *testing.T) {
+	newtx, cleanup := setupTest(t)
+	defer cleanup()
+
+	tx := newtx()
+	idx, err := NewExtendableHashIndex(tx, "idx", testLeafLayout(record.IntegerField, 0))
+	require.NoError(t, err)
+	ehi := idx.(*ExtendableHashIndex)
+
+	// the new half of a doubled directory reads as the old one, until its entries are written
+	ehi.globalDepth = 3
+	require.NoError(t, ehi.setEntry(6, ehEntry{bucket: 2, depth: 3}))
+	ehi.entries = make(map[int]ehEntry)
+
+	want := []ehEntry{{0, 1}, {1, 1}, {0, 1}, {1, 1}, {0, 1}, {1, 1}, {2, 3}, {1, 1}}
+	for i := range want {
+		e, err := ehi.entry(i)
+		require.NoError(t, err)
+		require.Equal(t, want[i], e, "entry %d", i)
+	}
+	require.NoError(t, tx.Commit())
+}
+
+func TestExtendableHashIndex_allocateNewBucket(t *testing.T) {
 	ehi := &ExtendableHashIndex{
-		globalDepth: 1,
-		directory:   []int{0, 1},
-		localDepths: []int{1, 1},
+		buckets: 3,
 	}
 
-	ehi.doubleDirectory()
+	got, err := ehi.allocateNewBucket()
+	require.NoError(t, err)
+	require.Equal(t, 3, got)
+	require.Equal(t, 4, ehi.buckets)
+}
+
+func TestExtendableHashIndex_splitEntries(t *testing.T) {
+	newtx, cleanup := setupTest(t)
+	defer cleanup()
+
+	tx := newtx()
+	idx, err := NewExtendableHashIndex(tx, "idx", testLeafLayout(record.IntegerField, 0))
+	require.NoError(t, err)
+	ehi := idx.(*ExtendableHashIndex)
+	ehi.globalDepth = 3
+
+	// Split bucket 0 with local depth 1, reached through entry 4, to create bucket 2
+	require.NoError(t, ehi.splitEntries(4, ehEntry{bucket: 0, depth: 1}, 2))
 
-	if ehi.globalDepth != 2 {
-		t.Errorf("globalDepth = %v, want 2", ehi.globalDepth)
+	// Only the entries of bucket 0 change, the ones with bit 1 set point to the new bucket
+	// Binary: 000 -> 0, 010 -> 2, 100 -> 0, 110 -> 2
+	want := []ehEntry{{0, 2}, {1, 1}, {2, 2}, {1, 1}, {0, 2}, {1, 1}, {2, 2}, {1, 1}}
+	for i := range want {
+		e, err := ehi.entry(i)
+		require.NoError(t, err)
+		require.Equal(t, want[i], e, "entry %d", i)
 	}
+	require.NoError(t, tx.Commit())
+}
 
-	wantDir := []int{0, 1, 0, 1}
-	if len(ehi.directory) != len(wantDir) {
-		t.Errorf("directory length = %v, want %v", len(ehi.directory), len(wantDir))
+func TestExtendableHashIndex_SearchCost(t *testing.T) {
+	ehi := &ExtendableHashIndex{}
+
+	// Extendable hash should always be 2 (directory + bucket)
+	cost := ehi.SearchCost(100, 10)
+	if cost != 2 {
+		t.Errorf("SearchCost() = %v, want 2", cost)
 	}
-	for i := range wantDir {
-		if ehi.directory[i] != wantDir[i] {
-			t.Errorf("directory[%d] = %v, want %v", i, ehi.directory[i], wantDir[i])
-		}
+}
+
+// dirEntries reads every entry of the directory of ehi
+func dirEntries(t *testing.T, ehi *ExtendableHashIndex) []ehEntry {
+	entries := make([]ehEntry, 0, 1<<ehi.globalDepth)
+	for i := range 1 << ehi.globalDepth {
+		e, err := ehi.entry(i)
+		require.NoError(t, err)
+		entries = append(entries, e)
 	}
+	return entries
+}
 
-	wantDepths := []int{1, 1, 1, 1}
-	if len(ehi.localDepths) != len(wantDepths) {
-		t.Errorf("localDepths length = %v, want %v", len(ehi.localDepths), len(wantDepths))
+// countKey returns how many index records hold key, each checked against the rid it was inserted with
+func countKey(t *testing.T, idx Index, key int) int {
+	val := record.NewIntConstant(key)
+	require.NoError(t, idx.BeforeFirst(&val))
+	count := 0
+	for idx.Next() {
+		rid, err := idx.GetDataRID()
+		require.NoError(t, err)
+		require.Equal(t, key, rid.Blknum)
+		count++
 	}
-	for i := range wantDepths {
-		if ehi.localDepths[i] != wantDepths[i] {
-			t.Errorf("localDepths[%d] = %v, want %v", i, ehi.localDepths[i], wantDepths[i])
-		}
+	return count
+}
+
+func TestExtendableHashIndex_Reopen(t *testing.T) {
+	newtx, cleanup := setupTest(t)
+	defer cleanup()
+	layout := testLeafLayout(record.IntegerField, 0)
+
+	tx := newtx()
+	idx, err := NewExtendableHashIndex(tx, "idx", layout)
+	require.NoError(t, err)
+	for i := range 500 {
+		val := record.NewIntConstant(i)
+		require.NoError(t, idx.Insert(&val, &record.RID{Blknum: i, Slot: 0}))
 	}
+	ehi := idx.(*ExtendableHashIndex)
+	require.Greater(t, ehi.globalDepth, 1)
+	globalDepth, entries := ehi.globalDepth, dirEntries(t, ehi)
+	require.NoError(t, idx.Close())
+	require.NoError(t, tx.Commit())
+
+	tx = newtx()
+	idx, err = NewExtendableHashIndex(tx, "idx", layout)
+	require.NoError(t, err)
+	reopened := idx.(*ExtendableHashIndex)
+	require.Equal(t, globalDepth, reopened.globalDepth)
+	require.Equal(t, entries, dirEntries(t, reopened))
+	for i := range 500 {
+		require.Equal(t, 1, countKey(t, idx, i), "key %d", i)
+	}
+	require.NoError(t, idx.Close())
+	require.NoError(t, tx.Commit())
 }
 
-func TestExtendableHashIndex_allocateNewBucket(t *testing.T) {
-	ehi := &ExtendableHashIndex{
-		directory: []int{0, 1, 2, 1},
+func TestExtendableHashIndex_Rollback(t *testing.T) {
+	newtx, cleanup := setupTest(t)
+	defer cleanup()
+	layout := testLeafLayout(record.IntegerField, 0)
+
+	tx := newtx()
+	idx, err := NewExtendableHashIndex(tx, "idx", layout)
+	require.NoError(t, err)
+	for i := range 50 {
+		val := record.NewIntConstant(i)
+		require.NoError(t, idx.Insert(&val, &record.RID{Blknum: i, Slot: 0}))
 	}
+	ehi := idx.(*ExtendableHashIndex)
+	globalDepth, entries := ehi.globalDepth, dirEntries(t, ehi)
+	require.NoError(t, idx.Close())
+	require.NoError(t, tx.Commit())
 
-	got := ehi.allocateNewBucket()
-	want := 3 // max is 2, so next should be 3
+	// the splits of a rolled back transaction leave the directory as it was
+	tx = newtx()
+	idx, err = NewExtendableHashIndex(tx, "idx", layout)
+	require.NoError(t, err)
+	for i := 50; i < 500; i++ {
+		val := record.NewIntConstant(i)
+		require.NoError(t, idx.Insert(&val, &record.RID{Blknum: i, Slot: 0}))
+	}
+	require.Greater(t, idx.(*ExtendableHashIndex).globalDepth, globalDepth)
+	require.NoError(t, idx.Close())
+	require.NoError(t, tx.Rollback())
 
-	if got != want {
-		t.Errorf("allocateNewBucket() = %v, want %v", got, want)
+	tx = newtx()
+	idx, err = NewExtendableHashIndex(tx, "idx", layout)
+	require.NoError(t, err)
+	reopened := idx.(*ExtendableHashIndex)
+	require.Equal(t, globalDepth, reopened.globalDepth)
+	require.Equal(t, entries, dirEntries(t, reopened))
+	for i := range 50 {
+		require.Equal(t, 1, countKey(t, idx, i), "key %d", i)
 	}
+	require.Equal(t, 0, countKey(t, idx, 50))
+	require.NoError(t, idx.Close())
+	require.NoError(t, tx.Commit())
 }
 
-func TestExtendableHashIndex_updateDirectoryAfterSplit(t *testing.T) {
-	ehi := &ExtendableHashIndex{
-		globalDepth: 2,
-		directory:   []int{0, 0, 0, 0}, // all pointing to bucket 0
-		localDepths: []int{1, 1, 1, 1},
-	}
-
-	// Split bucket 0 with local depth 1 to create bucket 1
-	// With newLocalDepth = 2
-	ehi.updateDirectoryAfterSplit(0, 1, 2)
-
-	// After split with depth 2:
-	// Entries with rightmost 2 bits >= 2 should point to new bucket
-	// Binary: 00 -> 0, 01 -> 0, 10 -> 1, 11 -> 1
-	wantDir := []int{0, 0, 1, 1}
-	for i := range wantDir {
-		if ehi.directory[i] != wantDir[i] {
-			t.Errorf("directory[%d] = %v, want %v", i, ehi.directory[i], wantDir[i])
-		}
+func TestExtendableHashIndex_Duplicates(t *testing.T) {
+	newtx, cleanup := setupTest(t)
+	defer cleanup()
+
+	tx := newtx()
+	idx, err := NewExtendableHashIndex(tx, "idx", testLeafLayout(record.IntegerField, 0))
+	require.NoError(t, err)
+
+	// more copies of one key than a bucket holds overflow instead of splitting forever
+	for i := range 100 {
+		val := record.NewIntConstant(7)
+		require.NoError(t, idx.Insert(&val, &record.RID{Blknum: 7, Slot: i}))
 	}
+	require.Equal(t, 1, idx.(*ExtendableHashIndex).globalDepth)
+	require.Equal(t, 100, countKey(t, idx, 7))
+
+	require.NoError(t, idx.Close())
+	require.NoError(t, tx.Commit())
+}
+
+// pinCounter counts the blocks a transaction pins
+type pinCounter struct {
+	transaction.Transaction
+	pins int
+}
+
+func (pc *pinCounter) Pin(blk *file.BlockId) error {
+	pc.pins++
+	return pc.Transaction.Pin(blk)
+}
+
+func TestExtendableHashIndex_Skewed(t *testing.T) {
+	newtx, cleanup := setupTest(t)
+	defer cleanup()
 
-	wantDepths := []int{2, 2, 2, 2}
-	for i := range wantDepths {
-		if ehi.localDepths[i] != wantDepths[i] {
-			t.Errorf("localDepths[%d] = %v, want %v", i, ehi.localDepths[i], wantDepths[i])
+	tx := &pinCounter{Transaction: newtx()}
+	idx, err := NewExtendableHashIndex(tx, "idx", testLeafLayout(record.IntegerField, 0))
+	require.NoError(t, err)
+
+	// most records hold one of a few keys, two of them only differ above the bits the directory can use
+	keys := []int{3, 5, 3 + 1<<EH_MAX_GLOBAL_DEPTH}
+	inserted := make(map[int]int)
+	insert := func(key int) {
+		val := record.NewIntConstant(key)
+		require.NoError(t, idx.Insert(&val, &record.RID{Blknum: key, Slot: inserted[key]}))
+		inserted[key]++
+	}
+	for i := range 2000 {
+		insert(keys[i%len(keys)])
+		if i%20 == 0 {
+			insert(1000 + i)
 		}
 	}
-}
+	ehi := idx.(*ExtendableHashIndex)
+	require.LessOrEqual(t, ehi.globalDepth, 8)
 
-func TestExtendableHashIndex_SearchCost(t *testing.T) {
-	ehi := &ExtendableHashIndex{}
+	// an insert reads the directory entry and the last block of a bucket, however long the bucket is
+	tx.pins = 0
+	for range 100 {
+		insert(keys[0])
+	}
+	require.LessOrEqual(t, tx.pins, 100*5)
 
-	// Extendable hash should always be 2 (directory + bucket)
-	cost := ehi.SearchCost(100, 10)
-	if cost != 2 {
-		t.Errorf("SearchCost() = %v, want 2", cost)
+	for key, count := range inserted {
+		require.Equal(t, count, countKey(t, idx, key), "key %d", key)
 	}
+
+	// reopening reads the header, not the directory
+	require.NoError(t, idx.Close())
+	tx.pins = 0
+	idx, err = NewExtendableHashIndex(tx, "idx", testLeafLayout(record.IntegerField, 0))
+	require.NoError(t, err)
+	require.LessOrEqual(t, tx.pins, 2)
+	require.Equal(t, inserted[keys[1]], countKey(t, idx, keys[1]))
+
+	require.NoError(t, idx.Close())
+	require.NoError(t, tx.Commit())
 }
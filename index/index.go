@@ -2,6 +2,16 @@ package index
 
 import "github.com/kanthorlabs/kanthorkv/record"
 
+// kinds of index a CREATE INDEX statement can ask for
+const (
+	TYPE_HASH    = "hash"
+	TYPE_BTREE   = "btree"
+	TYPE_EXTHASH = "exthash"
+)
+
+// Types lists every supported kind of index, the first one is the default.
+var Types = []string{TYPE_HASH, TYPE_BTREE, TYPE_EXTHASH}
+
 type Index interface {
 	SearchCost(numblocks, rpb int) int
 
@@ -11,9 +21,12 @@ type Index interface {
 
 	// Next moves the index to the next record having the search key
 	// specified in BeforeFirst. Returns false if there are no more
-	// such index records.
+	// such index records, or on an error that Err then returns.
 	Next() bool
 
+	// Err returns the error that stopped Next, if any.
+	Err() error
+
 	// GetDataRID returns the RID value stored in the current index record.
 	GetDataRID() (*record.RID, error)
 
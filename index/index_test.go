@@ -0,0 +1,76 @@
+package index
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaswdr/faker/v2"
+	"github.com/kanthorlabs/kanthorkv/buffer"
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/log"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx"
+	"github.com/kanthorlabs/kanthorkv/tx/concurrency"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBlockSize  = 400
+	testBufferSize = 16
+	testMaxTime    = time.Second * 10
+	testLogFile    = "testlog"
+)
+
+var (
+	fk     faker.Faker
+	fkOnce sync.Once
+)
+
+func init() {
+	fkOnce.Do(func() {
+		fk = faker.New()
+	})
+}
+
+func testdir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "kanthorkv-test-")
+	require.NoError(t, err)
+	return dir
+}
+
+// setupTest returns a function that starts transactions on a fresh directory with small blocks,
+// so a few hundred records are enough to split every level of an index
+func setupTest(t *testing.T) (func() transaction.Transaction, func()) {
+	dir := testdir(t)
+
+	fm, err := file.NewFileManager(dir, testBlockSize)
+	require.NoError(t, err)
+	lm, err := log.NewLogManager(fm, testLogFile)
+	require.NoError(t, err)
+	bm, err := buffer.NewBufferManager(fm, lm, testBufferSize, testMaxTime)
+	require.NoError(t, err)
+	lt := concurrency.NewLockTable()
+
+	newtx := func() transaction.Transaction {
+		tx, err := tx.NewTransaction(fm, lm, bm, lt)
+		require.NoError(t, err)
+		return tx
+	}
+	cleanup := func() {
+		fm.Close()
+		os.RemoveAll(dir)
+	}
+	return newtx, cleanup
+}
+
+// testLeafLayout is the layout of the records of an index on a field of the given type
+func testLeafLayout(fldtype record.FieldType, length int) *record.Layout {
+	sch := record.NewSchema()
+	sch.AddIntField("block")
+	sch.AddIntField("id")
+	sch.AddField("dataval", fldtype, length)
+	return record.NewLayoutOfSchema(sch)
+}
@@ -9,9 +9,12 @@ import (
 
 var _ Index = (*StaticHashIndex)(nil)
 
+// STATIC_HASH_NUM_BUCKETS is the number of bucket files of a static hash index
+const STATIC_HASH_NUM_BUCKETS = 100
+
 func NewStaticHashIndex(tx transaction.Transaction, idxName string, idxLayout *record.Layout) (Index, error) {
 	return &StaticHashIndex{
-		NumBuckets: 100, // default number of buckets
+		NumBuckets: STATIC_HASH_NUM_BUCKETS,
 		tx:         tx,
 		idxName:    idxName,
 		idxLayout:  idxLayout,
@@ -26,6 +29,7 @@ type StaticHashIndex struct {
 	idxLayout *record.Layout
 	searchKey *record.Constant
 	ts        *record.TableScan
+	err       error
 }
 
 func (hi *StaticHashIndex) SearchCost(numblocks, rpb int) int {
@@ -38,6 +42,7 @@ func (hi *StaticHashIndex) BeforeFirst(searchkey *record.Constant) error {
 	}
 
 	hi.searchKey = searchkey
+	hi.err = nil
 	bucket := searchkey.Hash() % hi.NumBuckets
 	tblname := fmt.Sprintf("%s%d", hi.idxName, bucket)
 	ts, err := record.NewTableScan(hi.tx, tblname, hi.idxLayout)
@@ -55,7 +60,8 @@ func (hi *StaticHashIndex) Next() bool {
 	for hi.ts.Next() {
 		dataval, err := hi.ts.GetVal("dataval")
 		if err != nil {
-			panic(err)
+			hi.err = err
+			return false
 		}
 		if dataval.Equal(*hi.searchKey) {
 			return true
@@ -110,7 +116,11 @@ func (hi *StaticHashIndex) Delete(val *record.Constant, rid *record.RID) error {
 			return hi.ts.Delete()
 		}
 	}
-	return nil
+	return hi.err
+}
+
+func (hi *StaticHashIndex) Err() error {
+	return hi.err
 }
 
 func (hi *StaticHashIndex) Close() (err error) {
@@ -0,0 +1,134 @@
+package kanthorkv
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jaswdr/faker/v2"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/plan"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fk     faker.Faker
+	fkOnce sync.Once
+)
+
+func init() {
+	fkOnce.Do(func() {
+		fk = faker.New()
+	})
+}
+
+func testdir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "kanthorkv-test-")
+	require.NoError(t, err)
+	return dir
+}
+
+// testdb opens a database in a fresh directory and starts the transaction a test runs in,
+// when the test ends the transaction is committed, the database closed and the directory removed
+func testdb(t *testing.T, opts Options) (*DB, transaction.Transaction) {
+	dir := testdir(t)
+	db, err := Open(dir, opts)
+	require.NoError(t, err)
+	tx, err := db.NewTx()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, tx.Commit())
+		require.NoError(t, db.Close())
+		os.RemoveAll(dir)
+	})
+	return db, tx
+}
+
+// exec runs the update statements sqls
+func exec(t *testing.T, db *DB, tx transaction.Transaction, sqls ...string) {
+	for _, sql := range sqls {
+		_, err := db.Exec(tx, sql)
+		require.NoError(t, err, sql)
+	}
+}
+
+// createStudentDept creates the tables student (sid, sname, majorid), with a student sNN of major majors[NN] for every NN,
+// and dept (did, dname), with a department dN for every did N of dids
+func createStudentDept(t *testing.T, db *DB, tx transaction.Transaction, majors, dids []int) {
+	exec(t, db, tx, "create table student (sid int, sname varchar(10), majorid int)", "create table dept (did int, dname varchar(10))")
+	for sid, major := range majors {
+		exec(t, db, tx, fmt.Sprintf("insert into student (sid, sname, majorid) values (%d, 's%02d', %d)", sid, sid, major))
+	}
+	for _, did := range dids {
+		exec(t, db, tx, fmt.Sprintf("insert into dept (did, dname) values (%d, 'd%d')", did, did))
+	}
+}
+
+// insertUnindexed inserts a record into the table tblname behind the back of its indexes,
+// it is only found by reading the table
+func insertUnindexed(t *testing.T, db *DB, tx transaction.Transaction, tblname string, vals map[string]record.Constant) {
+	layout, err := db.Metadata().GetLayout(tblname, tx)
+	require.NoError(t, err)
+	ts, err := record.NewTableScan(tx, tblname, layout)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	require.NoError(t, ts.Insert())
+	for fldname, val := range vals {
+		require.NoError(t, ts.SetVal(fldname, val))
+	}
+}
+
+// readRows reads the rest of s, a row holds the values of fields separated by spaces, strings are quoted
+func readRows(t *testing.T, s record.Scan, fields ...string) []string {
+	rows := make([]string, 0)
+	for s.Next() {
+		row := make([]string, 0, len(fields))
+		for _, fldname := range fields {
+			val, err := s.GetVal(fldname)
+			require.NoError(t, err)
+			row = append(row, val.String())
+		}
+		rows = append(rows, strings.Join(row, " "))
+	}
+	return rows
+}
+
+// queryRows runs the query sql and returns the values of fields, or of its select list when no fields are given
+func queryRows(t *testing.T, db *DB, tx transaction.Transaction, sql string, fields ...string) []string {
+	if len(fields) == 0 {
+		data, err := parser.New(parser.NewLexer(sql)).Query()
+		require.NoError(t, err, sql)
+		fields = data.Fields
+	}
+	s, err := db.Query(tx, sql)
+	require.NoError(t, err, sql)
+	defer s.Close()
+	return readRows(t, s, fields...)
+}
+
+// planRows plans the query sql with qp and returns the values of its select list
+func planRows(t *testing.T, tx transaction.Transaction, qp plan.QueryPlanner, sql string) []string {
+	data, err := parser.New(parser.NewLexer(sql)).Query()
+	require.NoError(t, err, sql)
+	p, err := qp.CreatePlan(data, tx)
+	require.NoError(t, err, sql)
+	s, err := p.Open()
+	require.NoError(t, err, sql)
+	defer s.Close()
+	return readRows(t, s, data.Fields...)
+}
+
+// queryPlanners are the query planners that every query must give the same records with
+func queryPlanners(db *DB) []plan.QueryPlanner {
+	return []plan.QueryPlanner{
+		plan.NewBasicQueryPlanner(db.Metadata()),
+		plan.NewHeuristicQueryPlanner(db.Metadata()),
+		plan.NewDPQueryPlanner(db.Metadata(), 0),
+	}
+}
@@ -0,0 +1,43 @@
+package kv
+
+import (
+	"fmt"
+	"strings"
+)
+
+var basename = "KANTHORKV.KV"
+
+func Errf(err string, args ...string) error {
+	return fmt.Errorf("%s.%s: %s", basename, err, strings.Join(args, " | "))
+}
+
+func ErrInvalidName(name string) error {
+	args := []string{
+		fmt.Sprintf("name=%q", name),
+	}
+	return Errf("STORE.INVALID_NAME", args...)
+}
+
+func ErrBlockTooSmall(blksize, slotsize int) error {
+	args := []string{
+		fmt.Sprintf("blksize=%d", blksize),
+		fmt.Sprintf("slotsize=%d", slotsize),
+	}
+	return Errf("STORE.BLOCK_TOO_SMALL", args...)
+}
+
+func ErrKeyTooLarge(size int) error {
+	args := []string{
+		fmt.Sprintf("size=%d", size),
+		fmt.Sprintf("max=%d", MAX_KEY_SIZE),
+	}
+	return Errf("STORE.KEY_TOO_LARGE", args...)
+}
+
+func ErrValueTooLarge(size int) error {
+	args := []string{
+		fmt.Sprintf("size=%d", size),
+		fmt.Sprintf("max=%d", MAX_VALUE_SIZE),
+	}
+	return Errf("STORE.VALUE_TOO_LARGE", args...)
+}
@@ -0,0 +1,90 @@
+package kv
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+// Iterator walks a range of a store in ascending order of keys.
+//
+//	it, err := store.Range(start, end)
+//	for it.Next() {
+//		use(it.Key(), it.Value())
+//	}
+//	err = errors.Join(it.Err(), it.Close())
+type Iterator struct {
+	idx *index.BTreeIndex
+	ts  *record.TableScan
+	end []byte
+
+	key   []byte
+	value []byte
+	err   error
+	done  bool
+}
+
+// Next moves to the next key, it returns false at the end of the range or on an error.
+func (it *Iterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	ok, err := it.idx.NextInOrder()
+	if err != nil || !ok {
+		return it.stop(err)
+	}
+	dataval, err := it.idx.GetDataVal()
+	if err != nil {
+		return it.stop(err)
+	}
+	key := []byte(dataval.AsString())
+	if it.end != nil && bytes.Compare(key, it.end) >= 0 {
+		return it.stop(nil)
+	}
+
+	rid, err := it.idx.GetDataRID()
+	if err != nil {
+		return it.stop(err)
+	}
+	if err := it.ts.MoveToRid(*rid); err != nil {
+		return it.stop(err)
+	}
+	value, err := it.ts.GetString("value")
+	if err != nil {
+		return it.stop(err)
+	}
+
+	it.key, it.value = key, []byte(value)
+	return true
+}
+
+// Key returns the key of the current entry.
+func (it *Iterator) Key() []byte {
+	return it.key
+}
+
+// Value returns the value of the current entry.
+func (it *Iterator) Value() []byte {
+	return it.value
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close releases the blocks pinned by the iterator.
+func (it *Iterator) Close() error {
+	it.done = true
+	return errors.Join(it.idx.Close(), it.ts.Close())
+}
+
+func (it *Iterator) stop(err error) bool {
+	it.done = true
+	it.err = err
+	it.key, it.value = nil, nil
+	return false
+}
@@ -0,0 +1,230 @@
+// Package kv stores byte keys and values inside a transaction.Transaction.
+//
+// A store is a table of values and a B-tree index from each key to the record of its value,
+// both are read and written through the transaction so they are locked and logged like SQL tables.
+package kv
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+const (
+	// MAX_KEY_SIZE is the largest key in bytes
+	MAX_KEY_SIZE = 64
+	// MAX_VALUE_SIZE is the largest value in bytes
+	MAX_VALUE_SIZE = 1024
+)
+
+// NewStore opens the store called name inside tx, creating its files on first use.
+// The store must not be used after the transaction ends.
+func NewStore(tx transaction.Transaction, name string) (*Store, error) {
+	if !validName(name) {
+		return nil, ErrInvalidName(name)
+	}
+
+	layout := record.NewLayoutOfSchema(valueSchema())
+	if layout.SlotSize() > tx.BlockSize() {
+		return nil, ErrBlockTooSmall(tx.BlockSize(), layout.SlotSize())
+	}
+
+	// a dot never appears in a SQL identifier, so the files cannot clash with a table
+	s := &Store{
+		tx:      tx,
+		tblname: "kv." + name,
+		idxname: "kv." + name + ".",
+		layout:  layout,
+	}
+	idx, err := s.openIndex()
+	if err != nil {
+		return nil, err
+	}
+	s.idx = idx
+	return s, nil
+}
+
+// Store is an ordered map of byte keys to byte values.
+type Store struct {
+	tx      transaction.Transaction
+	tblname string
+	idxname string
+	layout  *record.Layout
+	idx     *index.BTreeIndex
+}
+
+// Get returns the value of key, the bool reports whether the key exists.
+func (s *Store) Get(key []byte) ([]byte, bool, error) {
+	if len(key) > MAX_KEY_SIZE {
+		return nil, false, ErrKeyTooLarge(len(key))
+	}
+
+	rid, err := s.find(key)
+	if err != nil || rid == nil {
+		return nil, false, err
+	}
+	value, err := s.read(*rid)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Put sets the value of key, replacing the previous one.
+func (s *Store) Put(key, value []byte) error {
+	if len(key) > MAX_KEY_SIZE {
+		return ErrKeyTooLarge(len(key))
+	}
+	if len(value) > MAX_VALUE_SIZE {
+		return ErrValueTooLarge(len(value))
+	}
+
+	rid, err := s.find(key)
+	if err != nil {
+		return err
+	}
+
+	ts, err := record.NewTableScan(s.tx, s.tblname, s.layout)
+	if err != nil {
+		return err
+	}
+	if rid != nil {
+		if err := ts.MoveToRid(*rid); err != nil {
+			return errors.Join(err, ts.Close())
+		}
+		return errors.Join(ts.SetString("value", string(value)), ts.Close())
+	}
+
+	if err := ts.Insert(); err != nil {
+		return errors.Join(err, ts.Close())
+	}
+	if err := ts.SetString("value", string(value)); err != nil {
+		return errors.Join(err, ts.Close())
+	}
+	newrid := ts.GetRid()
+	if err := ts.Close(); err != nil {
+		return err
+	}
+
+	dataval := record.NewStringConstant(string(key))
+	return s.idx.Insert(&dataval, &newrid)
+}
+
+// Delete removes key, a missing key is not an error.
+func (s *Store) Delete(key []byte) error {
+	if len(key) > MAX_KEY_SIZE {
+		return ErrKeyTooLarge(len(key))
+	}
+
+	rid, err := s.find(key)
+	if err != nil || rid == nil {
+		return err
+	}
+
+	dataval := record.NewStringConstant(string(key))
+	if err := s.idx.Delete(&dataval, rid); err != nil {
+		return err
+	}
+	ts, err := record.NewTableScan(s.tx, s.tblname, s.layout)
+	if err != nil {
+		return err
+	}
+	if err := ts.MoveToRid(*rid); err != nil {
+		return errors.Join(err, ts.Close())
+	}
+	return errors.Join(ts.Delete(), ts.Close())
+}
+
+// Range returns an iterator over the keys from start, inclusive, to end, exclusive, in ascending order.
+// A nil start begins at the first key and a nil end goes on to the last one.
+// The store must not be changed while the iterator is open.
+func (s *Store) Range(start, end []byte) (*Iterator, error) {
+	idx, err := s.openIndex()
+	if err != nil {
+		return nil, err
+	}
+	from := record.NewStringConstant(string(start))
+	if err := idx.BeforeFirst(&from); err != nil {
+		return nil, errors.Join(err, idx.Close())
+	}
+	ts, err := record.NewTableScan(s.tx, s.tblname, s.layout)
+	if err != nil {
+		return nil, errors.Join(err, idx.Close())
+	}
+	return &Iterator{idx: idx, ts: ts, end: end}, nil
+}
+
+// Close releases the blocks pinned by the store.
+func (s *Store) Close() error {
+	return s.idx.Close()
+}
+
+// find returns the record of the value of key, nil if the key does not exist.
+func (s *Store) find(key []byte) (*record.RID, error) {
+	dataval := record.NewStringConstant(string(key))
+	if err := s.idx.BeforeFirst(&dataval); err != nil {
+		return nil, err
+	}
+	defer s.idx.Close()
+
+	if !s.idx.Next() {
+		return nil, s.idx.Err()
+	}
+	return s.idx.GetDataRID()
+}
+
+func (s *Store) read(rid record.RID) ([]byte, error) {
+	ts, err := record.NewTableScan(s.tx, s.tblname, s.layout)
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.MoveToRid(rid); err != nil {
+		return nil, errors.Join(err, ts.Close())
+	}
+	value, err := ts.GetString("value")
+	if err != nil {
+		return nil, errors.Join(err, ts.Close())
+	}
+	return []byte(value), ts.Close()
+}
+
+func (s *Store) openIndex() (*index.BTreeIndex, error) {
+	sch := record.NewSchema()
+	sch.AddIntField("block")
+	sch.AddIntField("id")
+	sch.AddStringField("dataval", chars(MAX_KEY_SIZE))
+	return index.NewBTreeIndex(s.tx, s.idxname, record.NewLayoutOfSchema(sch))
+}
+
+// validName accepts names that are safe to use in file names
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func valueSchema() *record.Schema {
+	sch := record.NewSchema()
+	sch.AddStringField("value", chars(MAX_VALUE_SIZE))
+	return sch
+}
+
+// chars returns the length of a string field that has room for size bytes,
+// string fields reserve file.MaxLength bytes which assumes the widest UTF-8 characters.
+func chars(size int) int {
+	length := (size + utf8.UTFMax - 1) / utf8.UTFMax
+	for file.MaxLength(length) < file.INT_SIZE+size {
+		length++
+	}
+	return length
+}
@@ -0,0 +1,171 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_PutGetDelete(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+	db := testdb(t, dir)
+	defer db.Close()
+
+	tx, err := db.NewTx()
+	require.NoError(t, err)
+	s, err := NewStore(tx, "default")
+	require.NoError(t, err)
+
+	entries := make(map[string][]byte)
+	for i := range 200 {
+		key := fmt.Sprintf("key-%04d", i)
+		entries[key] = []byte(fk.RandomStringWithLength(fk.IntBetween(0, 100)))
+		require.NoError(t, s.Put([]byte(key), entries[key]))
+	}
+
+	for key, want := range entries {
+		got, ok, err := s.Get([]byte(key))
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, string(want), string(got))
+	}
+
+	// binary keys and values survive untouched
+	binkey, binval := []byte{0, 0xff, 1}, []byte{0xfe, 0, 0x80}
+	require.NoError(t, s.Put(binkey, binval))
+	got, ok, err := s.Get(binkey)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, binval, got)
+
+	require.NoError(t, s.Put([]byte("key-0001"), []byte("replaced")))
+	got, _, err = s.Get([]byte("key-0001"))
+	require.NoError(t, err)
+	require.Equal(t, "replaced", string(got))
+
+	require.NoError(t, s.Delete([]byte("key-0002")))
+	_, ok, err = s.Get([]byte("key-0002"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.NoError(t, s.Delete([]byte("missing")))
+
+	require.NoError(t, s.Close())
+	require.NoError(t, tx.Commit())
+}
+
+func TestStore_Range(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+	db := testdb(t, dir)
+	defer db.Close()
+
+	tx, err := db.NewTx()
+	require.NoError(t, err)
+	s, err := NewStore(tx, "default")
+	require.NoError(t, err)
+
+	// inserted out of order, read back sorted
+	for _, i := range rand.Perm(100) {
+		require.NoError(t, s.Put(fmt.Appendf(nil, "%03d", i), fmt.Appendf(nil, "v%d", i)))
+	}
+
+	collect := func(start, end []byte) []string {
+		it, err := s.Range(start, end)
+		require.NoError(t, err)
+		keys := make([]string, 0)
+		for it.Next() {
+			require.Equal(t, "v"+fmt.Sprint(len(keys)+atoi(t, start)), string(it.Value()))
+			keys = append(keys, string(it.Key()))
+		}
+		require.NoError(t, errors.Join(it.Err(), it.Close()))
+		return keys
+	}
+
+	all := collect(nil, nil)
+	require.Len(t, all, 100)
+	require.Equal(t, "000", all[0])
+	require.Equal(t, "099", all[99])
+
+	require.Equal(t, []string{"010", "011", "012"}, collect([]byte("010"), []byte("013")))
+	require.Empty(t, collect([]byte("100"), nil))
+
+	require.NoError(t, s.Close())
+	require.NoError(t, tx.Commit())
+}
+
+func TestStore_RollbackAndReopen(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+	db := testdb(t, dir)
+
+	tx, err := db.NewTx()
+	require.NoError(t, err)
+	s, err := NewStore(tx, "default")
+	require.NoError(t, err)
+	require.NoError(t, s.Put([]byte("kept"), []byte("1")))
+	require.NoError(t, s.Close())
+	require.NoError(t, tx.Commit())
+
+	tx, err = db.NewTx()
+	require.NoError(t, err)
+	s, err = NewStore(tx, "default")
+	require.NoError(t, err)
+	require.NoError(t, s.Put([]byte("dropped"), []byte("2")))
+	require.NoError(t, s.Delete([]byte("kept")))
+	require.NoError(t, s.Close())
+	require.NoError(t, tx.Rollback())
+	require.NoError(t, db.Close())
+
+	db = testdb(t, dir)
+	defer db.Close()
+	tx, err = db.NewTx()
+	require.NoError(t, err)
+	s, err = NewStore(tx, "default")
+	require.NoError(t, err)
+
+	got, ok, err := s.Get([]byte("kept"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "1", string(got))
+	_, ok, err = s.Get([]byte("dropped"))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, s.Close())
+	require.NoError(t, tx.Commit())
+}
+
+func TestStore_Limits(t *testing.T) {
+	dir := testdir(t)
+	defer os.RemoveAll(dir)
+	db := testdb(t, dir)
+	defer db.Close()
+
+	tx, err := db.NewTx()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	_, err = NewStore(tx, "../escape")
+	require.Error(t, err)
+
+	s, err := NewStore(tx, "default")
+	require.NoError(t, err)
+	require.Error(t, s.Put(make([]byte, MAX_KEY_SIZE+1), nil))
+	require.Error(t, s.Put([]byte("k"), make([]byte, MAX_VALUE_SIZE+1)))
+	require.NoError(t, s.Put(make([]byte, MAX_KEY_SIZE), make([]byte, MAX_VALUE_SIZE)))
+}
+
+func atoi(t *testing.T, b []byte) int {
+	if b == nil {
+		return 0
+	}
+	var n int
+	_, err := fmt.Sscanf(string(b), "%d", &n)
+	require.NoError(t, err)
+	return n
+}
@@ -52,6 +52,7 @@ func (it *LogIterator) moveToBlock(blk *file.BlockId) error {
 		return err
 	}
 
+	it.blk = blk
 	it.boundary = it.page.Int(0)
 	it.currentpos = it.boundary
 
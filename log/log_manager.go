@@ -1,6 +1,10 @@
 package log
 
-import "github.com/kanthorlabs/kanthorkv/file"
+import (
+	"sync"
+
+	"github.com/kanthorlabs/kanthorkv/file"
+)
 
 var _ LogManager = (*locallm)(nil)
 
@@ -46,9 +50,15 @@ type locallm struct {
 	currentblk     *file.BlockId
 	latestLSN      int
 	latestSavedLSN int
+
+	// transactions append and flush the log concurrently
+	mu sync.Mutex
 }
 
 func (lm *locallm) Append(rec []byte) (int, error) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
 	boundary := lm.logpage.Int(0)
 	recsize := len(rec)
 	bytesneeded := file.INT_SIZE + recsize
@@ -74,6 +84,9 @@ func (lm *locallm) Append(rec []byte) (int, error) {
 }
 
 func (lm *locallm) Flush(lsn int) error {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
 	if lsn >= lm.latestSavedLSN {
 		return lm.flush()
 	}
@@ -81,6 +94,9 @@ func (lm *locallm) Flush(lsn int) error {
 }
 
 func (lm *locallm) Iterator() (*LogIterator, error) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
 	if err := lm.flush(); err != nil {
 		return nil, err
 	}
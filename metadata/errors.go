@@ -0,0 +1,61 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var basename = "KANTHORKV.METADATA"
+
+func Errf(err string, args ...string) error {
+	return fmt.Errorf("%s.%s: %s", basename, err, strings.Join(args, " | "))
+}
+
+// Wrapf describes a sentinel error with args, errors.Is still finds the sentinel.
+func Wrapf(sentinel error, args ...string) error {
+	return fmt.Errorf("%w: %s", sentinel, strings.Join(args, " | "))
+}
+
+// ErrTableNotExist is a table missing from the catalog.
+var ErrTableNotExist = errors.New(basename + ".TABLE_MANAGER.TABLE_NOT_FOUND")
+
+func ErrTableNotFound(tblname string) error {
+	args := []string{
+		fmt.Sprintf("tblname=%s", tblname),
+	}
+	return Wrapf(ErrTableNotExist, args...)
+}
+
+func ErrFieldNotFound(tblname, fldname string) error {
+	args := []string{
+		fmt.Sprintf("tblname=%s", tblname),
+		fmt.Sprintf("fldname=%s", fldname),
+	}
+	return Errf("TABLE_MANAGER.FIELD_NOT_FOUND", args...)
+}
+
+func ErrTooManyFields(tblname string, count int) error {
+	args := []string{
+		fmt.Sprintf("tblname=%s", tblname),
+		fmt.Sprintf("fields=%d", count),
+		fmt.Sprintf("max=%d", record.NULLABLE_FIELDS),
+	}
+	return Errf("TABLE_MANAGER.TOO_MANY_FIELDS", args...)
+}
+
+func ErrUnknownIndexType(idxtype string) error {
+	args := []string{
+		fmt.Sprintf("idxtype=%s", idxtype),
+	}
+	return Errf("INDEX_MANAGER.UNKNOWN_INDEX_TYPE", args...)
+}
+
+func ErrIndexExists(idxname string) error {
+	args := []string{
+		fmt.Sprintf("idxname=%s", idxname),
+	}
+	return Errf("INDEX_MANAGER.INDEX_EXISTS", args...)
+}
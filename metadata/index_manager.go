@@ -2,6 +2,7 @@ package metadata
 
 import (
 	"errors"
+	"slices"
 
 	"github.com/kanthorlabs/kanthorkv/index"
 	"github.com/kanthorlabs/kanthorkv/record"
@@ -14,6 +15,7 @@ func NewIndexMgr(isNew bool, tablemgr *TableMgr, statmgr *StatMgr, tx transactio
 		sche.AddStringField("indexname", 16)
 		sche.AddStringField("tablename", 16)
 		sche.AddStringField("fieldname", 16)
+		sche.AddStringField("indextype", 16)
 		if err := tablemgr.CreateTable("idxcat", sche, tx); err != nil {
 			return nil, err
 		}
@@ -37,11 +39,43 @@ type IndexMgr struct {
 	statmgr  *StatMgr
 }
 
-func (im *IndexMgr) CreateIndex(idxname, tblname, fldname string, tx transaction.Transaction) error {
+// CreateIndex records an index of the given type on a field, an empty type means index.TYPE_HASH.
+// Index names are unique across tables, and a field has at most one index.
+func (im *IndexMgr) CreateIndex(idxname, tblname, fldname, idxtype string, tx transaction.Transaction) (err error) {
+	if idxtype == "" {
+		idxtype = index.Types[0]
+	}
+	if !slices.Contains(index.Types, idxtype) {
+		return ErrUnknownIndexType(idxtype)
+	}
+	tbllayout, err := im.tablemgr.GetLayout(tblname, tx)
+	if err != nil {
+		return err
+	}
+	if !tbllayout.Schema().HasField(fldname) {
+		return ErrFieldNotFound(tblname, fldname)
+	}
+
 	ts, err := record.NewTableScan(tx, "idxcat", im.layout)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		err = errors.Join(err, ts.Close())
+	}()
+	// an index name names the files of the index
+	for ts.Next() {
+		name, err := ts.GetString("indexname")
+		if err != nil {
+			return err
+		}
+		if name == idxname {
+			return ErrIndexExists(idxname)
+		}
+	}
+	if err := ts.BeforeFirst(); err != nil {
+		return err
+	}
 	if err := ts.Insert(); err != nil {
 		return err
 	}
@@ -55,12 +89,33 @@ func (im *IndexMgr) CreateIndex(idxname, tblname, fldname string, tx transaction
 	if err := ts.SetString("fieldname", fldname); err != nil {
 		return err
 	}
+	// catalogs created before index types existed have no room for one
+	if im.layout.Schema().HasField("indextype") {
+		return ts.SetString("indextype", idxtype)
+	}
+	return nil
+}
 
-	return ts.Close()
+// GetIndexInfo returns an index for each indexed field of the table, the one the query planners search.
+// Of several indexes on a field the B-tree wins, it serves both equality and ORDER BY.
+func (im *IndexMgr) GetIndexInfo(tblname string, tx transaction.Transaction) (map[string]*IndexInfo, error) {
+	indexes, err := im.GetIndexes(tblname, tx)
+	if err != nil {
+		return nil, err
+	}
 
+	results := make(map[string]*IndexInfo)
+	for _, ii := range indexes {
+		if _, ok := results[ii.fldname]; !ok || ii.idxtype == index.TYPE_BTREE {
+			results[ii.fldname] = ii
+		}
+	}
+	return results, nil
 }
 
-func (im *IndexMgr) GetIndexInfo(tblname string, tx transaction.Transaction) (results map[string]*IndexInfo, err error) {
+// GetIndexes returns every index of the table in the order they were created,
+// a field may have more than one.
+func (im *IndexMgr) GetIndexes(tblname string, tx transaction.Transaction) (results []*IndexInfo, err error) {
 	ts, err := record.NewTableScan(tx, "idxcat", im.layout)
 	if err != nil {
 		return nil, err
@@ -68,7 +123,6 @@ func (im *IndexMgr) GetIndexInfo(tblname string, tx transaction.Transaction) (re
 	defer func() {
 		err = errors.Join(err, ts.Close())
 	}()
-	results = make(map[string]*IndexInfo)
 
 	for ts.Next() {
 		name, err := ts.GetString("tablename")
@@ -84,6 +138,12 @@ func (im *IndexMgr) GetIndexInfo(tblname string, tx transaction.Transaction) (re
 			if err != nil {
 				return nil, err
 			}
+			idxtype := index.Types[0]
+			if im.layout.Schema().HasField("indextype") {
+				if idxtype, err = ts.GetString("indextype"); err != nil {
+					return nil, err
+				}
+			}
 			tbllayout, err := im.tablemgr.GetLayout(tblname, tx)
 			if err != nil {
 				return nil, err
@@ -93,14 +153,16 @@ func (im *IndexMgr) GetIndexInfo(tblname string, tx transaction.Transaction) (re
 				return nil, err
 			}
 
-			results[fldname] = &IndexInfo{
+			ii := &IndexInfo{
 				idxname:   idxname,
 				fldname:   fldname,
+				idxtype:   idxtype,
 				tx:        tx,
 				tblSchema: tbllayout.Schema(),
-				idxLayout: tbllayout,
 				si:        tblsi,
 			}
+			ii.idxLayout = ii.CreateIdxLayout()
+			results = append(results, ii)
 		}
 	}
 
@@ -110,20 +172,50 @@ func (im *IndexMgr) GetIndexInfo(tblname string, tx transaction.Transaction) (re
 type IndexInfo struct {
 	idxname   string
 	fldname   string
+	idxtype   string
 	tx        transaction.Transaction
 	tblSchema *record.Schema
 	idxLayout *record.Layout
 	si        *StatInfo
 }
 
+// Open opens the index with the implementation of its type.
 func (ii *IndexInfo) Open() (index.Index, error) {
+	switch ii.idxtype {
+	case index.TYPE_BTREE:
+		return index.NewBTreeIndex(ii.tx, ii.idxname, ii.idxLayout)
+	case index.TYPE_EXTHASH:
+		return index.NewExtendableHashIndex(ii.tx, ii.idxname, ii.idxLayout)
+	}
 	return index.NewStaticHashIndex(ii.tx, ii.idxname, ii.idxLayout)
 }
 
+// IndexName returns the name of the index.
+func (ii *IndexInfo) IndexName() string {
+	return ii.idxname
+}
+
+// FieldName returns the name of the indexed field.
+func (ii *IndexInfo) FieldName() string {
+	return ii.fldname
+}
+
+// IndexType returns the kind of the index, one of index.Types.
+func (ii *IndexInfo) IndexType() string {
+	return ii.idxtype
+}
+
+// BlocksAccessed estimates the blocks read by a search of the index.
 func (ii *IndexInfo) BlocksAccessed() int {
 	rpb := ii.tx.BlockSize() / ii.idxLayout.SlotSize()
 	numblocks := ii.si.RecordsOutput() / rpb
-	return numblocks
+	switch ii.idxtype {
+	case index.TYPE_BTREE:
+		return (&index.BTreeIndex{}).SearchCost(numblocks, rpb)
+	case index.TYPE_EXTHASH:
+		return (&index.ExtendableHashIndex{}).SearchCost(numblocks, rpb)
+	}
+	return (&index.StaticHashIndex{NumBuckets: index.STATIC_HASH_NUM_BUCKETS}).SearchCost(numblocks, rpb)
 }
 
 func (ii *IndexInfo) RecordsOutput() int {
@@ -142,10 +234,10 @@ func (ii *IndexInfo) CreateIdxLayout() *record.Layout {
 	sche.AddIntField("block")
 	sche.AddIntField("id")
 	if ii.tblSchema.Type(ii.fldname) == record.IntegerField {
-		sche.AddIntField(ii.fldname)
+		sche.AddIntField("dataval")
 	} else {
 		fldlen := ii.tblSchema.Length(ii.fldname)
-		sche.AddStringField(ii.fldname, fldlen)
+		sche.AddStringField("dataval", fldlen)
 	}
 	return record.NewLayoutOfSchema(sche)
 }
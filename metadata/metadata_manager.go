@@ -53,14 +53,18 @@ func (mm *MetadataMgr) GetViewDef(viewname string, tx transaction.Transaction) (
 	return mm.viewmgr.GetViewDef(viewname, tx)
 }
 
-func (mm *MetadataMgr) CreateIndex(idxname, tblname, fldname string, tx transaction.Transaction) error {
-	return mm.indexmgr.CreateIndex(idxname, tblname, fldname, tx)
+func (mm *MetadataMgr) CreateIndex(idxname, tblname, fldname, idxtype string, tx transaction.Transaction) error {
+	return mm.indexmgr.CreateIndex(idxname, tblname, fldname, idxtype, tx)
 }
 
 func (mm *MetadataMgr) GetIndexInfo(tblname string, tx transaction.Transaction) (map[string]*IndexInfo, error) {
 	return mm.indexmgr.GetIndexInfo(tblname, tx)
 }
 
+func (mm *MetadataMgr) GetIndexes(tblname string, tx transaction.Transaction) ([]*IndexInfo, error) {
+	return mm.indexmgr.GetIndexes(tblname, tx)
+}
+
 func (mm *MetadataMgr) GetStatInfo(tblname string, layout *record.Layout, tx transaction.Transaction) (*StatInfo, error) {
 	return mm.statmgr.GetStatInfo(tblname, layout, tx)
 }
@@ -32,9 +32,13 @@ type StatMgr struct {
 }
 
 func (sm *StatMgr) GetStatInfo(tblname string, layout *record.Layout, tx transaction.Transaction) (*StatInfo, error) {
+	// the statistics are shared by every transaction of the database
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
 	sm.numcalls++
 	if sm.numcalls > 100 {
-		err := sm.RefreshStatistics(tx)
+		err := sm.refreshStatistics(tx)
 		if err != nil {
 			return nil, err
 		}
@@ -52,10 +56,14 @@ func (sm *StatMgr) GetStatInfo(tblname string, layout *record.Layout, tx transac
 	return si, nil
 }
 
-func (sm *StatMgr) RefreshStatistics(tx transaction.Transaction) (err error) {
+func (sm *StatMgr) RefreshStatistics(tx transaction.Transaction) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	return sm.refreshStatistics(tx)
+}
+
+func (sm *StatMgr) refreshStatistics(tx transaction.Transaction) (err error) {
 	sm.numcalls = 0
 	sm.tablestats = make(map[string]*StatInfo)
 
@@ -47,6 +47,10 @@ type TableMgr struct {
 }
 
 func (tm *TableMgr) CreateTable(tblname string, sch *record.Schema, tx transaction.Transaction) (err error) {
+	// every field of a record must be able to hold NULL, and the flag of a slot only has bits for so many
+	if len(sch.Fields()) > record.NULLABLE_FIELDS {
+		return ErrTooManyFields(tblname, len(sch.Fields()))
+	}
 	layout := record.NewLayoutOfSchema(sch)
 	// insert one record into table cat
 	tcat, err := record.NewTableScan(tx, "tblcat", tm.tcatLayout)
@@ -123,6 +127,9 @@ func (tm *TableMgr) GetLayout(tname string, tx transaction.Transaction) (*record
 			break
 		}
 	}
+	if size < 0 {
+		return nil, ErrTableNotFound(tname)
+	}
 
 	sch := record.NewSchema()
 	offsets := make(map[string]int)
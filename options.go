@@ -0,0 +1,65 @@
+package kanthorkv
+
+import (
+	"time"
+
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/plan"
+	"github.com/kanthorlabs/kanthorkv/tx/concurrency"
+)
+
+const (
+	// DEFAULT_NUM_BUFFERS is the number of pages kept in the buffer pool
+	DEFAULT_NUM_BUFFERS = 64
+	// DEFAULT_BUFFER_TIMEOUT is how long a transaction waits for an unpinned buffer
+	DEFAULT_BUFFER_TIMEOUT = 10 * time.Second
+	// LOG_FILE is the name of the write-ahead log file inside the database directory
+	LOG_FILE = "kanthorkv.log"
+)
+
+// Options configures the subsystems of a database.
+// Zero values are replaced by their defaults.
+type Options struct {
+	// BlockSize is the size of each disk block in bytes.
+	BlockSize int
+	// NumBuffers is the number of pages kept in the buffer pool.
+	NumBuffers int
+	// BufferTimeout is how long a transaction waits for an unpinned buffer.
+	BufferTimeout time.Duration
+	// LockTimeout is how long a transaction waits for a lock before it is aborted.
+	LockTimeout time.Duration
+	// MaxJoinTables is the largest number of tables whose join orders are all compared,
+	// the joins of bigger queries are ordered greedily.
+	MaxJoinTables int
+}
+
+// DefaultOptions returns the options used for any zero value field.
+func DefaultOptions() Options {
+	return Options{
+		BlockSize:     file.BLOCK_SIZE,
+		NumBuffers:    DEFAULT_NUM_BUFFERS,
+		BufferTimeout: DEFAULT_BUFFER_TIMEOUT,
+		LockTimeout:   concurrency.MAX_WAIT_TIME,
+		MaxJoinTables: plan.DP_MAX_TABLES,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	defaults := DefaultOptions()
+	if o.BlockSize <= 0 {
+		o.BlockSize = defaults.BlockSize
+	}
+	if o.NumBuffers <= 0 {
+		o.NumBuffers = defaults.NumBuffers
+	}
+	if o.BufferTimeout <= 0 {
+		o.BufferTimeout = defaults.BufferTimeout
+	}
+	if o.LockTimeout <= 0 {
+		o.LockTimeout = defaults.LockTimeout
+	}
+	if o.MaxJoinTables <= 0 {
+		o.MaxJoinTables = defaults.MaxJoinTables
+	}
+	return o
+}
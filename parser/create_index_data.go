@@ -5,17 +5,19 @@ import (
 )
 
 // CreateIndexData represents data for the SQL create index statement.
+// IndexType is empty when the statement lets the database choose.
 type CreateIndexData struct {
-	IndexName, TableName, FieldName string
+	IndexName, TableName, FieldName, IndexType string
 }
 
 // NewCreateIndexData creates a new CreateIndexData instance with the specified
-// index name, table name, and field name.
-func NewCreateIndexData(indexname, tblname, fieldname string) *CreateIndexData {
+// index name, table name, field name and index type.
+func NewCreateIndexData(indexname, tblname, fieldname, indextype string) *CreateIndexData {
 	return &CreateIndexData{
 		IndexName: indexname,
 		TableName: tblname,
 		FieldName: fieldname,
+		IndexType: indextype,
 	}
 }
 
@@ -29,5 +31,9 @@ func (cid *CreateIndexData) String() string {
 	result.WriteString(" (")
 	result.WriteString(cid.FieldName)
 	result.WriteString(")")
+	if cid.IndexType != "" {
+		result.WriteString(" USING ")
+		result.WriteString(cid.IndexType)
+	}
 	return result.String()
 }
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/kanthorlabs/kanthorkv/query"
+)
+
+// kinds of join clause
+const (
+	JOIN_INNER = "JOIN"
+	JOIN_LEFT  = "LEFT JOIN"
+	JOIN_RIGHT = "RIGHT JOIN"
+)
+
+// JoinData represents a table joined with the tables of its clauses, from left to right,
+// like a LEFT JOIN b ON ... JOIN c ON ...
+type JoinData struct {
+	Table   TableRef
+	Clauses []*JoinClause
+}
+
+// JoinClause joins a table with everything on its left.
+type JoinClause struct {
+	Kind  string
+	Table TableRef
+	On    *query.Predicate
+}
+
+// NewJoinData creates a new JoinData instance of the table on the left of every clause.
+func NewJoinData(table TableRef) *JoinData {
+	return &JoinData{Table: table}
+}
+
+// Tables returns the tables of the joins, from left to right.
+func (jd *JoinData) Tables() []TableRef {
+	tables := []TableRef{jd.Table}
+	for _, clause := range jd.Clauses {
+		tables = append(tables, clause.Table)
+	}
+	return tables
+}
+
+// HasOuterJoin tells whether a clause is an outer join, without one the joins are a product with a selection.
+func (jd *JoinData) HasOuterJoin() bool {
+	for _, clause := range jd.Clauses {
+		if clause.Kind != JOIN_INNER {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a string representation of the joins
+func (jd *JoinData) String() string {
+	var result strings.Builder
+	result.WriteString(jd.Table.String())
+	for _, clause := range jd.Clauses {
+		result.WriteString(" ")
+		result.WriteString(clause.Kind)
+		result.WriteString(" ")
+		result.WriteString(clause.Table.String())
+		result.WriteString(" ON ")
+		result.WriteString(clause.On.String())
+	}
+	return result.String()
+}
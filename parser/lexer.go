@@ -7,7 +7,7 @@ import (
 	"unicode"
 )
 
-var keywords = []string{"select", "from", "where", "and", "insert", "into", "values", "delete", "update", "set", "create", "table", "int", "varchar", "view", "as", "index", "on"}
+var keywords = []string{"select", "from", "where", "and", "insert", "into", "values", "delete", "update", "set", "create", "table", "int", "varchar", "view", "as", "index", "on", "using", "order", "by", "asc", "desc", "group", "having", "or", "not", "join", "inner", "left", "right", "outer", "limit", "offset"}
 
 const (
 	EOF        TokenType = "EOF"
@@ -16,9 +16,17 @@ const (
 	Keyword    TokenType = "KEYWORD"
 	Identifier TokenType = "IDENTIFIER"
 	Equal      TokenType = "EQUAL"
+	Compare    TokenType = "COMPARE" // any comparison operator but =
 	Comma      TokenType = "COMMA"
 	OpenParen  TokenType = "OPEN_PAREN"
 	CloseParen TokenType = "CLOSE_PAREN"
+	Star       TokenType = "STAR" // also the multiplication operator
+	Plus       TokenType = "PLUS"
+	Minus      TokenType = "MINUS"
+	Slash      TokenType = "SLASH"
+	Percent    TokenType = "PERCENT"
+	Concat     TokenType = "CONCAT"
+	Dot        TokenType = "DOT"         // between a table and one of its fields
 	LexerError TokenType = "LEXER_ERROR" // used for syntax errors
 )
 
@@ -62,19 +70,23 @@ func (l *Lexer) readInt() (string, error) {
 	return sb.String(), nil
 }
 
+// readString reads a string constant, a quote inside it is written twice.
 func (l *Lexer) readString() (string, error) {
 	var sb strings.Builder
 	l.readChar() // consume the opening '
-	ch := l.peek()
-	for ch != '\'' && ch != 0 {
-		sb.WriteByte(l.readChar())
-		ch = l.peek()
-	}
-	if ch == 0 {
-		return "", NewSyntaxError("unterminated string")
+	for {
+		ch := l.readChar()
+		if ch == 0 {
+			return "", NewSyntaxError("unterminated string")
+		}
+		if ch == '\'' {
+			if l.peek() != '\'' {
+				return sb.String(), nil
+			}
+			l.readChar()
+		}
+		sb.WriteByte(ch)
 	}
-	l.readChar() // consume the closing '
-	return sb.String(), nil
 }
 
 func (l *Lexer) readIdentifier() (string, error) {
@@ -87,6 +99,33 @@ func (l *Lexer) readIdentifier() (string, error) {
 	return sb.String(), nil
 }
 
+// readCompare reads <, <=, <>, >, >= or !=, the last one is returned as <>.
+func (l *Lexer) readCompare() Token {
+	ch := l.readChar()
+	next := l.peek()
+	switch {
+	case ch == '!' && next == '=':
+		l.readChar()
+		return NewToken(Compare, "<>")
+	case ch == '!':
+		return NewToken(LexerError, "expected = after !")
+	case next == '=' || (ch == '<' && next == '>'):
+		l.readChar()
+		return NewToken(Compare, string([]byte{ch, next}))
+	}
+	return NewToken(Compare, string(ch))
+}
+
+// readConcat reads the concatenation operator ||.
+func (l *Lexer) readConcat() Token {
+	l.readChar()
+	if l.peek() != '|' {
+		return NewToken(LexerError, "expected | after |")
+	}
+	l.readChar()
+	return NewToken(Concat, "||")
+}
+
 func (l *Lexer) skipWhitespace() {
 	for {
 		ch := l.peek()
@@ -111,10 +150,26 @@ func (l *Lexer) NextToken() Token {
 		t = NewToken(Comma, ",")
 	} else if ch == '=' {
 		t = NewToken(Equal, "=")
+	} else if ch == '<' || ch == '>' || ch == '!' {
+		return l.readCompare()
 	} else if ch == '(' {
 		t = NewToken(OpenParen, "(")
 	} else if ch == ')' {
 		t = NewToken(CloseParen, ")")
+	} else if ch == '*' {
+		t = NewToken(Star, "*")
+	} else if ch == '+' {
+		t = NewToken(Plus, "+")
+	} else if ch == '-' {
+		t = NewToken(Minus, "-")
+	} else if ch == '/' {
+		t = NewToken(Slash, "/")
+	} else if ch == '%' {
+		t = NewToken(Percent, "%")
+	} else if ch == '.' {
+		t = NewToken(Dot, ".")
+	} else if ch == '|' {
+		return l.readConcat()
 	} else if ch >= '0' && ch <= '9' {
 		i, err := l.readInt()
 		if err != nil {
@@ -37,6 +37,19 @@ func TestLexer_query(t *testing.T) {
 	checkToken(t, lexer, EOF, "")
 }
 
+func TestLexer_quotedString(t *testing.T) {
+	lexer := NewLexer("'it''s','''',''")
+	checkToken(t, lexer, String, "it's")
+	checkToken(t, lexer, Comma, ",")
+	checkToken(t, lexer, String, "'")
+	checkToken(t, lexer, Comma, ",")
+	checkToken(t, lexer, String, "")
+	checkToken(t, lexer, EOF, "")
+
+	lexer = NewLexer("'it''s")
+	checkToken(t, lexer, LexerError, "syntax error: unterminated string")
+}
+
 func checkToken(t *testing.T, lexer *Lexer, typ TokenType, lit string) {
 	token := lexer.NextToken()
 	if token.Literal != lit {
@@ -46,3 +59,59 @@ func checkToken(t *testing.T, lexer *Lexer, typ TokenType, lit string) {
 		t.Fatalf("expected %s, got %s", typ, token.Type)
 	}
 }
+
+func TestLexer_compare(t *testing.T) {
+	lexer := NewLexer("a<1 b<=c<>'x' d!=4>=e>f=7")
+	checkToken(t, lexer, Identifier, "a")
+	checkToken(t, lexer, Compare, "<")
+	checkToken(t, lexer, Int, "1")
+	checkToken(t, lexer, Identifier, "b")
+	checkToken(t, lexer, Compare, "<=")
+	checkToken(t, lexer, Identifier, "c")
+	checkToken(t, lexer, Compare, "<>")
+	checkToken(t, lexer, String, "x")
+	checkToken(t, lexer, Identifier, "d")
+	checkToken(t, lexer, Compare, "<>")
+	checkToken(t, lexer, Int, "4")
+	checkToken(t, lexer, Compare, ">=")
+	checkToken(t, lexer, Identifier, "e")
+	checkToken(t, lexer, Compare, ">")
+	checkToken(t, lexer, Identifier, "f")
+	checkToken(t, lexer, Equal, "=")
+	checkToken(t, lexer, Int, "7")
+	checkToken(t, lexer, EOF, "")
+
+	checkToken(t, NewLexer("!"), LexerError, "expected = after !")
+}
+
+func TestLexer_operators(t *testing.T) {
+	lexer := NewLexer("a+b-1*c/d%2||'x'")
+	checkToken(t, lexer, Identifier, "a")
+	checkToken(t, lexer, Plus, "+")
+	checkToken(t, lexer, Identifier, "b")
+	checkToken(t, lexer, Minus, "-")
+	checkToken(t, lexer, Int, "1")
+	checkToken(t, lexer, Star, "*")
+	checkToken(t, lexer, Identifier, "c")
+	checkToken(t, lexer, Slash, "/")
+	checkToken(t, lexer, Identifier, "d")
+	checkToken(t, lexer, Percent, "%")
+	checkToken(t, lexer, Int, "2")
+	checkToken(t, lexer, Concat, "||")
+	checkToken(t, lexer, String, "x")
+	checkToken(t, lexer, EOF, "")
+
+	checkToken(t, NewLexer("|"), LexerError, "expected | after |")
+}
+
+func TestLexer_qualifiedField(t *testing.T) {
+	lexer := NewLexer("e1.name=e2 . id")
+	checkToken(t, lexer, Identifier, "e1")
+	checkToken(t, lexer, Dot, ".")
+	checkToken(t, lexer, Identifier, "name")
+	checkToken(t, lexer, Equal, "=")
+	checkToken(t, lexer, Identifier, "e2")
+	checkToken(t, lexer, Dot, ".")
+	checkToken(t, lexer, Identifier, "id")
+	checkToken(t, lexer, EOF, "")
+}
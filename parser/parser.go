@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -10,20 +11,54 @@ import (
 )
 
 type Parser struct {
-	lex     *Lexer
+	// toks are all the tokens of the statement, up to EOF or the first lexing error,
+	// so that the parser can go back to an earlier one
+	toks    []Token
+	pos     int
 	curTok  Token
 	prevTok Token
+	// aggFns collects the aggregation functions of the query, they are only allowed while it is set
+	aggFns []query.AggregationFn
+	inAgg  bool
 }
 
 func New(lex *Lexer) *Parser {
-	p := &Parser{lex: lex}
-	p.nextToken()
+	p := &Parser{}
+	for {
+		tok := lex.NextToken()
+		p.toks = append(p.toks, tok)
+		if tok.Type == EOF || tok.Type == LexerError {
+			break
+		}
+	}
+	p.curTok = p.toks[0]
 	return p
 }
 
 func (p *Parser) nextToken() {
 	p.prevTok = p.curTok
-	p.curTok = p.lex.NextToken()
+	if p.pos < len(p.toks)-1 {
+		p.pos++
+	}
+	p.curTok = p.toks[p.pos]
+}
+
+// parserState is a point of the statement the parser can go back to.
+type parserState struct {
+	pos     int
+	prevTok Token
+	aggFns  int
+}
+
+func (p *Parser) mark() parserState {
+	return parserState{pos: p.pos, prevTok: p.prevTok, aggFns: len(p.aggFns)}
+}
+
+// reset goes back to the marked point, forgetting the aggregation functions parsed since.
+func (p *Parser) reset(state parserState) {
+	p.pos, p.prevTok = state.pos, state.prevTok
+	p.curTok = p.toks[p.pos]
+	p.aggFns = p.aggFns[:state.aggFns]
 }
 
 func (p *Parser) matchInt() bool {
@@ -94,7 +129,29 @@ func (p *Parser) Field() (string, error) {
 	return p.eatId()
 }
 
+// qualifiedField parses a field of a query, which can be qualified by the name or alias of its table like e.name.
+func (p *Parser) qualifiedField() (string, error) {
+	field, err := p.Field()
+	if err != nil || !p.matchDelim(Dot) {
+		return field, err
+	}
+	p.nextToken()
+	name, err := p.Field()
+	if err != nil {
+		return "", err
+	}
+	return field + "." + name, nil
+}
+
 func (p *Parser) Constant() (record.Constant, error) {
+	if p.matchDelim(Minus) {
+		p.nextToken()
+		val, err := p.eatInt()
+		if err != nil {
+			return record.Constant{}, err
+		}
+		return record.NewIntConstant(-val), nil
+	}
 	if p.matchString() {
 		s, err := p.eatString()
 		if err != nil {
@@ -111,12 +168,80 @@ func (p *Parser) Constant() (record.Constant, error) {
 	return record.Constant{}, NewSyntaxError("expected integer or string constant")
 }
 
+// Expression parses a concatenation of sums.
+// Concatenation binds loosest, then addition and subtraction, then multiplication, division and modulo,
+// then unary minus. Operators of the same precedence are evaluated from left to right.
 func (p *Parser) Expression() (*query.Expression, error) {
+	return p.binary(p.sum, map[TokenType]string{Concat: query.OP_CONCAT})
+}
+
+func (p *Parser) sum() (*query.Expression, error) {
+	return p.binary(p.product, map[TokenType]string{Plus: query.OP_ADD, Minus: query.OP_SUB})
+}
+
+func (p *Parser) product() (*query.Expression, error) {
+	return p.binary(p.unary, map[TokenType]string{Star: query.OP_MUL, Slash: query.OP_DIV, Percent: query.OP_MOD})
+}
+
+// binary parses operands with operand, separated by any of the operators ops.
+func (p *Parser) binary(operand func() (*query.Expression, error), ops map[TokenType]string) (*query.Expression, error) {
+	lhs, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := ops[p.curTok.Type]
+		if !ok {
+			return lhs, nil
+		}
+		p.nextToken()
+		rhs, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		lhs = query.NewOperatorExpression(op, lhs, rhs)
+	}
+}
+
+func (p *Parser) unary() (*query.Expression, error) {
+	if !p.matchDelim(Minus) {
+		return p.primary()
+	}
+	p.nextToken()
+	e, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	// a negative number is a constant like any other
+	if c := e.Constant(); c != nil && c.Type() == record.IntegerField {
+		negated := record.NewIntConstant(-c.AsInt())
+		return query.NewConstantExpression(&negated), nil
+	}
+	return query.NewNegateExpression(e), nil
+}
+
+// primary parses a field, a constant, a function call, or an expression in parentheses.
+// An aggregation function reads the field of the grouped records named after the function.
+func (p *Parser) primary() (*query.Expression, error) {
+	if p.matchDelim(OpenParen) {
+		p.nextToken()
+		e, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.eatDelim(CloseParen); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
 	if p.matchId() {
-		field, err := p.Field()
+		field, err := p.qualifiedField()
 		if err != nil {
 			return nil, err
 		}
+		if p.matchDelim(OpenParen) && !strings.Contains(field, ".") {
+			return p.call(strings.ToLower(field))
+		}
 		return query.NewFieldExpression(&field), nil
 	}
 	constant, err := p.Constant()
@@ -126,54 +251,152 @@ func (p *Parser) Expression() (*query.Expression, error) {
 	return query.NewConstantExpression(&constant), nil
 }
 
+// call parses the arguments of the function called name, the name is already eaten.
+func (p *Parser) call(name string) (*query.Expression, error) {
+	if query.IsAggregationFn(name) {
+		field, err := p.aggregation(name)
+		if err != nil {
+			return nil, err
+		}
+		return query.NewFieldExpression(&field), nil
+	}
+	if !query.IsScalarFn(name) {
+		return nil, NewSyntaxError(fmt.Sprintf("unknown function %s", name))
+	}
+
+	if err := p.eatDelim(OpenParen); err != nil {
+		return nil, err
+	}
+	args := []*query.Expression{}
+	for !p.matchDelim(CloseParen) {
+		if len(args) > 0 {
+			if err := p.eatDelim(Comma); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.nextToken()
+
+	e, err := query.NewFunctionExpression(name, args)
+	if err != nil {
+		return nil, NewSyntaxError(err.Error())
+	}
+	return e, nil
+}
+
+// Term parses the comparison of two expressions.
 func (p *Parser) Term() (*query.Term, error) {
 	lhs, err := p.Expression()
 	if err != nil {
 		return nil, err
 	}
-	if err := p.eatDelim(Equal); err != nil {
-		return nil, err
+	if !p.matchDelim(Equal) && !p.matchDelim(Compare) {
+		return nil, NewSyntaxError(fmt.Sprintf("expected comparison operator after token %s", p.prevTok.String()))
 	}
+	p.nextToken()
+	op := p.prevTok.Literal
 	rhs, err := p.Expression()
 	if err != nil {
 		return nil, err
 	}
-	return query.NewTerm(lhs, rhs), nil
+	return query.NewCompareTerm(lhs, op, rhs), nil
 }
 
+// Predicate parses a boolean expression. Its root is a conjunction,
+// parenthesised conjunctions are merged into it.
 func (p *Parser) Predicate() (*query.Predicate, error) {
-	term, err := p.Term()
+	disjunction, err := p.disjunction()
+	if err != nil {
+		return nil, err
+	}
+	pred := query.NewPredicate()
+	pred.ConjoinWith(disjunction)
+	return pred, nil
+}
+
+func (p *Parser) disjunction() (*query.Predicate, error) {
+	pred, err := p.conjunction()
+	if err != nil {
+		return nil, err
+	}
+	if !p.matchKeyword("or") {
+		return pred, nil
+	}
+	p.nextToken()
+	right, err := p.disjunction()
 	if err != nil {
 		return nil, err
 	}
-	pred := query.NewPredicate(term)
-	if p.matchKeyword("and") {
+	return query.NewOrPredicate(pred, right), nil
+}
+
+func (p *Parser) conjunction() (*query.Predicate, error) {
+	pred := query.NewPredicate()
+	for {
+		factor, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		pred.ConjoinWith(factor)
+		if !p.matchKeyword("and") {
+			return pred, nil
+		}
+		p.nextToken()
+	}
+}
+
+func (p *Parser) factor() (*query.Predicate, error) {
+	if p.matchKeyword("not") {
 		p.nextToken()
-		right, err := p.Predicate()
+		factor, err := p.factor()
 		if err != nil {
 			return nil, err
 		}
-		pred.ConjoinWith(right)
+		return query.NewNotPredicate(factor), nil
 	}
-	return pred, nil
+	if p.matchDelim(OpenParen) {
+		// the parenthesis opens either a predicate, or an expression on the left of a term like (a + 1) > b
+		start := p.mark()
+		p.nextToken()
+		pred, err := p.disjunction()
+		if err == nil {
+			err = p.eatDelim(CloseParen)
+		}
+		if err == nil {
+			return pred, nil
+		}
+		p.reset(start)
+	}
+	term, err := p.Term()
+	if err != nil {
+		return nil, err
+	}
+	return query.NewPredicate(term), nil
 }
 
 func (p *Parser) Query() (*QueryData, error) {
 	if err := p.eatKeyword("select"); err != nil {
 		return nil, err
 	}
-	fields, err := p.selectList()
+	p.aggFns, p.inAgg = nil, true
+	fields, exprs, err := p.selectList()
+	p.inAgg = false
 	if err != nil {
 		return nil, err
 	}
 	if err := p.eatKeyword("from"); err != nil {
 		return nil, err
 	}
-	tables, err := p.tableList()
+	tables, joins, on, err := p.tableList()
 	if err != nil {
 		return nil, err
 	}
-	pred := query.NewPredicate(nil)
+	pred := query.NewPredicate()
 	if p.matchKeyword("where") {
 		p.nextToken()
 		pred, err = p.Predicate()
@@ -181,7 +404,54 @@ func (p *Parser) Query() (*QueryData, error) {
 			return nil, err
 		}
 	}
-	return NewQueryData(fields, tables, pred), nil
+	pred.ConjoinWith(on)
+	var groupBy []string
+	if p.matchKeyword("group") {
+		p.nextToken()
+		if err := p.eatKeyword("by"); err != nil {
+			return nil, err
+		}
+		if groupBy, err = p.qualifiedFieldList(); err != nil {
+			return nil, err
+		}
+	}
+	having := query.NewPredicate()
+	p.inAgg = true
+	defer func() { p.inAgg = false }()
+	if p.matchKeyword("having") {
+		p.nextToken()
+		if having, err = p.Predicate(); err != nil {
+			return nil, err
+		}
+	}
+	var orderBy []query.SortField
+	if p.matchKeyword("order") {
+		p.nextToken()
+		if err := p.eatKeyword("by"); err != nil {
+			return nil, err
+		}
+		if orderBy, err = p.orderList(); err != nil {
+			return nil, err
+		}
+	}
+	limit, offset := NO_LIMIT, 0
+	if p.matchKeyword("limit") {
+		p.nextToken()
+		if limit, err = p.eatInt(); err != nil {
+			return nil, err
+		}
+	}
+	if p.matchKeyword("offset") {
+		p.nextToken()
+		if offset, err = p.eatInt(); err != nil {
+			return nil, err
+		}
+	}
+	data := NewQueryData(fields, exprs, tables, pred, orderBy)
+	data.Joins = joins
+	data.GroupBy, data.Having, data.AggFns = groupBy, having, p.aggFns
+	data.Limit, data.Offset = limit, offset
+	return data, nil
 }
 
 func (p *Parser) UpdateCmd() (interface{}, error) {
@@ -222,7 +492,7 @@ func (p *Parser) Delete() (*DeleteData, error) {
 	if err != nil {
 		return nil, err
 	}
-	pred := query.NewPredicate(nil)
+	pred := query.NewPredicate()
 	if p.matchKeyword("where") {
 		p.nextToken()
 		pred, err = p.Predicate()
@@ -292,7 +562,7 @@ func (p *Parser) Update() (*UpdateData, error) {
 	if err != nil {
 		return nil, err
 	}
-	pred := query.NewPredicate(nil)
+	pred := query.NewPredicate()
 	if p.matchKeyword("where") {
 		p.nextToken()
 		pred, err = p.Predicate()
@@ -367,45 +637,239 @@ func (p *Parser) CreateIndex() (*CreateIndexData, error) {
 	if err := p.eatDelim(CloseParen); err != nil {
 		return nil, err
 	}
-	return NewCreateIndexData(indexname, tblname, fieldname), nil
+	indextype := ""
+	if p.matchKeyword("using") {
+		p.nextToken()
+		if indextype, err = p.eatId(); err != nil {
+			return nil, err
+		}
+		indextype = strings.ToLower(indextype)
+	}
+	return NewCreateIndexData(indexname, tblname, fieldname, indextype), nil
 }
 
-func (p *Parser) selectList() ([]string, error) {
+// selectList parses the expressions of the select list and the names of their fields,
+// an expression without an alias is named after itself.
+func (p *Parser) selectList() ([]string, []*query.Expression, error) {
 	fields := []string{}
+	exprs := []*query.Expression{}
 	for {
-		field, err := p.Field()
+		expr, err := p.Expression()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		field := expr.String()
+		if p.matchKeyword("as") {
+			p.nextToken()
+			if field, err = p.eatId(); err != nil {
+				return nil, nil, err
+			}
 		}
 		fields = append(fields, field)
+		exprs = append(exprs, expr)
 		if !p.matchDelim(Comma) {
 			break
 		}
 		p.nextToken()
 	}
-	return fields, nil
+
+	// a column of a qualified field is named after the field alone, like name for e.name,
+	// unless another column would get that name too
+	names := make([]string, len(fields))
+	counts := make(map[string]int)
+	for i, expr := range exprs {
+		names[i] = fields[i]
+		if fldname := expr.FieldName(); fldname != nil && *fldname == fields[i] && !p.isAggregation(*fldname) {
+			if _, name, ok := strings.Cut(*fldname, "."); ok {
+				names[i] = name
+			}
+		}
+		counts[names[i]]++
+	}
+	for i, name := range names {
+		if counts[name] == 1 {
+			fields[i] = name
+		}
+	}
+	return fields, exprs, nil
 }
 
-func (p *Parser) tableList() ([]string, error) {
-	tables := []string{}
+// isAggregation tells whether fldname is the field of an aggregation function of the query, like count(e.id).
+func (p *Parser) isAggregation(fldname string) bool {
+	return slices.ContainsFunc(p.aggFns, func(fn query.AggregationFn) bool {
+		return fn.FieldName() == fldname
+	})
+}
+
+// tableList parses the FROM clause. The tables of joins without an outer join are listed with the others
+// and their ON conditions are returned, to be conjoined with the WHERE clause.
+func (p *Parser) tableList() ([]TableRef, []*JoinData, *query.Predicate, error) {
+	tables := []TableRef{}
+	joins := []*JoinData{}
+	on := query.NewPredicate()
 	for {
-		table, err := p.eatId()
+		join, err := p.joins()
 		if err != nil {
+			return nil, nil, nil, err
+		}
+		if join.HasOuterJoin() {
+			joins = append(joins, join)
+		} else {
+			tables = append(tables, join.Tables()...)
+			for _, clause := range join.Clauses {
+				on.ConjoinWith(clause.On)
+			}
+		}
+		if !p.matchDelim(Comma) {
+			break
+		}
+		p.nextToken()
+	}
+	return tables, joins, on, nil
+}
+
+// tableRef parses a table with an optional alias, the AS before the alias is optional too.
+func (p *Parser) tableRef() (TableRef, error) {
+	table, err := p.eatId()
+	if err != nil {
+		return TableRef{}, err
+	}
+	ref := TableRef{Table: table}
+	if p.matchKeyword("as") {
+		p.nextToken()
+		if ref.Alias, err = p.eatId(); err != nil {
+			return TableRef{}, err
+		}
+	} else if p.matchId() {
+		ref.Alias, _ = p.eatId()
+	}
+	return ref, nil
+}
+
+// joins parses a table followed by any number of join clauses.
+func (p *Parser) joins() (*JoinData, error) {
+	table, err := p.tableRef()
+	if err != nil {
+		return nil, err
+	}
+	join := NewJoinData(table)
+	for {
+		kind := JOIN_INNER
+		if p.matchKeyword("inner") {
+			p.nextToken()
+		} else if p.matchKeyword("left") || p.matchKeyword("right") {
+			kind = JOIN_LEFT
+			if p.matchKeyword("right") {
+				kind = JOIN_RIGHT
+			}
+			p.nextToken()
+			if p.matchKeyword("outer") {
+				p.nextToken()
+			}
+		} else if !p.matchKeyword("join") {
+			return join, nil
+		}
+		if err := p.eatKeyword("join"); err != nil {
 			return nil, err
 		}
-		tables = append(tables, table)
+
+		clause := &JoinClause{Kind: kind}
+		if clause.Table, err = p.tableRef(); err != nil {
+			return nil, err
+		}
+		if err := p.eatKeyword("on"); err != nil {
+			return nil, err
+		}
+		if clause.On, err = p.Predicate(); err != nil {
+			return nil, err
+		}
+		join.Clauses = append(join.Clauses, clause)
+	}
+}
+
+// selectField parses a field or an aggregation function, returning the name of the field it reads.
+func (p *Parser) selectField() (string, error) {
+	field, err := p.qualifiedField()
+	if err != nil || !p.matchDelim(OpenParen) {
+		return field, err
+	}
+	return p.aggregation(strings.ToLower(field))
+}
+
+// aggregation parses the argument of the aggregation function called name, the name is already eaten.
+func (p *Parser) aggregation(name string) (string, error) {
+	if !query.IsAggregationFn(name) {
+		return "", NewSyntaxError(fmt.Sprintf("unknown function %s", name))
+	}
+	if !p.inAgg {
+		return "", NewSyntaxError(fmt.Sprintf("aggregation function %s is not allowed here", name))
+	}
+	if err := p.eatDelim(OpenParen); err != nil {
+		return "", err
+	}
+	field := "*"
+	if p.matchDelim(Star) {
+		p.nextToken()
+	} else {
+		var err error
+		if field, err = p.qualifiedField(); err != nil {
+			return "", err
+		}
+	}
+	if err := p.eatDelim(CloseParen); err != nil {
+		return "", err
+	}
+
+	fn, err := query.NewAggregationFn(name, field)
+	if err != nil {
+		return "", NewSyntaxError(err.Error())
+	}
+	for _, other := range p.aggFns {
+		if other.FieldName() == fn.FieldName() {
+			return fn.FieldName(), nil
+		}
+	}
+	p.aggFns = append(p.aggFns, fn)
+	return fn.FieldName(), nil
+}
+
+func (p *Parser) orderList() ([]query.SortField, error) {
+	sortFields := []query.SortField{}
+	for {
+		field, err := p.selectField()
+		if err != nil {
+			return nil, err
+		}
+		sf := query.SortField{Name: field}
+		if p.matchKeyword("asc") {
+			p.nextToken()
+		} else if p.matchKeyword("desc") {
+			p.nextToken()
+			sf.Desc = true
+		}
+		sortFields = append(sortFields, sf)
 		if !p.matchDelim(Comma) {
 			break
 		}
 		p.nextToken()
 	}
-	return tables, nil
+	return sortFields, nil
 }
 
 func (p *Parser) fieldList() ([]string, error) {
+	return p.fieldsOf(p.Field)
+}
+
+// qualifiedFieldList parses fields that can be qualified by their table, like those of GROUP BY.
+func (p *Parser) qualifiedFieldList() ([]string, error) {
+	return p.fieldsOf(p.qualifiedField)
+}
+
+// fieldsOf parses a list of fields separated by commas, reading each one with parse.
+func (p *Parser) fieldsOf(parse func() (string, error)) ([]string, error) {
 	fields := []string{}
 	for {
-		field, err := p.Field()
+		field, err := parse()
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,138 @@
+package parser
+
+import "testing"
+
+func TestParser_CreateIndex(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want CreateIndexData
+	}{
+		{
+			sql:  "create index sididx on student (sid)",
+			want: CreateIndexData{IndexName: "sididx", TableName: "student", FieldName: "sid"},
+		},
+		{
+			sql:  "create index sididx on student (sid) using BTree",
+			want: CreateIndexData{IndexName: "sididx", TableName: "student", FieldName: "sid", IndexType: "btree"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			cmd, err := New(NewLexer(tt.sql)).UpdateCmd()
+			if err != nil {
+				t.Fatalf("UpdateCmd() error = %v", err)
+			}
+			got, ok := cmd.(*CreateIndexData)
+			if !ok {
+				t.Fatalf("UpdateCmd() = %T, want *CreateIndexData", cmd)
+			}
+			if *got != tt.want {
+				t.Errorf("UpdateCmd() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := New(NewLexer("create index sididx on student (sid) using")).UpdateCmd(); err == nil {
+		t.Errorf("UpdateCmd() without an index type should fail")
+	}
+}
+
+func TestParser_Query(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{sql: "select a from t", want: "SELECT a FROM t"},
+		{sql: "select a, b from t where a = 1 order by b", want: "SELECT a, b FROM t WHERE a = 1 ORDER BY b"},
+		{sql: "select a, b from t order by b DESC, a asc", want: "SELECT a, b FROM t ORDER BY b DESC, a"},
+		{
+			sql:  "select a, COUNT(*), sum(b) from t group by a having max(b) = 3 order by count(*) desc",
+			want: "SELECT a, count(*), sum(b) FROM t GROUP BY a HAVING max(b) = 3 ORDER BY count(*) DESC",
+		},
+		{
+			sql:  "select a from t where a >= 1 and (b < 2 or not c != 'x') and not (a = b or a <= 0)",
+			want: "SELECT a FROM t WHERE a >= 1 AND (b < 2 OR NOT c <> 'x') AND NOT (a = b OR a <= 0)",
+		},
+		{
+			sql:  "select a from t where (a > 1 and b = 2) or c = 3 or (d = 4)",
+			want: "SELECT a FROM t WHERE a > 1 AND b = 2 OR c = 3 OR d = 4",
+		},
+		{
+			sql:  "select a from t where ((a > 1 and b = 2)) and not not c = 3",
+			want: "SELECT a FROM t WHERE a > 1 AND b = 2 AND NOT NOT c = 3",
+		},
+		{
+			sql:  "select a+b*2, (a+b)*2 as c, a-(b-1), a-b-1, -a, - -3, -(a*b) % 4 from t",
+			want: "SELECT a + b * 2, (a + b) * 2 AS c, a - (b - 1), a - b - 1, -a, 3, -(a * b) % 4 FROM t",
+		},
+		{
+			sql:  "select Upper(s) || '-' || substr(s, 2, a + 1) as x, LENGTH(s), coalesce(s, 'none'), abs(-a) from t",
+			want: "SELECT upper(s) || '-' || substr(s, 2, a + 1) AS x, length(s), coalesce(s, 'none'), abs(-a) FROM t",
+		},
+		{
+			sql:  "select a from t where (a + 1) * 2 > b and (a) = (b) or (a % 2 = 0)",
+			want: "SELECT a FROM t WHERE (a + 1) * 2 > b AND a = b OR a % 2 = 0",
+		},
+		{
+			sql:  "select a, sum(b) * 2 as total from t group by a order by total desc",
+			want: "SELECT a, sum(b) * 2 AS total FROM t GROUP BY a ORDER BY total DESC",
+		},
+		{
+			sql:  "select a, c from t inner join u on a = b join v on c = d where a > 1",
+			want: "SELECT a, c FROM t, u, v WHERE a > 1 AND a = b AND c = d",
+		},
+		{
+			sql:  "select a, c from s, t left outer join u on a = b and c = 1 right join v on c = d where a > 1",
+			want: "SELECT a, c FROM s, t LEFT JOIN u ON a = b AND c = 1 RIGHT JOIN v ON c = d WHERE a > 1",
+		},
+		{
+			sql:  "select e.name, d.name as dept from emp e join dept as d on e.deptid = d.id where e.id > 1 order by e.name desc",
+			want: "SELECT e.name AS name, d.name AS dept FROM emp e, dept d WHERE e.id > 1 AND e.deptid = d.id ORDER BY e.name DESC",
+		},
+		{
+			sql:  "select e1.name, e2.name, count(e1.id), max(e2 . id) from emp e1 left join emp e2 on e1.boss = e2.id group by e1.name, e2.name",
+			want: "SELECT e1.name, e2.name, count(e1.id), max(e2.id) FROM emp e1 LEFT JOIN emp e2 ON e1.boss = e2.id GROUP BY e1.name, e2.name",
+		},
+		{
+			sql:  "select upper(t.a) || u.b as c, t.a + 1 from t, u as v",
+			want: "SELECT upper(t.a) || u.b AS c, t.a + 1 FROM t, u v",
+		},
+		{
+			sql:  "select a from t join u on a = b left join v on b = c",
+			want: "SELECT a FROM t JOIN u ON a = b LEFT JOIN v ON b = c",
+		},
+		{sql: "select a from t order by a desc limit 10 offset 5", want: "SELECT a FROM t ORDER BY a DESC LIMIT 10 OFFSET 5"},
+		{sql: "select a from t where a > 1 LIMIT 0", want: "SELECT a FROM t WHERE a > 1 LIMIT 0"},
+		{sql: "select a from t offset 3", want: "SELECT a FROM t OFFSET 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			data, err := New(NewLexer(tt.sql)).Query()
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if got := data.String(); got != tt.want {
+				t.Errorf("Query().String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, sql := range []string{
+		"select a from t order a", "select a from t order by", "select a from t order by desc",
+		"select sum(*) from t", "select foo(a) from t", "select a from t where count(*) = 1", "select a from t group a",
+		"select a from t where a", "select a from t where (a = 1", "select a from t where a = 1 or", "select a from t where not",
+		"select a + from t", "select (a + 1 from t", "select upper(a, b) from t", "select substr(a) from t",
+		"select a as from t", "select a | b from t",
+		"select a from t join u", "select a from t left u on a = b", "select a from t right outer u on a = b",
+		"select a from t join on a = b",
+		"select t. from t", "select t.a.b from t", "select a from t as", "select a from t join u as on a = b",
+		"select a from t group by t.", "select t.count(a) from t", "select a from t, join u on a = b",
+		"select a from t limit", "select a from t limit -1", "select a from t limit a", "select a from t offset",
+	} {
+		if _, err := New(NewLexer(sql)).Query(); err == nil {
+			t.Errorf("Query(%q) should fail", sql)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/kanthorlabs/kanthorkv/query"
@@ -8,30 +10,112 @@ import (
 
 // QueryData represents data for the SQL select statement.
 type QueryData struct {
+	// Fields are the names of the columns of the select list, Exprs compute their values
 	Fields []string
-	Tables []string
-	Pred   *query.Predicate
+	Exprs  []*query.Expression
+	// Tables are the tables and views of the FROM clause, the other clauses can qualify their fields by their names
+	Tables []TableRef
+	// Joins are the joins of the FROM clause that have an outer join, their order matters.
+	// The tables of other joins are in Tables, with their ON conditions in Pred.
+	Joins []*JoinData
+	Pred  *query.Predicate
+	// GroupBy lists the fields of the GROUP BY clause
+	GroupBy []string
+	// Having filters the groups, it is empty when there is no HAVING clause
+	Having *query.Predicate
+	// AggFns are the aggregation functions the query mentions,
+	// the other clauses read their value from the field named after the function
+	AggFns []query.AggregationFn
+	// OrderBy lists the fields of the ORDER BY clause, it is empty when the order does not matter
+	OrderBy []query.SortField
+	// Limit is the most records the query returns after skipping the first Offset ones, NO_LIMIT for all of them
+	Limit  int
+	Offset int
 }
 
-// NewQueryData creates a new QueryData instance with the specified fields, tables, and predicate.
-func NewQueryData(fields []string, tables []string, pred *query.Predicate) *QueryData {
+// NO_LIMIT is the Limit of a query without a LIMIT clause
+const NO_LIMIT = -1
+
+// NewQueryData creates a new QueryData instance with the specified select list, tables, predicate and sort order.
+func NewQueryData(fields []string, exprs []*query.Expression, tables []TableRef, pred *query.Predicate, orderBy []query.SortField) *QueryData {
 	return &QueryData{
-		Fields: fields,
-		Tables: tables,
-		Pred:   pred,
+		Fields:  fields,
+		Exprs:   exprs,
+		Tables:  tables,
+		Pred:    pred,
+		Having:  query.NewPredicate(),
+		OrderBy: orderBy,
+		Limit:   NO_LIMIT,
+	}
+}
+
+// AllTables returns every table of the FROM clause, those of the joins with an outer join last.
+func (q *QueryData) AllTables() []TableRef {
+	tables := slices.Clone(q.Tables)
+	for _, join := range q.Joins {
+		tables = append(tables, join.Tables()...)
 	}
+	return tables
 }
 
 // String returns a string representation of the query
 func (q *QueryData) String() string {
 	var result strings.Builder
 	result.WriteString("SELECT ")
-	result.WriteString(strings.Join(q.Fields, ", "))
+	for i, expr := range q.Exprs {
+		if i > 0 {
+			result.WriteString(", ")
+		}
+		result.WriteString(expr.String())
+		if q.Fields[i] != expr.String() {
+			result.WriteString(" AS ")
+			result.WriteString(q.Fields[i])
+		}
+	}
 	result.WriteString(" FROM ")
-	result.WriteString(strings.Join(q.Tables, ", "))
+	for i, table := range q.Tables {
+		if i > 0 {
+			result.WriteString(", ")
+		}
+		result.WriteString(table.String())
+	}
+	for i, join := range q.Joins {
+		if i > 0 || len(q.Tables) > 0 {
+			result.WriteString(", ")
+		}
+		result.WriteString(join.String())
+	}
 	if predString := q.Pred.String(); predString != "" {
 		result.WriteString(" WHERE ")
 		result.WriteString(predString)
 	}
+	if len(q.GroupBy) > 0 {
+		result.WriteString(" GROUP BY ")
+		result.WriteString(strings.Join(q.GroupBy, ", "))
+	}
+	if havingString := q.Having.String(); havingString != "" {
+		result.WriteString(" HAVING ")
+		result.WriteString(havingString)
+	}
+	if len(q.OrderBy) > 0 {
+		result.WriteString(" ORDER BY ")
+		for i, sf := range q.OrderBy {
+			if i > 0 {
+				result.WriteString(", ")
+			}
+			result.WriteString(sf.Name)
+			if sf.Desc {
+				result.WriteString(" DESC")
+			}
+		}
+	}
+	if q.Limit != NO_LIMIT {
+		result.WriteString(" LIMIT ")
+		result.WriteString(strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		result.WriteString(" OFFSET ")
+		result.WriteString(strconv.Itoa(q.Offset))
+	}
 	return result.String()
 }
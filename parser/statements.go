@@ -0,0 +1,32 @@
+package parser
+
+import "strings"
+
+// SplitStatements returns the complete statements of text, without their ; terminators,
+// and whatever follows the last terminator.
+// A terminator inside a string constant does not end a statement.
+func SplitStatements(text string) ([]string, string) {
+	stmts := make([]string, 0)
+	quoted, start := false, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\'':
+			quoted = !quoted
+		case ';':
+			if quoted {
+				continue
+			}
+			if stmt := strings.TrimSpace(text[start:i]); stmt != "" {
+				stmts = append(stmts, stmt)
+			}
+			start = i + 1
+		}
+	}
+	return stmts, text[start:]
+}
+
+// IsQuery tells whether the statement sql is a query, which returns records instead of a count of changed ones.
+func IsQuery(sql string) bool {
+	fields := strings.Fields(sql)
+	return len(fields) > 0 && strings.EqualFold(fields[0], "select")
+}
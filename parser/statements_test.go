@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	stmts, rest := SplitStatements("select a from t where b = ';'; delete from t; insert")
+	expected := []string{"select a from t where b = ';'", "delete from t"}
+	if !reflect.DeepEqual(stmts, expected) {
+		t.Fatalf("expected %v, got %v", expected, stmts)
+	}
+	if rest != " insert" {
+		t.Fatalf("expected %q, got %q", " insert", rest)
+	}
+}
+
+func TestIsQuery(t *testing.T) {
+	for sql, expected := range map[string]bool{
+		"select a from t":              true,
+		"  SELECT a from t":            true,
+		"insert into t (a) values (1)": false,
+		"selected":                     false,
+		"":                             false,
+	} {
+		if got := IsQuery(sql); got != expected {
+			t.Errorf("IsQuery(%q) = %v, want %v", sql, got, expected)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package parser
+
+// TableRef is a table or a view listed in the FROM clause, with the alias it is given there.
+type TableRef struct {
+	Table string
+	// Alias is empty when the table goes by its own name
+	Alias string
+}
+
+// Name returns the name that qualifies the fields of the table in the other clauses, like e in e.name.
+func (tr TableRef) Name() string {
+	if tr.Alias != "" {
+		return tr.Alias
+	}
+	return tr.Table
+}
+
+// String returns a string representation of the table reference
+func (tr TableRef) String() string {
+	if tr.Alias != "" {
+		return tr.Table + " " + tr.Alias
+	}
+	return tr.Table
+}
@@ -1,6 +1,9 @@
 package plan
 
 import (
+	"maps"
+	"slices"
+
 	"github.com/kanthorlabs/kanthorkv/metadata"
 	"github.com/kanthorlabs/kanthorkv/parser"
 	"github.com/kanthorlabs/kanthorkv/query"
@@ -20,49 +23,117 @@ func NewBasicQueryPlanner(mdm *metadata.MetadataMgr) *BasicQueryPlanner {
 }
 
 // CreatePlan creates a query plan by first taking the product of all tables
-// and views; it then selects on the predicate; and finally it projects
-// on the fields list.
+// and views; it then selects on the predicate, aggregates the groups,
+// sorts on the ORDER BY fields, and finally it projects on the fields list.
 func (bqp *BasicQueryPlanner) CreatePlan(data *parser.QueryData, tx transaction.Transaction) (query.Plan, error) {
-	// Step 1: create a plan for each mentioned table or view.
-	plans := make([]query.Plan, 0, len(data.Tables))
-	for _, tblname := range data.Tables {
-		viewdef, err := bqp.mdm.GetViewDef(tblname, tx)
-		if err != nil {
-			return nil, err
-		}
-
-		if viewdef != "" {
-			// Recursively plan the view.
-			lexer := parser.NewLexer(viewdef)
-			p := parser.New(lexer)
-			viewdata, err := p.Query()
-			if err != nil {
-				return nil, err
-			}
-			plan, err := bqp.CreatePlan(viewdata, tx)
-			if err != nil {
-				return nil, err
-			}
-			plans = append(plans, plan)
-		} else {
-			plan, err := NewTablePlan(tblname, tx, bqp.mdm)
-			if err != nil {
+	// Step 1: create a plan for each mentioned table or view,
+	// under the field names the query resolves to
+	sc, data, err := newQueryScope(data, tx, bqp.mdm, bqp)
+	if err != nil {
+		return nil, err
+	}
+	plans := make([]query.Plan, 0, len(data.Tables)+len(data.Joins))
+	for _, ref := range data.Tables {
+		plan := sc.plans[ref.Name()]
+		if tp, ok := plan.(*TablePlan); ok {
+			if plan, err = bqp.tablePlan(tp, data.Pred); err != nil {
 				return nil, err
 			}
-			plans = append(plans, plan)
 		}
+		plans = append(plans, plan)
+	}
+	for _, join := range data.Joins {
+		plan, err := planJoins(join, sc, tx, bqp.mdm)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
 	}
 
-	// Step 2: create the product of all table plans
+	// Step 2: join all table plans, through an index when possible
 	plan := plans[0]
 	plans = plans[1:]
 	for _, nextplan := range plans {
-		plan = NewProductPlan(plan, nextplan)
+		var err error
+		if plan, err = bqp.joinPlan(plan, nextplan, data.Pred, tx); err != nil {
+			return nil, err
+		}
 	}
 
 	// Step 3: add a select plan for the predicate
 	plan = NewSelectPlan(plan, data.Pred)
 
-	// Step 4: project on the field names
-	return NewProjectPlan(plan, data.Fields), nil
+	// Step 4: aggregate the groups
+	plan, err = groupBy(plan, data, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 5: compute the select list, sorted on the ORDER BY fields
+	plan, err = project(plan, data, tx, bqp.mdm)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 6: keep the records of the LIMIT and OFFSET clauses
+	return limit(plan, data), nil
+}
+
+// tablePlan reads a table through an index when the predicate equates an indexed field with a constant,
+// the index expected to return the fewest records wins.
+func (bqp *BasicQueryPlanner) tablePlan(tp *TablePlan, pred *query.Predicate) (query.Plan, error) {
+	indexes, err := tp.Indexes(bqp.mdm)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *IndexSelectPlan
+	for _, fldname := range slices.Sorted(maps.Keys(indexes)) {
+		// an index can only be searched with a key of its own type
+		val := pred.EquatesWithConstant(fldname)
+		if val == nil || val.Type() != tp.Schema().Type(fldname) {
+			continue
+		}
+		isp := NewIndexSelectPlan(tp, indexes[fldname], *val)
+		if best == nil || isp.RecordsOutput() < best.RecordsOutput() {
+			best = isp
+		}
+	}
+	if best == nil {
+		return tp, nil
+	}
+	return best, nil
+}
+
+// joinPlan joins p2 to p1 through an index of p2 when the predicate equates one of its indexed fields
+// with a field of p1, otherwise it takes the product.
+// Of several usable indexes the one with the fewest blocks accessed wins.
+func (bqp *BasicQueryPlanner) joinPlan(p1, p2 query.Plan, pred *query.Predicate, tx transaction.Transaction) (query.Plan, error) {
+	tp, ok := p2.(*TablePlan)
+	if !ok {
+		return NewProductPlan(p1, p2), nil
+	}
+	indexes, err := tp.Indexes(bqp.mdm)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *IndexJoinPlan
+	for _, fldname := range slices.Sorted(maps.Keys(indexes)) {
+		joinfield := pred.EquatesWithField(fldname)
+		if joinfield == nil || !p1.Schema().HasField(*joinfield) {
+			continue
+		}
+		if p1.Schema().Type(*joinfield) != tp.Schema().Type(fldname) {
+			continue
+		}
+		ijp := NewIndexJoinPlan(p1, tp, indexes[fldname], *joinfield)
+		if best == nil || ijp.BlocksAccessed() < best.BlocksAccessed() {
+			best = ijp
+		}
+	}
+	if best == nil {
+		return NewProductPlan(p1, p2), nil
+	}
+	return best, nil
 }
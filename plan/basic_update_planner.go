@@ -1,6 +1,10 @@
 package plan
 
 import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+
 	"github.com/kanthorlabs/kanthorkv/metadata"
 	"github.com/kanthorlabs/kanthorkv/parser"
 	"github.com/kanthorlabs/kanthorkv/query"
@@ -26,6 +30,9 @@ func (p *BasicUpdatePlanner) ExecuteDelete(data *parser.DeleteData, tx transacti
 	if err != nil {
 		return 0, err
 	}
+	if data, err = resolveDelete(data, plan); err != nil {
+		return 0, err
+	}
 
 	plan = NewSelectPlan(plan, data.Pred)
 	s, err := plan.Open()
@@ -51,6 +58,13 @@ func (p *BasicUpdatePlanner) ExecuteUpdate(data *parser.UpdateData, tx transacti
 	if err != nil {
 		return 0, err
 	}
+	if data, err = resolveUpdate(data, plan); err != nil {
+		return 0, err
+	}
+
+	if err := checkNewValue(data, plan.Schema()); err != nil {
+		return 0, err
+	}
 
 	plan = NewSelectPlan(plan, data.Pred)
 	s, err := plan.Open()
@@ -65,6 +79,9 @@ func (p *BasicUpdatePlanner) ExecuteUpdate(data *parser.UpdateData, tx transacti
 		if err != nil {
 			return 0, err
 		}
+		if err := checkValue(plan.Schema(), data.TargetField, val); err != nil {
+			return 0, err
+		}
 		err = us.SetVal(data.TargetField, val)
 		if err != nil {
 			return 0, err
@@ -80,6 +97,9 @@ func (p *BasicUpdatePlanner) ExecuteInsert(data *parser.InsertData, tx transacti
 	if err != nil {
 		return 0, err
 	}
+	if err := checkInsert(data, plan.Schema()); err != nil {
+		return 0, err
+	}
 	s, err := plan.Open()
 	if err != nil {
 		return 0, err
@@ -115,8 +135,57 @@ func (p *BasicUpdatePlanner) ExecuteCreateView(data *parser.CreateViewData, tx t
 }
 
 func (p *BasicUpdatePlanner) ExecuteCreateIndex(data *parser.CreateIndexData, tx transaction.Transaction) (int, error) {
-	if err := p.mdm.CreateIndex(data.IndexName, data.TableName, data.FieldName, tx); err != nil {
+	if err := p.mdm.CreateIndex(data.IndexName, data.TableName, data.FieldName, data.IndexType, tx); err != nil {
 		return 0, err
 	}
 	return 0, nil
 }
+
+// checkNewValue makes sure the new value of an UPDATE fits the modified field of records of schema sch,
+// before any record is changed.
+func checkNewValue(data *parser.UpdateData, sch *record.Schema) error {
+	if !sch.HasField(data.TargetField) {
+		return fmt.Errorf("field %s not found", data.TargetField)
+	}
+	t, _, err := data.NewValue.Type(sch)
+	if err != nil {
+		return err
+	}
+	if t != sch.Type(data.TargetField) {
+		return fmt.Errorf("cannot set %s field %s to %s", sch.Type(data.TargetField), data.TargetField, data.NewValue)
+	}
+	return nil
+}
+
+// checkInsert makes sure every value of an INSERT fits its field of records of schema sch,
+// before a slot is taken.
+func checkInsert(data *parser.InsertData, sch *record.Schema) error {
+	if len(data.Fields) != len(data.Values) {
+		return fmt.Errorf("%d fields but %d values", len(data.Fields), len(data.Values))
+	}
+	for i, fldname := range data.Fields {
+		if !sch.HasField(fldname) {
+			return fmt.Errorf("field %s not found", fldname)
+		}
+		if err := checkValue(sch, fldname, data.Values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkValue makes sure val fits in the field fldname of records of schema sch,
+// a value of its type, a string within the length of the field and an integer within the 4 bytes of its slot.
+func checkValue(sch *record.Schema, fldname string, val record.Constant) error {
+	switch {
+	case val.IsNull():
+		return nil
+	case val.Type() != sch.Type(fldname):
+		return fmt.Errorf("cannot set %s field %s to %s", sch.Type(fldname), fldname, val)
+	case val.Type() == record.StringField && utf8.RuneCountInString(val.AsString()) > sch.Length(fldname):
+		return fmt.Errorf("value %s is longer than the %d characters of field %s", val, sch.Length(fldname), fldname)
+	case val.Type() == record.IntegerField && (val.AsInt() < math.MinInt32 || val.AsInt() > math.MaxInt32):
+		return fmt.Errorf("value %s overflows the 4 bytes of integer field %s", val, fldname)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdatePlanner_checkValue(t *testing.T) {
+	env := setupTest(t, 8)
+	env.exec(t,
+		"create table t (a int, s varchar(5))",
+		"insert into t (a, s) values (2147483647, 'abc')",
+		"insert into t (a, s) values (-2147483647, 'de')",
+	)
+
+	for name, up := range map[string]UpdatePlanner{"basic": NewBasicUpdatePlanner(env.mdm), "index": NewIndexUpdatePlanner(env.mdm)} {
+		planner := NewPlanner(NewBasicQueryPlanner(env.mdm), up)
+		for _, sql := range []string{
+			"update t set s = s || 'xyz'",
+			"update t set s = 'abcdef'",
+			"update t set a = a + 1 where a > 0",
+			"update t set a = a - 2 where a < 0",
+			"update t set a = a * 2",
+		} {
+			_, err := planner.ExecuteUpdate(sql, env.tx)
+			require.Error(t, err, "%s: %s", name, sql)
+		}
+
+		// the values that fit are written, up to the length of the field and the bounds of an int
+		for _, sql := range []string{
+			"update t set s = 'vwxyz'",
+			"update t set a = -2147483647 - 1 where a < 0",
+			"update t set a = a - 1 where a > 0",
+			"update t set a = a + 1 where a > 0",
+		} {
+			_, err := planner.ExecuteUpdate(sql, env.tx)
+			require.NoError(t, err, "%s: %s", name, sql)
+		}
+		require.ElementsMatch(t, []string{"2147483647 'vwxyz'", "-2147483648 'vwxyz'"}, readRows(t, env.table(t, "t"), "a", "s"))
+	}
+}
+
+func TestUpdatePlanner_CreateTableFields(t *testing.T) {
+	env := setupTest(t, 8)
+
+	// the slot flag has a NULL bit for each of the first NULLABLE_FIELDS fields only
+	fields := make([]string, 0, record.NULLABLE_FIELDS+1)
+	for i := range record.NULLABLE_FIELDS {
+		fields = append(fields, fmt.Sprintf("f%d int", i))
+	}
+	env.exec(t, fmt.Sprintf("create table fit (%s)", strings.Join(fields, ", ")))
+
+	fields = append(fields, "extra int")
+	_, err := env.planner.ExecuteUpdate(fmt.Sprintf("create table wide (%s)", strings.Join(fields, ", ")), env.tx)
+	require.ErrorContains(t, err, "TOO_MANY_FIELDS")
+	_, err = env.mdm.GetLayout("wide", env.tx)
+	require.Error(t, err)
+}
+
+func TestUpdatePlanner_checkInsert(t *testing.T) {
+	env := setupTest(t, 8)
+	env.exec(t, "create table t (a int, s varchar(5))")
+
+	for name, up := range map[string]UpdatePlanner{"basic": NewBasicUpdatePlanner(env.mdm), "index": NewIndexUpdatePlanner(env.mdm)} {
+		planner := NewPlanner(NewBasicQueryPlanner(env.mdm), up)
+		for _, sql := range []string{
+			"insert into t (a, s) values ('x', 'abc')",
+			"insert into t (a, s) values (1, 2)",
+			"insert into t (a, s) values (1, 'abcdef')",
+			"insert into t (a, missing) values (1, 'abc')",
+		} {
+			_, err := planner.ExecuteUpdate(sql, env.tx)
+			require.Error(t, err, "%s: %s", name, sql)
+		}
+	}
+
+	// a rejected insert takes no slot
+	require.Empty(t, readRows(t, env.table(t, "t"), "a", "s"))
+}
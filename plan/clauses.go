@@ -0,0 +1,164 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// planJoins plans a table and its join clauses from left to right, the order outer joins need.
+// The ON condition of an inner join selects from the join of both sides.
+// The one of an outer join is checked on every pair instead, only its terms on the side that gets padded with NULLs
+// can select from that side beforehand.
+func planJoins(join *parser.JoinData, sc *scope, tx transaction.Transaction, mdm *metadata.MetadataMgr) (query.Plan, error) {
+	p := sc.plans[join.Table.Name()]
+	for _, clause := range join.Clauses {
+		tp, err := sc.tablePlanner(clause.Table, clause.On, tx, mdm)
+		if err != nil {
+			return nil, err
+		}
+		switch clause.Kind {
+		case parser.JOIN_LEFT:
+			p = NewOuterJoinPlan(p, tp.MakeSelectPlan(), clause.On)
+		case parser.JOIN_RIGHT:
+			if sub := clause.On.SelectSubPred(p.Schema()); sub != nil {
+				p = NewSelectPlan(p, sub)
+			}
+			p = NewOuterJoinPlan(tp.myplan, p, clause.On)
+		default:
+			joined := tp.MakeJoinPlan(p)
+			if joined == nil {
+				joined = tp.MakeProductPlan(p)
+			}
+			p = NewSelectPlan(joined, clause.On)
+		}
+	}
+	return p, nil
+}
+
+// groupBy aggregates p on the GROUP BY fields of data and keeps the groups that satisfy HAVING.
+// A query with aggregation functions but no GROUP BY has a single group, even over no records.
+func groupBy(p query.Plan, data *parser.QueryData, tx transaction.Transaction) (query.Plan, error) {
+	if len(data.GroupBy) == 0 && len(data.AggFns) == 0 {
+		if data.Having.String() != "" {
+			return nil, errors.New("HAVING needs GROUP BY or an aggregation function")
+		}
+		return p, nil
+	}
+
+	gp, err := NewGroupByPlan(tx, p, data.GroupBy, data.AggFns)
+	if err != nil {
+		return nil, err
+	}
+	// the records of a group only agree on the group fields
+	for _, expr := range data.Exprs {
+		if !expr.AppliesTo(gp.Schema()) {
+			return nil, fmt.Errorf("%s is neither grouped nor aggregated", expr)
+		}
+	}
+	if data.Having.String() == "" {
+		return gp, nil
+	}
+	return NewSelectPlan(gp, data.Having), nil
+}
+
+// project computes the select list of data on p, in the order of the ORDER BY clause.
+// The records are sorted before the projection when they have every sort field, so an index can still
+// provide the order. Otherwise the clause names columns of the select list, sorted after the projection.
+func project(p query.Plan, data *parser.QueryData, tx transaction.Transaction, mdm *metadata.MetadataMgr) (query.Plan, error) {
+	sortFirst := true
+	for _, sf := range data.OrderBy {
+		sortFirst = sortFirst && p.Schema().HasField(sf.Name) && !redefines(data, sf.Name)
+	}
+
+	// a query with a LIMIT only needs its first records in order
+	n := parser.NO_LIMIT
+	if data.Limit != parser.NO_LIMIT {
+		n = data.Limit + data.Offset
+	}
+	var err error
+	if sortFirst {
+		if p, err = orderBy(p, data.OrderBy, n, tx, mdm); err != nil {
+			return nil, err
+		}
+	}
+	pp, err := NewComputedProjectPlan(p, data.Fields, data.Exprs)
+	if err != nil {
+		return nil, err
+	}
+	if sortFirst {
+		return pp, nil
+	}
+	return orderBy(pp, data.OrderBy, n, tx, mdm)
+}
+
+// limit keeps the records of p selected by the LIMIT and OFFSET clauses of data.
+func limit(p query.Plan, data *parser.QueryData) query.Plan {
+	if data.Limit == parser.NO_LIMIT && data.Offset == 0 {
+		return p
+	}
+	return NewLimitPlan(p, data.Limit, data.Offset)
+}
+
+// redefines tells whether the select list of data names a column fldname that is not the field fldname,
+// like b in SELECT a AS b.
+func redefines(data *parser.QueryData, fldname string) bool {
+	for i, expr := range data.Exprs {
+		if data.Fields[i] == fldname && (expr.FieldName() == nil || *expr.FieldName() != fldname) {
+			return true
+		}
+	}
+	return false
+}
+
+// orderBy sorts p on the fields of an ORDER BY clause, only the first n records are needed unless n is parser.NO_LIMIT.
+// A table ordered on a single ascending field with a B-tree index is read through the index instead,
+// which returns its records in that order without sorting them.
+// When the first n records fit in memory, they are picked in a single pass without writing sorted runs.
+func orderBy(p query.Plan, sortFields []query.SortField, n int, tx transaction.Transaction, mdm *metadata.MetadataMgr) (query.Plan, error) {
+	if len(sortFields) == 0 {
+		return p, nil
+	}
+	if len(sortFields) == 1 && !sortFields[0].Desc {
+		ip, err := indexOrdered(p, sortFields[0].Name, tx, mdm)
+		if err != nil {
+			return nil, err
+		}
+		if ip != nil {
+			return ip, nil
+		}
+	}
+	if n != parser.NO_LIMIT && n <= memoryRows(tx, p.Schema()) {
+		return NewTopNPlan(p, sortFields, n)
+	}
+	return NewOrderedSortPlan(tx, p, sortFields)
+}
+
+// indexOrdered replaces the table read under the selections of p by a read through a B-tree index on fldname,
+// or returns nil when p reads something else or the table has no such index.
+func indexOrdered(p query.Plan, fldname string, tx transaction.Transaction, mdm *metadata.MetadataMgr) (query.Plan, error) {
+	switch p := p.(type) {
+	case *SelectPlan:
+		inner, err := indexOrdered(p.p, fldname, tx, mdm)
+		if inner == nil || err != nil {
+			return nil, err
+		}
+		return NewSelectPlan(inner, p.pred), nil
+	case *TablePlan:
+		indexes, err := p.Indexes(mdm)
+		if err != nil {
+			return nil, err
+		}
+		ii, ok := indexes[fldname]
+		if !ok || ii.IndexType() != index.TYPE_BTREE {
+			return nil, nil
+		}
+		return NewIndexOrderPlan(p, ii), nil
+	}
+	return nil, nil
+}
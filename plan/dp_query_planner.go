@@ -0,0 +1,91 @@
+package plan
+
+import (
+	"math/bits"
+
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// DP_MAX_TABLES is the default number of tables above which join orders are chosen greedily,
+// the enumeration looks at n * 2^n joins
+const DP_MAX_TABLES = 8
+
+// DPQueryPlanner chooses the cheapest left-deep join order by dynamic programming over the subsets of tables.
+// Queries with more than maxTables tables are planned by HeuristicQueryPlanner.
+type DPQueryPlanner struct {
+	mdm       *metadata.MetadataMgr
+	maxTables int
+	greedy    *HeuristicQueryPlanner
+}
+
+var _ QueryPlanner = (*DPQueryPlanner)(nil)
+
+// NewDPQueryPlanner creates a new DPQueryPlanner, a non-positive maxTables means DP_MAX_TABLES.
+func NewDPQueryPlanner(mdm *metadata.MetadataMgr, maxTables int) *DPQueryPlanner {
+	if maxTables <= 0 {
+		maxTables = DP_MAX_TABLES
+	}
+	return &DPQueryPlanner{mdm: mdm, maxTables: maxTables, greedy: NewHeuristicQueryPlanner(mdm)}
+}
+
+// CreatePlan creates a query plan by finding the cheapest plan of every subset of tables,
+// smallest subsets first. The plan of a subset joins one of its tables
+// to the cheapest plan of the others, every selection term is pushed down to its table.
+func (dqp *DPQueryPlanner) CreatePlan(data *parser.QueryData, tx transaction.Transaction) (query.Plan, error) {
+	if len(data.Tables)+len(data.Joins) > dqp.maxTables {
+		return dqp.greedy.CreatePlan(data, tx)
+	}
+
+	// Step 1: create a table planner for each mentioned table or view,
+	// under the field names the query resolves to
+	sc, data, err := newQueryScope(data, tx, dqp.mdm, dqp)
+	if err != nil {
+		return nil, err
+	}
+	planners, err := newTablePlanners(data, sc, tx, dqp.mdm)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: the plan of a single table is its select plan
+	// a subset of tables is a bit mask of their positions in planners
+	best := make([]query.Plan, 1<<len(planners))
+	for i, tp := range planners {
+		best[1<<i] = tp.MakeSelectPlan()
+	}
+
+	// Step 3: a subset only depends on smaller subsets, which have smaller masks
+	for subset := 1; subset < len(best); subset++ {
+		if bits.OnesCount(uint(subset)) < 2 {
+			continue
+		}
+		for i, tp := range planners {
+			if subset&(1<<i) == 0 {
+				continue
+			}
+			for _, p := range tp.JoinPlans(best[subset&^(1<<i)]) {
+				if best[subset] == nil || cheaper(p, best[subset]) {
+					best[subset] = p
+				}
+			}
+		}
+	}
+
+	// Step 4: aggregate the groups
+	plan, err := groupBy(best[len(best)-1], data, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 5: compute the select list, sorted on the ORDER BY fields
+	plan, err = project(plan, data, tx, dqp.mdm)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 6: keep the records of the LIMIT and OFFSET clauses
+	return limit(plan, data), nil
+}
@@ -1,7 +1,9 @@
 package plan
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/kanthorlabs/kanthorkv/query"
 	"github.com/kanthorlabs/kanthorkv/record"
@@ -10,6 +12,8 @@ import (
 
 var _ query.Plan = (*GroupByPlan)(nil)
 
+// GroupByPlan sorts its input on the group fields, then aggregates the records of every group.
+// Its schema has the group fields followed by a field per aggregation function, named after the function.
 type GroupByPlan struct {
 	plan        *SortPlan
 	groupFields []string
@@ -27,7 +31,14 @@ func NewGroupByPlan(tx transaction.Transaction, plan query.Plan, groupFields []s
 		schema.Add(fldname, plan.Schema())
 	}
 	for _, fn := range aggFns {
-		schema.AddIntField(fn.FieldName())
+		if schema.HasField(fn.FieldName()) {
+			continue
+		}
+		t, length, err := fn.Type(plan.Schema())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn.FieldName(), err)
+		}
+		schema.AddField(fn.FieldName(), t, length)
 	}
 	return &GroupByPlan{
 		plan:        sortPlan,
@@ -42,11 +53,11 @@ func (gp *GroupByPlan) Open() (record.Scan, error) {
 	if err != nil {
 		return nil, err
 	}
-	ss, ok := s.(*query.SortScan)
-	if !ok {
-		return nil, err
+	gs, err := query.NewGroupByScan(s.(*query.SortScan), gp.groupFields, gp.aggFns)
+	if err != nil {
+		return nil, errors.Join(err, s.Close())
 	}
-	return query.NewGroupByScan(ss, gp.groupFields, gp.aggFns), nil
+	return gs, nil
 }
 
 func (gp *GroupByPlan) BlocksAccessed() int {
@@ -62,7 +73,7 @@ func (gp *GroupByPlan) RecordsOutput() int {
 }
 
 func (gp *GroupByPlan) DistinctValues(fieldName string) int {
-	if gp.schema.HasField(fieldName) {
+	if slices.Contains(gp.groupFields, fieldName) {
 		return gp.plan.DistinctValues(fieldName)
 	}
 	return gp.RecordsOutput()
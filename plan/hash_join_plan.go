@@ -0,0 +1,90 @@
+package plan
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+var _ query.Plan = (*HashJoinPlan)(nil)
+
+// NewHashJoinPlan creates a plan that joins the records of p1 and p2 whose fldname1 equals fldname2.
+// The input expected to output fewer records is the build side of the hash table.
+func NewHashJoinPlan(tx transaction.Transaction, p1, p2 query.Plan, fldname1, fldname2 string) *HashJoinPlan {
+	schema := record.NewSchema()
+	schema.AddAll(p1.Schema())
+	schema.AddAll(p2.Schema())
+	hjp := &HashJoinPlan{tx: tx, build: p1, probe: p2, buildfield: fldname1, probefield: fldname2, schema: schema}
+	if p2.RecordsOutput() < p1.RecordsOutput() {
+		hjp.build, hjp.probe = p2, p1
+		hjp.buildfield, hjp.probefield = fldname2, fldname1
+	}
+	return hjp
+}
+
+type HashJoinPlan struct {
+	tx           transaction.Transaction
+	build, probe query.Plan
+	buildfield   string
+	probefield   string
+	schema       *record.Schema
+}
+
+func (hjp *HashJoinPlan) Open() (record.Scan, error) {
+	build, err := hjp.build.Open()
+	if err != nil {
+		return nil, err
+	}
+	probe, err := hjp.probe.Open()
+	if err != nil {
+		return nil, errors.Join(err, build.Close())
+	}
+	return query.NewHashJoinScan(
+		hjp.tx,
+		build, hjp.build.Schema(), hjp.buildfield,
+		probe, hjp.probe.Schema(), hjp.probefield,
+		hjp.maxRows(), hjp.build.RecordsOutput(),
+	)
+}
+
+// BlocksAccessed is the cost of reading both inputs when the build side fits in the free buffers.
+// Otherwise both inputs are also written to and read back from their partitions,
+// once more for every time the partitions would still be too big to fit.
+func (hjp *HashJoinPlan) BlocksAccessed() int {
+	cost := hjp.build.BlocksAccessed() + hjp.probe.BlocksAccessed()
+	spill := 2 * (materializedBlocks(hjp.tx, hjp.build) + materializedBlocks(hjp.tx, hjp.probe))
+	partitions := max(2, hjp.tx.AvailableBuffs()/2)
+	for size := hjp.build.RecordsOutput(); size > hjp.maxRows(); size /= partitions {
+		cost += spill
+	}
+	return cost
+}
+
+func (hjp *HashJoinPlan) RecordsOutput() int {
+	rf := max(hjp.build.DistinctValues(hjp.buildfield), hjp.probe.DistinctValues(hjp.probefield), 1)
+	return hjp.build.RecordsOutput() * hjp.probe.RecordsOutput() / rf
+}
+
+func (hjp *HashJoinPlan) DistinctValues(fldname string) int {
+	if hjp.build.Schema().HasField(fldname) {
+		return hjp.build.DistinctValues(fldname)
+	}
+	return hjp.probe.DistinctValues(fldname)
+}
+
+func (hjp *HashJoinPlan) Schema() *record.Schema {
+	return hjp.schema
+}
+
+// maxRows is the number of build records that fit in the free buffers of the transaction.
+func (hjp *HashJoinPlan) maxRows() int {
+	layout := record.NewLayoutOfSchema(hjp.build.Schema())
+	return max(hjp.tx.AvailableBuffs(), 1) * (hjp.tx.BlockSize() / layout.SlotSize())
+}
+
+// materializedBlocks is the number of blocks the output of p takes in a temporary table.
+func materializedBlocks(tx transaction.Transaction, p query.Plan) int {
+	return NewMaterializePlan(tx, p).BlocksAccessed()
+}
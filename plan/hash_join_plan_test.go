@@ -0,0 +1,31 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashJoinPlan(t *testing.T) {
+	// the few free buffers hold a small part of either side, so both are partitioned
+	env := setupTest(t, 5)
+
+	env.exec(t, "create table student (sid int, sname varchar(10), majorid int)", "create table dept (did int, dname varchar(10))")
+	for i := range 300 {
+		env.exec(t, fmt.Sprintf("insert into student (sid, sname, majorid) values (%d, 's%d', %d)", i, i, i%40))
+	}
+	for i := range 30 {
+		env.exec(t, fmt.Sprintf("insert into dept (did, dname) values (%d, 'd%d')", i, i))
+	}
+
+	expected := make([]string, 0)
+	for i := range 300 {
+		if i%40 < 30 {
+			expected = append(expected, fmt.Sprintf("'s%d' 'd%d'", i, i%40))
+		}
+	}
+	student, dept := env.table(t, "student"), env.table(t, "dept")
+	require.ElementsMatch(t, expected, readRows(t, NewHashJoinPlan(env.tx, dept, student, "did", "majorid"), "sname", "dname"))
+	require.ElementsMatch(t, expected, readRows(t, NewHashJoinPlan(env.tx, student, dept, "majorid", "did"), "sname", "dname"))
+}
@@ -0,0 +1,144 @@
+package plan
+
+import (
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// HeuristicQueryPlanner orders the joins of a query greedily.
+type HeuristicQueryPlanner struct {
+	mdm *metadata.MetadataMgr
+}
+
+var _ QueryPlanner = (*HeuristicQueryPlanner)(nil)
+
+// NewHeuristicQueryPlanner creates a new HeuristicQueryPlanner.
+func NewHeuristicQueryPlanner(mdm *metadata.MetadataMgr) *HeuristicQueryPlanner {
+	return &HeuristicQueryPlanner{mdm: mdm}
+}
+
+// CreatePlan creates a query plan by pushing every selection term down to its table,
+// starting from the table that outputs the fewest records,
+// and then repeatedly joining the table that gives the cheapest plan.
+// Tables linked to the plan by a join term are preferred over products.
+func (hqp *HeuristicQueryPlanner) CreatePlan(data *parser.QueryData, tx transaction.Transaction) (query.Plan, error) {
+	// Step 1: create a table planner for each mentioned table or view,
+	// under the field names the query resolves to
+	sc, data, err := newQueryScope(data, tx, hqp.mdm, hqp)
+	if err != nil {
+		return nil, err
+	}
+	planners, err := newTablePlanners(data, sc, tx, hqp.mdm)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: choose the lowest-size plan to begin the join order
+	current, planners := lowestSelectPlan(planners)
+
+	// Step 3: repeatedly add a plan to the join order
+	for len(planners) > 0 {
+		var p query.Plan
+		if p, planners = lowestJoinPlan(planners, current); p == nil {
+			p, planners = lowestProductPlan(planners, current)
+		}
+		current = p
+	}
+
+	// Step 4: aggregate the groups
+	if current, err = groupBy(current, data, tx); err != nil {
+		return nil, err
+	}
+
+	// Step 5: compute the select list, sorted on the ORDER BY fields
+	current, err = project(current, data, tx, hqp.mdm)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 6: keep the records of the LIMIT and OFFSET clauses
+	return limit(current, data), nil
+}
+
+// newTablePlanners creates a table planner for every table of the query, under the names of sc.
+func newTablePlanners(data *parser.QueryData, sc *scope, tx transaction.Transaction, mdm *metadata.MetadataMgr) ([]*TablePlanner, error) {
+	planners := make([]*TablePlanner, 0, len(data.Tables)+len(data.Joins))
+	for _, ref := range data.Tables {
+		tp, err := sc.tablePlanner(ref, data.Pred, tx, mdm)
+		if err != nil {
+			return nil, err
+		}
+		planners = append(planners, tp)
+	}
+	// the tables of an outer join keep their order, the join is planned on its own like a view
+	for _, join := range data.Joins {
+		plan, err := planJoins(join, sc, tx, mdm)
+		if err != nil {
+			return nil, err
+		}
+		planners = append(planners, NewTablePlanner(plan, nil, data.Pred, tx))
+	}
+	return planners, nil
+}
+
+// lowestSelectPlan returns the select plan with the fewest records and the planners left.
+func lowestSelectPlan(planners []*TablePlanner) (query.Plan, []*TablePlanner) {
+	var best query.Plan
+	besti := 0
+	for i, tp := range planners {
+		p := tp.MakeSelectPlan()
+		if best == nil || p.RecordsOutput() < best.RecordsOutput() {
+			best, besti = p, i
+		}
+	}
+	return best, removePlanner(planners, besti)
+}
+
+// lowestJoinPlan returns the cheapest join with current and the planners left,
+// or nil when no planner joins current.
+func lowestJoinPlan(planners []*TablePlanner, current query.Plan) (query.Plan, []*TablePlanner) {
+	var best query.Plan
+	besti := 0
+	for i, tp := range planners {
+		p := tp.MakeJoinPlan(current)
+		if p == nil {
+			continue
+		}
+		if best == nil || cheaper(p, best) {
+			best, besti = p, i
+		}
+	}
+	if best == nil {
+		return nil, planners
+	}
+	return best, removePlanner(planners, besti)
+}
+
+// lowestProductPlan returns the cheapest product with current and the planners left.
+func lowestProductPlan(planners []*TablePlanner, current query.Plan) (query.Plan, []*TablePlanner) {
+	var best query.Plan
+	besti := 0
+	for i, tp := range planners {
+		p := tp.MakeProductPlan(current)
+		if best == nil || cheaper(p, best) {
+			best, besti = p, i
+		}
+	}
+	return best, removePlanner(planners, besti)
+}
+
+// cheaper compares plans by the blocks they access, then by the records they output.
+func cheaper(p1, p2 query.Plan) bool {
+	if p1.BlocksAccessed() != p2.BlocksAccessed() {
+		return p1.BlocksAccessed() < p2.BlocksAccessed()
+	}
+	return p1.RecordsOutput() < p2.RecordsOutput()
+}
+
+func removePlanner(planners []*TablePlanner, i int) []*TablePlanner {
+	rest := make([]*TablePlanner, 0, len(planners)-1)
+	rest = append(rest, planners[:i]...)
+	return append(rest, planners[i+1:]...)
+}
@@ -0,0 +1,57 @@
+package plan
+
+import (
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ query.Plan = (*IndexEntryPlan)(nil)
+
+// NewIndexEntryPlan creates a plan of the index entries of the records of tp for the field fldname,
+// a record of the plan holds the value of the field in dataval and the rid of the table record in block and id.
+func NewIndexEntryPlan(tp *TablePlan, fldname string) *IndexEntryPlan {
+	schema := record.NewSchema()
+	schema.AddField(query.INDEX_ENTRY_DATAVAL, tp.Schema().Type(fldname), tp.Schema().Length(fldname))
+	schema.AddIntField(query.INDEX_ENTRY_BLOCK)
+	schema.AddIntField(query.INDEX_ENTRY_ID)
+
+	return &IndexEntryPlan{
+		tp:      tp,
+		fldname: fldname,
+		schema:  schema,
+	}
+}
+
+type IndexEntryPlan struct {
+	tp      *TablePlan
+	fldname string
+	schema  *record.Schema
+}
+
+func (p *IndexEntryPlan) Open() (record.Scan, error) {
+	s, err := p.tp.Open()
+	if err != nil {
+		return nil, err
+	}
+	return query.NewIndexEntryScan(s.(*record.TableScan), p.fldname)
+}
+
+func (p *IndexEntryPlan) BlocksAccessed() int {
+	return p.tp.BlocksAccessed()
+}
+
+func (p *IndexEntryPlan) RecordsOutput() int {
+	return p.tp.RecordsOutput()
+}
+
+// DistinctValues returns the distinct values of the indexed field for dataval, the rids are all distinct.
+func (p *IndexEntryPlan) DistinctValues(fldname string) int {
+	if fldname == query.INDEX_ENTRY_DATAVAL {
+		return p.tp.DistinctValues(p.fldname)
+	}
+	return p.tp.RecordsOutput()
+}
+
+func (p *IndexEntryPlan) Schema() *record.Schema {
+	return p.schema
+}
@@ -0,0 +1,69 @@
+package plan
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ query.Plan = (*IndexJoinPlan)(nil)
+
+// NewIndexJoinPlan creates a plan that joins p1 with the table of p2
+// by searching the index ii of p2 with the joinfield of every p1 record.
+func NewIndexJoinPlan(p1 query.Plan, p2 *TablePlan, ii *metadata.IndexInfo, joinfield string) *IndexJoinPlan {
+	schema := record.NewSchema()
+	schema.AddAll(p1.Schema())
+	schema.AddAll(p2.Schema())
+	return &IndexJoinPlan{p1: p1, p2: p2, ii: ii, joinfield: joinfield, schema: schema}
+}
+
+type IndexJoinPlan struct {
+	p1        query.Plan
+	p2        *TablePlan
+	ii        *metadata.IndexInfo
+	joinfield string
+	schema    *record.Schema
+}
+
+func (ijp *IndexJoinPlan) Open() (record.Scan, error) {
+	s1, err := ijp.p1.Open()
+	if err != nil {
+		return nil, err
+	}
+	s2, err := ijp.p2.Open()
+	if err != nil {
+		return nil, errors.Join(err, s1.Close())
+	}
+	idx, err := ijp.ii.Open()
+	if err != nil {
+		return nil, errors.Join(err, s1.Close(), s2.Close())
+	}
+	s, err := query.NewIndexJoinScan(s1, idx, ijp.joinfield, s2.(*record.TableScan))
+	if err != nil {
+		return nil, errors.Join(err, s1.Close(), idx.Close(), s2.Close())
+	}
+	return s, nil
+}
+
+// BlocksAccessed is the cost of reading p1, plus one index search per p1 record,
+// plus one block per joined record.
+func (ijp *IndexJoinPlan) BlocksAccessed() int {
+	return ijp.p1.BlocksAccessed() + (ijp.p1.RecordsOutput() * ijp.ii.BlocksAccessed()) + ijp.RecordsOutput()
+}
+
+func (ijp *IndexJoinPlan) RecordsOutput() int {
+	return ijp.p1.RecordsOutput() * ijp.ii.RecordsOutput()
+}
+
+func (ijp *IndexJoinPlan) DistinctValues(fldname string) int {
+	if ijp.p1.Schema().HasField(fldname) {
+		return ijp.p1.DistinctValues(fldname)
+	}
+	return ijp.p2.DistinctValues(fldname)
+}
+
+func (ijp *IndexJoinPlan) Schema() *record.Schema {
+	return ijp.schema
+}
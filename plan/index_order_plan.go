@@ -0,0 +1,63 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ query.Plan = (*IndexOrderPlan)(nil)
+
+// NewIndexOrderPlan creates a plan that reads every record of a table
+// in ascending order of the field of its B-tree index ii.
+func NewIndexOrderPlan(p *TablePlan, ii *metadata.IndexInfo) *IndexOrderPlan {
+	return &IndexOrderPlan{p: p, ii: ii}
+}
+
+type IndexOrderPlan struct {
+	p  *TablePlan
+	ii *metadata.IndexInfo
+}
+
+func (iop *IndexOrderPlan) Open() (record.Scan, error) {
+	s, err := iop.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	idx, err := iop.ii.Open()
+	if err != nil {
+		return nil, errors.Join(err, s.Close())
+	}
+	bti, ok := idx.(*index.BTreeIndex)
+	if !ok {
+		err := fmt.Errorf("index %s is not a B-tree, its records are not ordered", iop.ii.IndexName())
+		return nil, errors.Join(err, idx.Close(), s.Close())
+	}
+	scan, err := query.NewIndexOrderScan(s.(*record.TableScan), bti, iop.p.Schema().Type(iop.p.fieldName(iop.ii.FieldName())))
+	if err != nil {
+		return nil, errors.Join(err, idx.Close(), s.Close())
+	}
+	return scan, nil
+}
+
+// BlocksAccessed is the cost of searching the index,
+// plus one block per record.
+func (iop *IndexOrderPlan) BlocksAccessed() int {
+	return iop.ii.BlocksAccessed() + iop.RecordsOutput()
+}
+
+func (iop *IndexOrderPlan) RecordsOutput() int {
+	return iop.p.RecordsOutput()
+}
+
+func (iop *IndexOrderPlan) DistinctValues(fldname string) int {
+	return iop.p.DistinctValues(fldname)
+}
+
+func (iop *IndexOrderPlan) Schema() *record.Schema {
+	return iop.p.Schema()
+}
@@ -0,0 +1,57 @@
+package plan
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ query.Plan = (*IndexSelectPlan)(nil)
+
+// NewIndexSelectPlan creates a plan that selects the records of a table
+// whose indexed field equals val.
+func NewIndexSelectPlan(p *TablePlan, ii *metadata.IndexInfo, val record.Constant) *IndexSelectPlan {
+	return &IndexSelectPlan{p: p, ii: ii, val: val}
+}
+
+type IndexSelectPlan struct {
+	p   *TablePlan
+	ii  *metadata.IndexInfo
+	val record.Constant
+}
+
+func (isp *IndexSelectPlan) Open() (record.Scan, error) {
+	s, err := isp.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	idx, err := isp.ii.Open()
+	if err != nil {
+		return nil, errors.Join(err, s.Close())
+	}
+	scan, err := query.NewIndexSelectScan(s.(*record.TableScan), idx, isp.val)
+	if err != nil {
+		return nil, errors.Join(err, idx.Close(), s.Close())
+	}
+	return scan, nil
+}
+
+// BlocksAccessed is the cost of searching the index,
+// plus one block per matching record.
+func (isp *IndexSelectPlan) BlocksAccessed() int {
+	return isp.ii.BlocksAccessed() + isp.RecordsOutput()
+}
+
+func (isp *IndexSelectPlan) RecordsOutput() int {
+	return isp.ii.RecordsOutput()
+}
+
+func (isp *IndexSelectPlan) DistinctValues(fldname string) int {
+	return isp.ii.DistinctValues(fldname)
+}
+
+func (isp *IndexSelectPlan) Schema() *record.Schema {
+	return isp.p.Schema()
+}
@@ -0,0 +1,303 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// IndexUpdatePlanner is a planner for SQL update statements
+// that keeps every index of the modified table up to date.
+type IndexUpdatePlanner struct {
+	mdm *metadata.MetadataMgr
+}
+
+var _ UpdatePlanner = (*IndexUpdatePlanner)(nil)
+
+// NewIndexUpdatePlanner creates a new IndexUpdatePlanner.
+func NewIndexUpdatePlanner(mdm *metadata.MetadataMgr) *IndexUpdatePlanner {
+	return &IndexUpdatePlanner{mdm: mdm}
+}
+
+func (p *IndexUpdatePlanner) ExecuteInsert(data *parser.InsertData, tx transaction.Transaction) (int, error) {
+	plan, err := NewTablePlan(data.TableName, tx, p.mdm)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkInsert(data, plan.Schema()); err != nil {
+		return 0, err
+	}
+	indexes, err := p.mdm.GetIndexes(data.TableName, tx)
+	if err != nil {
+		return 0, err
+	}
+	s, err := plan.Open()
+	if err != nil {
+		return 0, err
+	}
+	us := s.(record.UpdateScan)
+	// take the slot first, its rid goes into every index
+	if err = us.Insert(); err != nil {
+		return 0, errors.Join(err, us.Close())
+	}
+	rid := us.GetRid()
+
+	for i, val := range data.Values {
+		if err = us.SetVal(data.Fields[i], val); err != nil {
+			return 0, errors.Join(err, us.Close())
+		}
+	}
+	// a field left out of the statement keeps the value of an empty slot, its index needs it all the same
+	for _, ii := range indexes {
+		val, err := us.GetVal(ii.FieldName())
+		if err != nil {
+			return 0, errors.Join(err, us.Close())
+		}
+		if err := insertIndex(ii, val, rid); err != nil {
+			return 0, errors.Join(err, us.Close())
+		}
+	}
+
+	// we inserted one record
+	return 1, us.Close()
+}
+
+func (p *IndexUpdatePlanner) ExecuteDelete(data *parser.DeleteData, tx transaction.Transaction) (int, error) {
+	var plan query.Plan
+	plan, err := NewTablePlan(data.TableName, tx, p.mdm)
+	if err != nil {
+		return 0, err
+	}
+	if data, err = resolveDelete(data, plan); err != nil {
+		return 0, err
+	}
+	indexes, err := p.openIndexes(data.TableName, "", tx)
+	if err != nil {
+		return 0, err
+	}
+
+	plan = NewSelectPlan(plan, data.Pred)
+	s, err := plan.Open()
+	if err != nil {
+		return 0, errors.Join(err, closeIndexes(indexes))
+	}
+
+	// SelectPlan use SelectScan, that is implementation of UpdateScan
+	us, count := s.(record.UpdateScan), 0
+	for us.Next() {
+		// the index records go first, they are found by the rid of the record
+		rid := us.GetRid()
+		for _, fi := range indexes {
+			val, err := us.GetVal(fi.fldname)
+			if err != nil {
+				return 0, errors.Join(err, us.Close(), closeIndexes(indexes))
+			}
+			if err := fi.idx.Delete(&val, &rid); err != nil {
+				return 0, errors.Join(err, us.Close(), closeIndexes(indexes))
+			}
+		}
+
+		if err := us.Delete(); err != nil {
+			return 0, errors.Join(err, us.Close(), closeIndexes(indexes))
+		}
+		count++
+	}
+
+	return count, errors.Join(us.Close(), closeIndexes(indexes))
+}
+
+func (p *IndexUpdatePlanner) ExecuteUpdate(data *parser.UpdateData, tx transaction.Transaction) (int, error) {
+	var plan query.Plan
+	plan, err := NewTablePlan(data.TableName, tx, p.mdm)
+	if err != nil {
+		return 0, err
+	}
+	if data, err = resolveUpdate(data, plan); err != nil {
+		return 0, err
+	}
+	if err := checkNewValue(data, plan.Schema()); err != nil {
+		return 0, err
+	}
+	// only the indexes on the modified field change
+	indexes, err := p.openIndexes(data.TableName, data.TargetField, tx)
+	if err != nil {
+		return 0, err
+	}
+	closeIndex := func() error {
+		return closeIndexes(indexes)
+	}
+
+	plan = NewSelectPlan(plan, data.Pred)
+	s, err := plan.Open()
+	if err != nil {
+		return 0, errors.Join(err, closeIndex())
+	}
+
+	// SelectPlan use SelectScan, that is implementation of UpdateScan
+	us, count := s.(record.UpdateScan), 0
+	for us.Next() {
+		val, err := data.NewValue.Evaluate(us)
+		if err != nil {
+			return 0, errors.Join(err, us.Close(), closeIndex())
+		}
+		if err := checkValue(plan.Schema(), data.TargetField, val); err != nil {
+			return 0, errors.Join(err, us.Close(), closeIndex())
+		}
+		oldval, err := us.GetVal(data.TargetField)
+		if err != nil {
+			return 0, errors.Join(err, us.Close(), closeIndex())
+		}
+		if err = us.SetVal(data.TargetField, val); err != nil {
+			return 0, errors.Join(err, us.Close(), closeIndex())
+		}
+
+		rid := us.GetRid()
+		for _, fi := range indexes {
+			if err := fi.idx.Delete(&oldval, &rid); err != nil {
+				return 0, errors.Join(err, us.Close(), closeIndex())
+			}
+			if err := fi.idx.Insert(&val, &rid); err != nil {
+				return 0, errors.Join(err, us.Close(), closeIndex())
+			}
+		}
+		count++
+	}
+
+	return count, errors.Join(us.Close(), closeIndex())
+}
+
+func (p *IndexUpdatePlanner) ExecuteCreateTable(data *parser.CreateTableData, tx transaction.Transaction) (int, error) {
+	if err := p.mdm.CreateTable(data.TableName, data.Schema, tx); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+func (p *IndexUpdatePlanner) ExecuteCreateView(data *parser.CreateViewData, tx transaction.Transaction) (int, error) {
+	if err := p.mdm.CreateView(data.ViewName, data.QueryData.String(), tx); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// ExecuteCreateIndex records the index and fills it with the records already in the table,
+// returning the number of indexed records.
+func (p *IndexUpdatePlanner) ExecuteCreateIndex(data *parser.CreateIndexData, tx transaction.Transaction) (int, error) {
+	if err := p.mdm.CreateIndex(data.IndexName, data.TableName, data.FieldName, data.IndexType, tx); err != nil {
+		return 0, err
+	}
+	infos, err := p.mdm.GetIndexes(data.TableName, tx)
+	if err != nil {
+		return 0, err
+	}
+	var ii *metadata.IndexInfo
+	for _, info := range infos {
+		if info.IndexName() == data.IndexName {
+			ii = info
+		}
+	}
+	if ii == nil {
+		return 0, fmt.Errorf("index %s not found after it was created", data.IndexName)
+	}
+
+	tp, err := NewTablePlan(data.TableName, tx, p.mdm)
+	if err != nil {
+		return 0, err
+	}
+	var plan query.Plan = NewIndexEntryPlan(tp, data.FieldName)
+	// the entries of a table larger than the free buffers are loaded in key order,
+	// so the index fills one page at a time instead of reading its pages back and forth
+	size, err := tx.Size(data.TableName + ".tbl")
+	if err != nil {
+		return 0, err
+	}
+	if size > tx.AvailableBuffs() {
+		if plan, err = NewSortPlan(tx, plan, []string{query.INDEX_ENTRY_DATAVAL}); err != nil {
+			return 0, err
+		}
+	}
+
+	idx, err := ii.Open()
+	if err != nil {
+		return 0, err
+	}
+	s, err := plan.Open()
+	if err != nil {
+		return 0, errors.Join(err, idx.Close())
+	}
+
+	count := 0
+	for s.Next() {
+		val, err := s.GetVal(query.INDEX_ENTRY_DATAVAL)
+		if err != nil {
+			return 0, errors.Join(err, s.Close(), idx.Close())
+		}
+		blknum, err := s.GetInt(query.INDEX_ENTRY_BLOCK)
+		if err != nil {
+			return 0, errors.Join(err, s.Close(), idx.Close())
+		}
+		slot, err := s.GetInt(query.INDEX_ENTRY_ID)
+		if err != nil {
+			return 0, errors.Join(err, s.Close(), idx.Close())
+		}
+		rid := record.NewRID(blknum, slot)
+		if err := idx.Insert(&val, &rid); err != nil {
+			return 0, errors.Join(err, s.Close(), idx.Close())
+		}
+		count++
+	}
+
+	return count, errors.Join(s.Close(), idx.Close())
+}
+
+// fieldIndex is an open index and the field it indexes.
+type fieldIndex struct {
+	fldname string
+	idx     index.Index
+}
+
+// openIndexes opens the indexes of a table on the field fldname, or every index when fldname is empty.
+func (p *IndexUpdatePlanner) openIndexes(tblname, fldname string, tx transaction.Transaction) ([]fieldIndex, error) {
+	infos, err := p.mdm.GetIndexes(tblname, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]fieldIndex, 0, len(infos))
+	for _, ii := range infos {
+		if fldname != "" && ii.FieldName() != fldname {
+			continue
+		}
+		idx, err := ii.Open()
+		if err != nil {
+			return nil, errors.Join(err, closeIndexes(indexes))
+		}
+		indexes = append(indexes, fieldIndex{fldname: ii.FieldName(), idx: idx})
+	}
+	return indexes, nil
+}
+
+func closeIndexes(indexes []fieldIndex) error {
+	var errs []error
+	for _, fi := range indexes {
+		errs = append(errs, fi.idx.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func insertIndex(ii *metadata.IndexInfo, val record.Constant, rid record.RID) error {
+	idx, err := ii.Open()
+	if err != nil {
+		return err
+	}
+	if err := idx.Insert(&val, &rid); err != nil {
+		return errors.Join(err, idx.Close())
+	}
+	return idx.Close()
+}
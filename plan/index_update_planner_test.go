@@ -0,0 +1,109 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexUpdatePlanner_CreateIndexBackfill(t *testing.T) {
+	env := setupTest(t, 8)
+
+	// a field for every index type, the large table does not fit in the free buffers and is loaded in key order
+	fields := []string{"a", "b", "c"}
+	require.Len(t, index.Types, len(fields))
+	for tblname, size := range map[string]int{"small": 5, "large": 600} {
+		env.exec(t, fmt.Sprintf("create table %s (a int, b int, c int)", tblname))
+		for range size {
+			env.exec(t, fmt.Sprintf("insert into %s (a, b, c) values (%d, %d, %d)", tblname, fk.IntBetween(0, 500), fk.IntBetween(0, 500), fk.IntBetween(0, 500)))
+		}
+		blocks, err := env.tx.Size(tblname + ".tbl")
+		require.NoError(t, err)
+		require.Equal(t, tblname == "large", blocks > env.tx.AvailableBuffs(), tblname)
+
+		for i, fldname := range fields {
+			idxname := tblname + fldname
+			affected, err := env.planner.ExecuteUpdate(fmt.Sprintf("create index %s on %s (%s) using %s", idxname, tblname, fldname, index.Types[i]), env.tx)
+			require.NoError(t, err)
+			require.Equal(t, size, affected)
+
+			// the index finds the rid of every record under the value of its field
+			expected := make(map[int][]record.RID)
+			s, err := env.table(t, tblname).Open()
+			require.NoError(t, err)
+			ts := s.(*record.TableScan)
+			for ts.Next() {
+				val, err := ts.GetInt(fldname)
+				require.NoError(t, err)
+				expected[val] = append(expected[val], ts.GetRid())
+			}
+			require.NoError(t, ts.Close())
+
+			indexes, err := env.mdm.GetIndexInfo(tblname, env.tx)
+			require.NoError(t, err)
+			require.Equal(t, idxname, indexes[fldname].IndexName())
+			idx, err := indexes[fldname].Open()
+			require.NoError(t, err)
+			for val, rids := range expected {
+				key := record.NewIntConstant(val)
+				require.NoError(t, idx.BeforeFirst(&key))
+				found := make([]record.RID, 0)
+				for idx.Next() {
+					rid, err := idx.GetDataRID()
+					require.NoError(t, err)
+					found = append(found, *rid)
+				}
+				require.ElementsMatch(t, rids, found, "%s %d", idxname, val)
+			}
+			require.NoError(t, idx.Close())
+		}
+	}
+}
+
+func TestIndexUpdatePlanner_SeveralIndexes(t *testing.T) {
+	env := setupTest(t, 8)
+	env.exec(t, "create table t (a int, b int)")
+	for _, idxtype := range index.Types {
+		env.exec(t, fmt.Sprintf("create index a%s on t (a) using %s", idxtype, idxtype))
+	}
+	for i := range 50 {
+		env.exec(t, fmt.Sprintf("insert into t (a, b) values (%d, %d)", i%10, i))
+	}
+	env.exec(t, "delete from t where b < 5", "update t set a = 100 where b > 44")
+
+	// every index of the field follows inserts, deletes and updates
+	expected := make(map[int][]record.RID)
+	s, err := env.table(t, "t").Open()
+	require.NoError(t, err)
+	ts := s.(*record.TableScan)
+	for ts.Next() {
+		val, err := ts.GetInt("a")
+		require.NoError(t, err)
+		expected[val] = append(expected[val], ts.GetRid())
+	}
+	require.NoError(t, ts.Close())
+	require.Len(t, expected[100], 5)
+
+	infos, err := env.mdm.GetIndexes("t", env.tx)
+	require.NoError(t, err)
+	require.Len(t, infos, len(index.Types))
+	for _, ii := range infos {
+		idx, err := ii.Open()
+		require.NoError(t, err)
+		for _, val := range []int{0, 4, 5, 100} {
+			key := record.NewIntConstant(val)
+			require.NoError(t, idx.BeforeFirst(&key))
+			found := make([]record.RID, 0)
+			for idx.Next() {
+				rid, err := idx.GetDataRID()
+				require.NoError(t, err)
+				found = append(found, *rid)
+			}
+			require.ElementsMatch(t, expected[val], found, "%s %d", ii.IndexName(), val)
+		}
+		require.NoError(t, idx.Close())
+	}
+}
@@ -0,0 +1,49 @@
+package plan
+
+import (
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ query.Plan = (*LimitPlan)(nil)
+
+// NewLimitPlan creates a plan that skips the first offset records of p and keeps at most limit of the next ones,
+// a negative limit keeps all of them.
+func NewLimitPlan(p query.Plan, limit, offset int) *LimitPlan {
+	return &LimitPlan{p: p, limit: limit, offset: offset}
+}
+
+type LimitPlan struct {
+	p      query.Plan
+	limit  int
+	offset int
+}
+
+func (lp *LimitPlan) Open() (record.Scan, error) {
+	s, err := lp.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return query.NewLimitScan(s, lp.limit, lp.offset)
+}
+
+// BlocksAccessed is the cost of reading p entirely, reading fewer records does not always read fewer blocks.
+func (lp *LimitPlan) BlocksAccessed() int {
+	return lp.p.BlocksAccessed()
+}
+
+func (lp *LimitPlan) RecordsOutput() int {
+	records := max(lp.p.RecordsOutput()-lp.offset, 0)
+	if lp.limit >= 0 {
+		records = min(records, lp.limit)
+	}
+	return records
+}
+
+func (lp *LimitPlan) DistinctValues(fldname string) int {
+	return min(lp.p.DistinctValues(fldname), max(lp.RecordsOutput(), 1))
+}
+
+func (lp *LimitPlan) Schema() *record.Schema {
+	return lp.p.Schema()
+}
@@ -0,0 +1,41 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitPlan(t *testing.T) {
+	env := setupTest(t, 8)
+
+	env.exec(t, "create table enroll (eid int, grade int)")
+	for i := range 50 {
+		env.exec(t, fmt.Sprintf("insert into enroll (eid, grade) values (%d, %d)", i, i%5))
+	}
+
+	all := readRows(t, env.table(t, "enroll"), "eid")
+	tests := []struct {
+		limit, offset int
+		want          []string
+	}{
+		{limit: 10, offset: 0, want: all[:10]},
+		{limit: 10, offset: 45, want: all[45:]},
+		{limit: 10, offset: 60, want: []string{}},
+		{limit: -1, offset: 20, want: all[20:]},
+	}
+	for _, tt := range tests {
+		src := env.table(t, "enroll")
+		lp := NewLimitPlan(src, tt.limit, tt.offset)
+		require.Equal(t, tt.want, readRows(t, lp, "eid"), "limit %d offset %d", tt.limit, tt.offset)
+
+		// the estimates come from those of the source
+		records := max(src.RecordsOutput()-tt.offset, 0)
+		if tt.limit >= 0 {
+			records = min(records, tt.limit)
+		}
+		require.Equal(t, records, lp.RecordsOutput())
+		require.LessOrEqual(t, lp.DistinctValues("grade"), max(records, 1))
+	}
+}
@@ -1,6 +1,7 @@
 package plan
 
 import (
+	"errors"
 	"math"
 
 	"github.com/kanthorlabs/kanthorkv/query"
@@ -23,18 +24,33 @@ type MaterializePlan struct {
 }
 
 func (p *MaterializePlan) Open() (record.Scan, error) {
+	temp, err := p.materialize()
+	if err != nil {
+		return nil, err
+	}
+	return temp.Open()
+}
+
+// materialize copies every record of the source plan into a new temporary table.
+func (p *MaterializePlan) materialize() (temp *query.TempTable, err error) {
 	sch := p.srcplan.Schema()
-	temp := query.NewTempTable(p.tx, sch)
+	temp = query.NewTempTable(p.tx, sch)
 	src, err := p.srcplan.Open()
 	if err != nil {
 		return nil, err
 	}
-	defer src.Close()
+	// a source that stopped on an error returns it when closed
+	defer func() {
+		if err = errors.Join(err, src.Close()); err != nil {
+			temp = nil
+		}
+	}()
 
 	dest, err := temp.Open()
 	if err != nil {
 		return nil, err
 	}
+	defer dest.Close()
 
 	for src.Next() {
 		if err = dest.Insert(); err != nil {
@@ -53,11 +69,7 @@ func (p *MaterializePlan) Open() (record.Scan, error) {
 		}
 	}
 
-	if err := dest.BeforeFirst(); err != nil {
-		return nil, err
-	}
-
-	return dest, nil
+	return temp, nil
 }
 
 func (p *MaterializePlan) BlocksAccessed() int {
@@ -0,0 +1,48 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/stretchr/testify/require"
+)
+
+// failingClosePlan opens the scans of its plan, closing them returns err
+type failingClosePlan struct {
+	*TablePlan
+	err error
+}
+
+func (p *failingClosePlan) Open() (record.Scan, error) {
+	s, err := p.TablePlan.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &failingCloseScan{Scan: s, err: p.err}, nil
+}
+
+type failingCloseScan struct {
+	record.Scan
+	err error
+}
+
+func (s *failingCloseScan) Close() error {
+	return errors.Join(s.err, s.Scan.Close())
+}
+
+func TestMaterializePlan(t *testing.T) {
+	env := setupTest(t, 8)
+
+	env.exec(t, "create table enroll (eid int, grade int)")
+	for i := range 20 {
+		env.exec(t, fmt.Sprintf("insert into enroll (eid, grade) values (%d, %d)", i, i%5))
+	}
+	require.Equal(t, readRows(t, env.table(t, "enroll"), "eid", "grade"), readRows(t, NewMaterializePlan(env.tx, env.table(t, "enroll")), "eid", "grade"))
+
+	// a source that stopped on an error reports it when closed, the copy is not used
+	src := &failingClosePlan{TablePlan: env.table(t, "enroll"), err: errors.New("source failure")}
+	_, err := NewMaterializePlan(env.tx, src).Open()
+	require.ErrorContains(t, err, "source failure")
+}
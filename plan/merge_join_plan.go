@@ -0,0 +1,99 @@
+package plan
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+var _ query.Plan = (*MergeJoinPlan)(nil)
+
+// NewMergeJoinPlan creates a plan that sorts p1 on fldname1 and p2 on fldname2,
+// then merges them into the records whose join fields are equal.
+// An input already sorted on its join field is not sorted again.
+func NewMergeJoinPlan(tx transaction.Transaction, p1, p2 query.Plan, fldname1, fldname2 string) (*MergeJoinPlan, error) {
+	sp1, sorted1, err := sortedOn(tx, p1, fldname1)
+	if err != nil {
+		return nil, err
+	}
+	sp2, sorted2, err := sortedOn(tx, p2, fldname2)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := record.NewSchema()
+	schema.AddAll(p1.Schema())
+	schema.AddAll(p2.Schema())
+	return &MergeJoinPlan{
+		tx:       tx,
+		p1:       sp1,
+		p2:       sp2,
+		sorted1:  sorted1,
+		sorted2:  sorted2,
+		fldname1: fldname1,
+		fldname2: fldname2,
+		schema:   schema,
+	}, nil
+}
+
+type MergeJoinPlan struct {
+	tx                 transaction.Transaction
+	p1, p2             *SortPlan
+	sorted1, sorted2   bool
+	fldname1, fldname2 string
+	schema             *record.Schema
+}
+
+func (mjp *MergeJoinPlan) Open() (record.Scan, error) {
+	s1, err := mjp.p1.Open()
+	if err != nil {
+		return nil, err
+	}
+	s2, err := mjp.p2.Open()
+	if err != nil {
+		return nil, errors.Join(err, s1.Close())
+	}
+	return query.NewMergeJoinScan(s1, s2.(*query.SortScan), mjp.fldname1, mjp.fldname2)
+}
+
+// BlocksAccessed is the cost of reading both sorted inputs,
+// plus the cost of sorting those that were not sorted yet.
+func (mjp *MergeJoinPlan) BlocksAccessed() int {
+	return sortCost(mjp.p1, mjp.sorted1) + sortCost(mjp.p2, mjp.sorted2)
+}
+
+func (mjp *MergeJoinPlan) RecordsOutput() int {
+	rf := max(mjp.p1.DistinctValues(mjp.fldname1), mjp.p2.DistinctValues(mjp.fldname2), 1)
+	return mjp.p1.RecordsOutput() * mjp.p2.RecordsOutput() / rf
+}
+
+func (mjp *MergeJoinPlan) DistinctValues(fldname string) int {
+	if mjp.p1.Schema().HasField(fldname) {
+		return mjp.p1.DistinctValues(fldname)
+	}
+	return mjp.p2.DistinctValues(fldname)
+}
+
+func (mjp *MergeJoinPlan) Schema() *record.Schema {
+	return mjp.schema
+}
+
+// sortedOn returns p sorted on fldname in ascending order, and whether p already was.
+func sortedOn(tx transaction.Transaction, p query.Plan, fldname string) (*SortPlan, bool, error) {
+	if sp, ok := p.(*SortPlan); ok && len(sp.comp.Fields) > 0 && sp.comp.Fields[0] == fldname && !sp.comp.Desc[0] {
+		return sp, true, nil
+	}
+	sp, err := NewSortPlan(tx, p, []string{fldname})
+	return sp, false, err
+}
+
+// sortCost is the cost of reading a sorted plan,
+// sorting also reads its input once and writes its runs, which the merge reads back.
+func sortCost(sp *SortPlan, sorted bool) int {
+	if sorted {
+		return sp.BlocksAccessed()
+	}
+	return sp.plan.BlocksAccessed() + 2*sp.BlocksAccessed()
+}
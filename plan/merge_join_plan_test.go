@@ -0,0 +1,49 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeJoinPlan(t *testing.T) {
+	env := setupTest(t, 8)
+
+	env.exec(t, "create table student (sid int, sname varchar(10), majorid int)", "create table dept (did int, dname varchar(10))")
+	// majors and departments both repeat, in no particular order
+	majors := make([]int, 0)
+	for i := range 100 {
+		major := fk.IntBetween(0, 12)
+		majors = append(majors, major)
+		env.exec(t, fmt.Sprintf("insert into student (sid, sname, majorid) values (%d, 's%d', %d)", i, i, major))
+	}
+	dids := make([]int, 0)
+	for i := range 20 {
+		did := fk.IntBetween(0, 10)
+		dids = append(dids, did)
+		env.exec(t, fmt.Sprintf("insert into dept (did, dname) values (%d, 'd%d')", did, i))
+	}
+
+	expected := make([]string, 0)
+	for sid, major := range majors {
+		for i, did := range dids {
+			if major == did {
+				expected = append(expected, fmt.Sprintf("'s%d' 'd%d'", sid, i))
+			}
+		}
+	}
+
+	student, dept := env.table(t, "student"), env.table(t, "dept")
+	sorted, err := NewSortPlan(env.tx, dept, []string{"did"})
+	require.NoError(t, err)
+	for _, p2 := range []query.Plan{dept, sorted} {
+		mjp, err := NewMergeJoinPlan(env.tx, student, p2, "majorid", "did")
+		require.NoError(t, err)
+		require.ElementsMatch(t, expected, readRows(t, mjp, "sname", "dname"))
+	}
+
+	_, err = NewMergeJoinPlan(env.tx, student, dept, "majorid", "nosuchfield")
+	require.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package plan
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+var _ query.Plan = (*MultibufferProductPlan)(nil)
+
+// NewMultibufferProductPlan creates the product of lhs and rhs,
+// materializing rhs so it can be read in chunks of pinned blocks.
+func NewMultibufferProductPlan(tx transaction.Transaction, lhs, rhs query.Plan) *MultibufferProductPlan {
+	schema := record.NewSchema()
+	schema.AddAll(lhs.Schema())
+	schema.AddAll(rhs.Schema())
+	return &MultibufferProductPlan{tx: tx, lhs: lhs, rhs: NewMaterializePlan(tx, rhs), schema: schema}
+}
+
+type MultibufferProductPlan struct {
+	tx     transaction.Transaction
+	lhs    query.Plan
+	rhs    *MaterializePlan
+	schema *record.Schema
+}
+
+func (mpp *MultibufferProductPlan) Open() (record.Scan, error) {
+	tt, err := mpp.rhs.materialize()
+	if err != nil {
+		return nil, err
+	}
+	lhs, err := mpp.lhs.Open()
+	if err != nil {
+		return nil, err
+	}
+	s, err := query.NewMultibufferProductScan(mpp.tx, lhs, tt.TableName, tt.Layout())
+	if err != nil {
+		return nil, errors.Join(err, lhs.Close())
+	}
+	return s, nil
+}
+
+// BlocksAccessed is the cost of materializing rhs, plus reading lhs once per chunk of rhs.
+func (mpp *MultibufferProductPlan) BlocksAccessed() int {
+	size := mpp.rhs.BlocksAccessed()
+	// a chunk leaves two buffers to the rest of the query, see query.BestFactor
+	avail := max(mpp.tx.AvailableBuffs()-2, 1)
+	numchunks := max((size+avail-1)/avail, 1)
+	return mpp.rhs.srcplan.BlocksAccessed() + size + mpp.lhs.BlocksAccessed()*numchunks
+}
+
+func (mpp *MultibufferProductPlan) RecordsOutput() int {
+	return mpp.lhs.RecordsOutput() * mpp.rhs.RecordsOutput()
+}
+
+func (mpp *MultibufferProductPlan) DistinctValues(fldname string) int {
+	if mpp.lhs.Schema().HasField(fldname) {
+		return mpp.lhs.DistinctValues(fldname)
+	}
+	return mpp.rhs.DistinctValues(fldname)
+}
+
+func (mpp *MultibufferProductPlan) Schema() *record.Schema {
+	return mpp.schema
+}
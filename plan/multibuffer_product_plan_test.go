@@ -0,0 +1,27 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultibufferProductPlan(t *testing.T) {
+	// the right side takes several chunks of the few free buffers
+	env := setupTest(t, 8)
+
+	env.exec(t, "create table student (sid int, sname varchar(10))", "create table course (cid int, title varchar(10))")
+	for i := range 15 {
+		env.exec(t, fmt.Sprintf("insert into student (sid, sname) values (%d, 's%d')", i, i))
+	}
+	for i := range 100 {
+		env.exec(t, fmt.Sprintf("insert into course (cid, title) values (%d, 'c%d')", i, i))
+	}
+
+	student, course := env.table(t, "student"), env.table(t, "course")
+	expected := readRows(t, NewProductPlan(student, course), "sname", "title")
+	require.Len(t, expected, 1500)
+	require.ElementsMatch(t, expected, readRows(t, NewMultibufferProductPlan(env.tx, student, course), "sname", "title"))
+	require.ElementsMatch(t, expected, readRows(t, NewMultibufferProductPlan(env.tx, course, student), "sname", "title"))
+}
@@ -0,0 +1,64 @@
+package plan
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ query.Plan = (*OuterJoinPlan)(nil)
+
+// NewOuterJoinPlan creates the left outer join of p1 with p2 on pred,
+// the records of p1 that join with nothing come with the fields of p2 NULL.
+// A right outer join is the left outer join of its operands swapped.
+func NewOuterJoinPlan(p1, p2 query.Plan, pred *query.Predicate) *OuterJoinPlan {
+	schema := record.NewSchema()
+	schema.AddAll(p1.Schema())
+	schema.AddAll(p2.Schema())
+	return &OuterJoinPlan{p1: p1, p2: p2, pred: pred, schema: schema}
+}
+
+type OuterJoinPlan struct {
+	p1, p2 query.Plan
+	pred   *query.Predicate
+	schema *record.Schema
+}
+
+func (ojp *OuterJoinPlan) Open() (record.Scan, error) {
+	s1, err := ojp.p1.Open()
+	if err != nil {
+		return nil, err
+	}
+	s2, err := ojp.p2.Open()
+	if err != nil {
+		return nil, errors.Join(err, s1.Close())
+	}
+	return query.NewOuterJoinScan(s1, s2, ojp.pred)
+}
+
+// BlocksAccessed is the one of the product, p2 is read once for each record of p1.
+func (ojp *OuterJoinPlan) BlocksAccessed() int {
+	return ojp.p1.BlocksAccessed() + (ojp.p1.RecordsOutput() * ojp.p2.BlocksAccessed())
+}
+
+// RecordsOutput is the size of the inner join, but never less than p1 which every record of is kept.
+func (ojp *OuterJoinPlan) RecordsOutput() int {
+	product := NewProductPlan(ojp.p1, ojp.p2)
+	factor, err := ojp.pred.ReductionFactor(product)
+	if err != nil {
+		factor = 1
+	}
+	return max(ojp.p1.RecordsOutput(), product.RecordsOutput()/factor)
+}
+
+func (ojp *OuterJoinPlan) DistinctValues(fldname string) int {
+	if ojp.p1.Schema().HasField(fldname) {
+		return ojp.p1.DistinctValues(fldname)
+	}
+	return ojp.p2.DistinctValues(fldname)
+}
+
+func (ojp *OuterJoinPlan) Schema() *record.Schema {
+	return ojp.schema
+}
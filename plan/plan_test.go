@@ -0,0 +1,118 @@
+package plan
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaswdr/faker/v2"
+	"github.com/kanthorlabs/kanthorkv/buffer"
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/log"
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/tx"
+	"github.com/kanthorlabs/kanthorkv/tx/concurrency"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBlockSize = 400
+	testMaxTime   = time.Second * 10
+	testLogFile   = "testlog"
+)
+
+var (
+	fk     faker.Faker
+	fkOnce sync.Once
+)
+
+func init() {
+	fkOnce.Do(func() {
+		fk = faker.New()
+	})
+}
+
+func testdir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "kanthorkv-test-")
+	require.NoError(t, err)
+	return dir
+}
+
+// testEnv is a fresh database with small blocks, and the transaction a test runs in
+type testEnv struct {
+	tx      transaction.Transaction
+	mdm     *metadata.MetadataMgr
+	planner *Planner
+}
+
+// setupTest creates a database with numbuffs buffers,
+// its transaction is committed and its directory removed when the test ends
+func setupTest(t *testing.T, numbuffs int) *testEnv {
+	dir := testdir(t)
+
+	fm, err := file.NewFileManager(dir, testBlockSize)
+	require.NoError(t, err)
+	lm, err := log.NewLogManager(fm, testLogFile)
+	require.NoError(t, err)
+	bm, err := buffer.NewBufferManager(fm, lm, numbuffs, testMaxTime)
+	require.NoError(t, err)
+	tx, err := tx.NewTransaction(fm, lm, bm, concurrency.NewLockTable())
+	require.NoError(t, err)
+	mdm, err := metadata.NewMetadataMgr(true, tx)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, tx.Commit())
+		fm.Close()
+		os.RemoveAll(dir)
+	})
+	return &testEnv{tx: tx, mdm: mdm, planner: NewPlanner(NewBasicQueryPlanner(mdm), NewIndexUpdatePlanner(mdm))}
+}
+
+// exec runs the update statements sqls
+func (env *testEnv) exec(t *testing.T, sqls ...string) {
+	for _, sql := range sqls {
+		_, err := env.planner.ExecuteUpdate(sql, env.tx)
+		require.NoError(t, err, sql)
+	}
+}
+
+// table returns the plan of the table tblname
+func (env *testEnv) table(t *testing.T, tblname string) *TablePlan {
+	tp, err := NewTablePlan(tblname, env.tx, env.mdm)
+	require.NoError(t, err)
+	return tp
+}
+
+// readRows opens p and reads every record twice, the second time after BeforeFirst,
+// a row holds the values of fields separated by spaces
+func readRows(t *testing.T, p query.Plan, fields ...string) []string {
+	s, err := p.Open()
+	require.NoError(t, err)
+	defer s.Close()
+
+	var first []string
+	for pass := range 2 {
+		rows := make([]string, 0)
+		for s.Next() {
+			row := make([]string, 0, len(fields))
+			for _, fldname := range fields {
+				val, err := s.GetVal(fldname)
+				require.NoError(t, err)
+				row = append(row, val.String())
+			}
+			rows = append(rows, strings.Join(row, " "))
+		}
+		if pass == 0 {
+			first = rows
+			require.NoError(t, s.BeforeFirst())
+			continue
+		}
+		require.Equal(t, first, rows, "a second pass after BeforeFirst returns the same records")
+	}
+	return first
+}
@@ -19,9 +19,32 @@ func NewProjectPlan(p query.Plan, fieldnames []string) *ProjectPlan {
 	}
 }
 
+// NewComputedProjectPlan creates a plan whose field fieldnames[i] is the value of exprs[i] on the records of p.
+// The type of each field is derived from its expression, which fails when the expression does not fit p.
+func NewComputedProjectPlan(p query.Plan, fieldnames []string, exprs []*query.Expression) (*ProjectPlan, error) {
+	schema := record.NewSchema()
+	for i, expr := range exprs {
+		t, length, err := expr.Type(p.Schema())
+		if err != nil {
+			return nil, err
+		}
+		schema.AddField(fieldnames[i], t, length)
+	}
+
+	return &ProjectPlan{
+		p:      p,
+		schema: schema,
+		fields: fieldnames,
+		exprs:  exprs,
+	}, nil
+}
+
 type ProjectPlan struct {
 	p      query.Plan
 	schema *record.Schema
+	// fields and exprs are the computed fields, in the order of the select list
+	fields []string
+	exprs  []*query.Expression
 }
 
 func (pp *ProjectPlan) Open() (record.Scan, error) {
@@ -29,7 +52,10 @@ func (pp *ProjectPlan) Open() (record.Scan, error) {
 	if err != nil {
 		return nil, err
 	}
-	return query.NewProjectScan(s, pp.schema.Fields())
+	if pp.exprs == nil {
+		return query.NewProjectScan(s, pp.schema.Fields())
+	}
+	return query.NewComputedProjectScan(s, pp.fields, pp.exprs)
 }
 
 func (pp *ProjectPlan) BlocksAccessed() int {
@@ -40,7 +66,20 @@ func (pp *ProjectPlan) RecordsOutput() int {
 	return pp.p.RecordsOutput()
 }
 
+// DistinctValues of a computed field is at most the number of records.
 func (pp *ProjectPlan) DistinctValues(fldname string) int {
+	for i, expr := range pp.exprs {
+		if pp.fields[i] != fldname {
+			continue
+		}
+		if expr.FieldName() != nil {
+			return pp.p.DistinctValues(*expr.FieldName())
+		}
+		if expr.Constant() != nil {
+			return 1
+		}
+		return pp.p.RecordsOutput()
+	}
 	return pp.p.DistinctValues(fldname)
 }
 
@@ -0,0 +1,278 @@
+package plan
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// scope resolves the field names of a statement against the tables and views it reads.
+// A field that a single table has keeps its name. A field that several tables have is read from each one
+// under the name of the table and the field, like e1.id, so no two tables of a query share a field name.
+type scope struct {
+	// plans read the tables under the field names of the scope, by the name of their reference
+	plans map[string]query.Plan
+	// names maps every field of a table to the name it is read under, by the name of the reference of the table
+	names map[string]map[string]string
+	// owners are the names of the references of the tables that have a field, in the order of the FROM clause
+	owners map[string][]string
+}
+
+// newScope names the fields of the tables refs, which plans read in the same order.
+func newScope(refs []parser.TableRef, plans []query.Plan) (*scope, error) {
+	sc := &scope{
+		plans:  make(map[string]query.Plan, len(refs)),
+		names:  make(map[string]map[string]string, len(refs)),
+		owners: make(map[string][]string),
+	}
+	for i, ref := range refs {
+		if _, ok := sc.plans[ref.Name()]; ok {
+			return nil, fmt.Errorf("table %s is listed twice, one of them needs an alias", ref.Name())
+		}
+		sc.plans[ref.Name()] = plans[i]
+		for _, fldname := range plans[i].Schema().Fields() {
+			sc.owners[fldname] = append(sc.owners[fldname], ref.Name())
+		}
+	}
+
+	for i, ref := range refs {
+		names := make(map[string]string)
+		renames := make(map[string]string)
+		for _, fldname := range plans[i].Schema().Fields() {
+			names[fldname] = fldname
+			if len(sc.owners[fldname]) > 1 {
+				names[fldname] = ref.Name() + "." + fldname
+				renames[fldname] = names[fldname]
+			}
+		}
+		sc.names[ref.Name()] = names
+		if len(renames) == 0 {
+			continue
+		}
+		p, err := rename(plans[i], renames)
+		if err != nil {
+			return nil, err
+		}
+		sc.plans[ref.Name()] = p
+	}
+	return sc, nil
+}
+
+// newQueryScope plans every table and view of the FROM clause of data, views recursively by qp,
+// and returns data with its field names resolved against them.
+func newQueryScope(data *parser.QueryData, tx transaction.Transaction, mdm *metadata.MetadataMgr, qp QueryPlanner) (*scope, *parser.QueryData, error) {
+	refs := data.AllTables()
+	plans := make([]query.Plan, 0, len(refs))
+	for _, ref := range refs {
+		p, err := planTable(ref.Table, tx, mdm, qp)
+		if err != nil {
+			return nil, nil, err
+		}
+		plans = append(plans, p)
+	}
+	sc, err := newScope(refs, plans)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolved, err := sc.resolveQuery(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sc, resolved, nil
+}
+
+// planTable plans the table tblname, or the view tblname recursively by qp.
+func planTable(tblname string, tx transaction.Transaction, mdm *metadata.MetadataMgr, qp QueryPlanner) (query.Plan, error) {
+	viewdef, err := mdm.GetViewDef(tblname, tx)
+	if err != nil {
+		return nil, err
+	}
+	if viewdef == "" {
+		tp, err := NewTablePlan(tblname, tx, mdm)
+		if err != nil {
+			return nil, err
+		}
+		return tp, nil
+	}
+
+	lexer := parser.NewLexer(viewdef)
+	p := parser.New(lexer)
+	viewdata, err := p.Query()
+	if err != nil {
+		return nil, err
+	}
+	return qp.CreatePlan(viewdata, tx)
+}
+
+// rename reads the fields of p in renames under their new names.
+// A table is read through a renamed layout, which keeps its indexes usable, a view through a projection.
+func rename(p query.Plan, renames map[string]string) (query.Plan, error) {
+	if tp, ok := p.(*TablePlan); ok {
+		return tp.Rename(renames), nil
+	}
+	fields := make([]string, 0, len(p.Schema().Fields()))
+	exprs := make([]*query.Expression, 0, len(p.Schema().Fields()))
+	for _, fldname := range p.Schema().Fields() {
+		newname, ok := renames[fldname]
+		if !ok {
+			newname = fldname
+		}
+		fields = append(fields, newname)
+		exprs = append(exprs, query.NewFieldExpression(&fldname))
+	}
+	return NewComputedProjectPlan(p, fields, exprs)
+}
+
+// tablePlanner creates the planner of the table ref with pred, a view comes without indexes.
+func (sc *scope) tablePlanner(ref parser.TableRef, pred *query.Predicate, tx transaction.Transaction, mdm *metadata.MetadataMgr) (*TablePlanner, error) {
+	p := sc.plans[ref.Name()]
+	tp, ok := p.(*TablePlan)
+	if !ok {
+		return NewTablePlanner(p, nil, pred, tx), nil
+	}
+	indexes, err := tp.Indexes(mdm)
+	if err != nil {
+		return nil, err
+	}
+	return NewTablePlanner(tp, indexes, pred, tx), nil
+}
+
+// resolve returns the name of the field fldname in the scope, fldname is either the name of a field
+// that a single table has, or qualified by the name of its table like e.name.
+// Other names are not fields of a table, like the name of a column of the select list, and are returned as is.
+func (sc *scope) resolve(fldname string) (string, error) {
+	if owners := sc.owners[fldname]; len(owners) > 1 {
+		return "", fmt.Errorf("field %s is ambiguous, tables %s all have it", fldname, strings.Join(owners, ", "))
+	} else if len(owners) == 1 {
+		return sc.names[owners[0]][fldname], nil
+	}
+
+	tblname, field, ok := strings.Cut(fldname, ".")
+	if !ok {
+		return fldname, nil
+	}
+	names, ok := sc.names[tblname]
+	if !ok {
+		return "", fmt.Errorf("table %s of field %s is not in FROM", tblname, fldname)
+	}
+	name, ok := names[field]
+	if !ok {
+		return "", fmt.Errorf("field %s not found", fldname)
+	}
+	return name, nil
+}
+
+// resolveQuery returns a copy of data whose clauses read the fields under their names in the scope.
+// An ORDER BY field that cannot be resolved may still name a column of the select list.
+func (sc *scope) resolveQuery(data *parser.QueryData) (*parser.QueryData, error) {
+	// an aggregation function is named after the field it reads, the other clauses refer to it by that name
+	aggNames := make(map[string]string, len(data.AggFns))
+	aggFns := make([]query.AggregationFn, 0, len(data.AggFns))
+	for _, fn := range data.AggFns {
+		name, fldname, _ := strings.Cut(strings.TrimSuffix(fn.FieldName(), ")"), "(")
+		fldname, err := sc.resolve(fldname)
+		if err != nil {
+			return nil, err
+		}
+		resolved, err := query.NewAggregationFn(name, fldname)
+		if err != nil {
+			return nil, err
+		}
+		aggNames[fn.FieldName()] = resolved.FieldName()
+		aggFns = append(aggFns, resolved)
+	}
+	resolve := func(fldname string) (string, error) {
+		if name, ok := aggNames[fldname]; ok {
+			return name, nil
+		}
+		return sc.resolve(fldname)
+	}
+
+	exprs := make([]*query.Expression, 0, len(data.Exprs))
+	for _, expr := range data.Exprs {
+		resolved, err := expr.Resolve(resolve)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, resolved)
+	}
+	pred, err := data.Pred.Resolve(resolve)
+	if err != nil {
+		return nil, err
+	}
+	joins := make([]*parser.JoinData, 0, len(data.Joins))
+	for _, join := range data.Joins {
+		resolved := parser.NewJoinData(join.Table)
+		for _, clause := range join.Clauses {
+			on, err := clause.On.Resolve(resolve)
+			if err != nil {
+				return nil, err
+			}
+			resolved.Clauses = append(resolved.Clauses, &parser.JoinClause{Kind: clause.Kind, Table: clause.Table, On: on})
+		}
+		joins = append(joins, resolved)
+	}
+	groupBy := make([]string, 0, len(data.GroupBy))
+	for _, fldname := range data.GroupBy {
+		fldname, err := resolve(fldname)
+		if err != nil {
+			return nil, err
+		}
+		groupBy = append(groupBy, fldname)
+	}
+	having, err := data.Having.Resolve(resolve)
+	if err != nil {
+		return nil, err
+	}
+	orderBy := make([]query.SortField, 0, len(data.OrderBy))
+	for _, sf := range data.OrderBy {
+		fldname, err := resolve(sf.Name)
+		if err != nil {
+			if !slices.Contains(data.Fields, sf.Name) {
+				return nil, err
+			}
+			fldname = sf.Name
+		}
+		orderBy = append(orderBy, query.SortField{Name: fldname, Desc: sf.Desc})
+	}
+
+	resolved := parser.NewQueryData(data.Fields, exprs, data.Tables, pred, orderBy)
+	resolved.Joins, resolved.GroupBy, resolved.Having, resolved.AggFns = joins, groupBy, having, aggFns
+	resolved.Limit, resolved.Offset = data.Limit, data.Offset
+	return resolved, nil
+}
+
+// tableScope is the scope of a statement that reads the table tblname alone, through p.
+func tableScope(tblname string, p query.Plan) *scope {
+	// a single table cannot be listed twice
+	sc, _ := newScope([]parser.TableRef{{Table: tblname}}, []query.Plan{p})
+	return sc
+}
+
+// resolveDelete returns a copy of data whose predicate reads the fields of the table under their names in p.
+func resolveDelete(data *parser.DeleteData, p query.Plan) (*parser.DeleteData, error) {
+	pred, err := data.Pred.Resolve(tableScope(data.TableName, p).resolve)
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewDeleteData(data.TableName, pred), nil
+}
+
+// resolveUpdate returns a copy of data whose new value and predicate read the fields of the table under their names in p.
+func resolveUpdate(data *parser.UpdateData, p query.Plan) (*parser.UpdateData, error) {
+	sc := tableScope(data.TableName, p)
+	newval, err := data.NewValue.Resolve(sc.resolve)
+	if err != nil {
+		return nil, err
+	}
+	pred, err := data.Pred.Resolve(sc.resolve)
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewUpdateData(data.TableName, data.TargetField, newval, pred), nil
+}
@@ -1,6 +1,8 @@
 package plan
 
 import (
+	"container/heap"
+	"errors"
 	"fmt"
 
 	"github.com/kanthorlabs/kanthorkv/query"
@@ -8,13 +10,27 @@ import (
 	"github.com/kanthorlabs/kanthorkv/tx/transaction"
 )
 
+// NewSortPlan creates a plan that sorts the records of plan by sortFields, in ascending order.
 func NewSortPlan(tx transaction.Transaction, plan query.Plan, sortFields []string) (*SortPlan, error) {
-	return &SortPlan{
+	return newSortPlan(tx, plan, query.NewRecordComparator(sortFields))
+}
+
+// NewOrderedSortPlan creates a plan that sorts the records of plan by sortFields, each in its own direction.
+func NewOrderedSortPlan(tx transaction.Transaction, plan query.Plan, sortFields []query.SortField) (*SortPlan, error) {
+	return newSortPlan(tx, plan, query.NewSortFieldComparator(sortFields))
+}
 
+func newSortPlan(tx transaction.Transaction, plan query.Plan, comp *query.RecordComparator) (*SortPlan, error) {
+	for _, fldname := range comp.Fields {
+		if !plan.Schema().HasField(fldname) {
+			return nil, fmt.Errorf("sort field %s is not in the schema", fldname)
+		}
+	}
+	return &SortPlan{
 		plan:   plan,
 		tx:     tx,
 		schema: plan.Schema(),
-		comp:   query.NewRecordComparator(sortFields),
+		comp:   comp,
 	}, nil
 }
 
@@ -27,6 +43,8 @@ type SortPlan struct {
 	comp   *query.RecordComparator
 }
 
+// Open splits the source into sorted runs, then merges as many runs at once as the free buffers allow
+// until the SortScan can merge the rest while it is read.
 func (sp *SortPlan) Open() (record.Scan, error) {
 	src, err := sp.plan.Open()
 	if err != nil {
@@ -34,14 +52,20 @@ func (sp *SortPlan) Open() (record.Scan, error) {
 	}
 	runs, err := sp.splitIntoRuns(src)
 	if err != nil {
-		return nil, err
+		return nil, errors.Join(err, src.Close())
 	}
 	if err := src.Close(); err != nil {
 		return nil, err
 	}
 
-	for len(runs) > 2 {
-		runs, err = sp.doAMergeIteration(runs)
+	// an empty source still needs a run to scan
+	if len(runs) == 0 {
+		runs = append(runs, query.NewTempTable(sp.tx, sp.schema))
+	}
+	// a merge pins one buffer per run, there is always room for two
+	k := max(2, query.BestRoot(sp.tx.AvailableBuffs(), len(runs)))
+	for len(runs) > k {
+		runs, err = sp.doAMergeIteration(runs, k)
 		if err != nil {
 			return nil, err
 		}
@@ -71,55 +95,112 @@ func (sp *SortPlan) Schema() *record.Schema {
 	return sp.schema
 }
 
-// splitIntoRuns splits the source scan into sorted runs with original order preserved.
-// Lets say we have [11, 5, 7, 2, 9, 1, 8]
-// This would be split into the following runs:
-// [11], [5, 7], [2, 9], [1, 8]
+// splitIntoRuns splits the source scan into sorted runs by replacement selection.
+// The records held in memory fill the free buffers. The smallest one that can still extend the current run
+// is written to it and replaced by the next source record, so runs are about twice as long as memory,
+// and a source that is already sorted becomes a single run.
 func (sp *SortPlan) splitIntoRuns(src record.Scan) ([]*query.TempTable, error) {
 	temps := make([]*query.TempTable, 0)
-	err := src.BeforeFirst()
-	if err != nil {
+	if err := src.BeforeFirst(); err != nil {
 		return nil, fmt.Errorf("src.BeforeFirst: %w", err)
 	}
 
-	currentTemp := query.NewTempTable(sp.tx, sp.schema)
-	temps = append(temps, currentTemp)
-	currentScan, err := currentTemp.Open()
-	if err != nil {
-		return nil, err
-	}
-
-	for src.Next() {
-		next, err := sp.copy(src, currentScan)
+	h := &selectionHeap{comp: sp.comp}
+	hasMore := src.Next()
+	for ; hasMore && h.Len() < sp.memoryRows(); hasMore = src.Next() {
+		vals, err := sp.readVals(src)
 		if err != nil {
 			return nil, err
 		}
-		if !next {
-			break
+		heap.Push(h, selectionRecord{vals: vals})
+	}
+
+	var dest *record.TableScan
+	run := -1
+	for h.Len() > 0 {
+		rec := heap.Pop(h).(selectionRecord)
+		if rec.run != run {
+			if dest != nil {
+				if err := dest.Close(); err != nil {
+					return nil, err
+				}
+			}
+			temp := query.NewTempTable(sp.tx, sp.schema)
+			temps = append(temps, temp)
+			var err error
+			if dest, err = temp.Open(); err != nil {
+				return nil, err
+			}
+			run = rec.run
+		}
+		if err := sp.write(rec.vals, dest); err != nil {
+			return nil, errors.Join(err, dest.Close())
 		}
 
-		cmp, err := sp.comp.Compare(src, currentScan)
+		if !hasMore {
+			continue
+		}
+		vals, err := sp.readVals(src)
 		if err != nil {
-			return nil, err
+			return nil, errors.Join(err, dest.Close())
+		}
+		// a record smaller than the one just written has to wait for the next run
+		next := selectionRecord{run: run, vals: vals}
+		cmp, err := sp.comp.CompareMap(vals, rec.vals)
+		if err != nil {
+			return nil, errors.Join(err, dest.Close())
 		}
-		// src < currentScan
-		// we've reached the end of a sorted run.
 		if cmp < 0 {
-			if err := currentScan.Close(); err != nil {
-				return nil, err
-			}
-			currentTemp = query.NewTempTable(sp.tx, sp.schema)
-			temps = append(temps, currentTemp)
-			currentScan, err = currentTemp.Open()
-			if err != nil {
-				return nil, err
-			}
+			next.run = run + 1
+		}
+		heap.Push(h, next)
+		hasMore = src.Next()
+	}
+
+	if dest == nil {
+		return temps, nil
+	}
+	return temps, dest.Close()
+}
+
+// memoryRows is the number of records that fit in the free buffers,
+// leaving two buffers to read the source and write the run.
+func (sp *SortPlan) memoryRows() int {
+	return memoryRows(sp.tx, sp.schema)
+}
+
+// memoryRows is the number of records of schema sch a plan can hold in memory,
+// as many as the free buffers would hold, but two.
+func memoryRows(tx transaction.Transaction, sch *record.Schema) int {
+	layout := record.NewLayoutOfSchema(sch)
+	return max(tx.AvailableBuffs()-2, 1) * max(tx.BlockSize()/layout.SlotSize(), 1)
+}
+
+func (sp *SortPlan) readVals(src record.Scan) (map[string]record.Constant, error) {
+	vals := make(map[string]record.Constant, len(sp.schema.Fields()))
+	for _, fieldName := range sp.schema.Fields() {
+		val, err := src.GetVal(fieldName)
+		if err != nil {
+			return nil, err
 		}
+		vals[fieldName] = val
 	}
+	return vals, nil
+}
 
-	return temps, currentScan.Close()
+func (sp *SortPlan) write(vals map[string]record.Constant, dest record.UpdateScan) error {
+	if err := dest.Insert(); err != nil {
+		return err
+	}
+	for _, fieldName := range sp.schema.Fields() {
+		if err := dest.SetVal(fieldName, vals[fieldName]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// copy writes the current record of src into dest and moves src to its next record.
 func (sp *SortPlan) copy(src record.Scan, dest record.UpdateScan) (bool, error) {
 	if err := dest.Insert(); err != nil {
 		return false, err
@@ -139,37 +220,33 @@ func (sp *SortPlan) copy(src record.Scan, dest record.UpdateScan) (bool, error)
 	return src.Next(), nil
 }
 
-func (sp *SortPlan) doAMergeIteration(runs []*query.TempTable) ([]*query.TempTable, error) {
-	result := make([]*query.TempTable, 0)
-	for len(runs) > 1 {
-		p1 := runs[0]
-		p2 := runs[1]
-		runs = runs[2:]
-		merged, err := sp.mergeTwoRuns(p1, p2)
+// doAMergeIteration merges every k consecutive runs into one.
+func (sp *SortPlan) doAMergeIteration(runs []*query.TempTable, k int) ([]*query.TempTable, error) {
+	result := make([]*query.TempTable, 0, len(runs)/k+1)
+	for len(runs) > 0 {
+		n := min(k, len(runs))
+		group := runs[:n]
+		runs = runs[n:]
+		if len(group) == 1 {
+			result = append(result, group[0])
+			continue
+		}
+
+		merged, err := sp.mergeRuns(group)
 		if err != nil {
 			return nil, err
 		}
 		result = append(result, merged)
 	}
-
-	if len(runs) == 1 {
-		result = append(result, runs[0])
-	}
 	return result, nil
 }
 
-func (sp *SortPlan) mergeTwoRuns(p1 *query.TempTable, p2 *query.TempTable) (*query.TempTable, error) {
-	src1, err := p1.Open()
+func (sp *SortPlan) mergeRuns(runs []*query.TempTable) (*query.TempTable, error) {
+	src, err := query.NewSortScan(runs, sp.comp)
 	if err != nil {
 		return nil, err
 	}
-	defer src1.Close()
-
-	src2, err := p2.Open()
-	if err != nil {
-		return nil, err
-	}
-	defer src2.Close()
+	defer src.Close()
 
 	result := query.NewTempTable(sp.tx, sp.schema)
 	dest, err := result.Open()
@@ -178,40 +255,47 @@ func (sp *SortPlan) mergeTwoRuns(p1 *query.TempTable, p2 *query.TempTable) (*que
 	}
 	defer dest.Close()
 
-	var hasMore1, hasMore2 bool
-	for src1.Next() && src2.Next() {
-		cmp, err := sp.comp.Compare(src1, src2)
-		if err != nil {
+	for hasMore := src.Next(); hasMore; {
+		if hasMore, err = sp.copy(src, dest); err != nil {
 			return nil, err
 		}
-		if cmp < 0 {
-			hasMore1, err = sp.copy(src1, dest)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			hasMore2, err = sp.copy(src2, dest)
-			if err != nil {
-				return nil, err
-			}
-		}
 	}
+	return result, nil
+}
 
-	if hasMore1 {
-		for hasMore1 {
-			hasMore1, err = sp.copy(src1, dest)
-			if err != nil {
-				return nil, err
-			}
-		}
-	} else {
-		for hasMore2 {
-			hasMore2, err = sp.copy(src2, dest)
-			if err != nil {
-				return nil, err
-			}
-		}
+// selectionRecord is a record held in memory during run generation, with the run it is written to.
+type selectionRecord struct {
+	run  int
+	vals map[string]record.Constant
+}
+
+// selectionHeap orders records by run, then by the sort fields.
+type selectionHeap struct {
+	comp    *query.RecordComparator
+	records []selectionRecord
+}
+
+func (h *selectionHeap) Len() int { return len(h.records) }
+
+func (h *selectionHeap) Less(i, j int) bool {
+	r1, r2 := h.records[i], h.records[j]
+	if r1.run != r2.run {
+		return r1.run < r2.run
+	}
+	// NewSortPlan checked that every sort field is in the schema
+	cmp, err := h.comp.CompareMap(r1.vals, r2.vals)
+	if err != nil {
+		panic(err)
 	}
+	return cmp < 0
+}
 
-	return result, nil
+func (h *selectionHeap) Swap(i, j int) { h.records[i], h.records[j] = h.records[j], h.records[i] }
+
+func (h *selectionHeap) Push(x any) { h.records = append(h.records, x.(selectionRecord)) }
+
+func (h *selectionHeap) Pop() any {
+	last := h.records[len(h.records)-1]
+	h.records = h.records[:len(h.records)-1]
+	return last
 }
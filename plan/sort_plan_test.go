@@ -0,0 +1,47 @@
+package plan
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortPlan(t *testing.T) {
+	// the records fill many times the few free buffers, so the runs are merged in several passes
+	env := setupTest(t, 6)
+
+	inputs := map[string]func(i int) int{
+		"random":   func(int) int { return fk.IntBetween(0, 50) },
+		"reversed": func(i int) int { return 1000 - i },
+		"sorted":   func(i int) int { return i },
+	}
+	for tblname, grade := range inputs {
+		env.exec(t, fmt.Sprintf("create table %s (sid int, grade int)", tblname))
+
+		expected := make([][2]int, 0)
+		for i := range 600 {
+			g := grade(i)
+			expected = append(expected, [2]int{g, i})
+			env.exec(t, fmt.Sprintf("insert into %s (sid, grade) values (%d, %d)", tblname, i, g))
+		}
+		slices.SortFunc(expected, func(a, b [2]int) int {
+			if a[0] != b[0] {
+				return a[0] - b[0]
+			}
+			return a[1] - b[1]
+		})
+		rows := make([]string, 0, len(expected))
+		for _, r := range expected {
+			rows = append(rows, fmt.Sprintf("%d %d", r[0], r[1]))
+		}
+
+		sp, err := NewSortPlan(env.tx, env.table(t, tblname), []string{"grade", "sid"})
+		require.NoError(t, err)
+		require.Equal(t, rows, readRows(t, sp, "grade", "sid"), tblname)
+	}
+
+	_, err := NewSortPlan(env.tx, env.table(t, "sorted"), []string{"nosuchfield"})
+	require.Error(t, err)
+}
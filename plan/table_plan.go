@@ -35,6 +35,40 @@ type TablePlan struct {
 	tx      transaction.Transaction
 	layout  *record.Layout
 	si      *metadata.StatInfo
+	// renames are the fields the plan reads under another name, nil when it reads them under their own
+	renames map[string]string
+}
+
+// Rename returns a plan of the same table that reads each field of renames under its new name.
+func (tp *TablePlan) Rename(renames map[string]string) *TablePlan {
+	return &TablePlan{
+		tblname: tp.tblname,
+		tx:      tp.tx,
+		layout:  tp.layout.Rename(renames),
+		si:      tp.si,
+		renames: renames,
+	}
+}
+
+// Indexes returns the indexes of the table, by the name the plan reads their field under.
+func (tp *TablePlan) Indexes(mdm *metadata.MetadataMgr) (map[string]*metadata.IndexInfo, error) {
+	indexes, err := mdm.GetIndexInfo(tp.tblname, tp.tx)
+	if err != nil || tp.renames == nil {
+		return indexes, err
+	}
+	renamed := make(map[string]*metadata.IndexInfo, len(indexes))
+	for fldname, ii := range indexes {
+		renamed[tp.fieldName(fldname)] = ii
+	}
+	return renamed, nil
+}
+
+// fieldName returns the name the plan reads the field fldname of the table under.
+func (tp *TablePlan) fieldName(fldname string) string {
+	if newname, ok := tp.renames[fldname]; ok {
+		return newname
+	}
+	return fldname
 }
 
 func (tp *TablePlan) Open() (record.Scan, error) {
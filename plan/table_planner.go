@@ -0,0 +1,220 @@
+package plan
+
+import (
+	"iter"
+	"maps"
+	"slices"
+
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// NewTablePlanner creates the planner of one table of a query.
+// Views are planned by the caller and come without indexes.
+func NewTablePlanner(p query.Plan, indexes map[string]*metadata.IndexInfo, pred *query.Predicate, tx transaction.Transaction) *TablePlanner {
+	return &TablePlanner{myplan: p, mypred: pred, indexes: indexes, tx: tx}
+}
+
+// NewTablePlannerOfTable creates the planner of a table, loading its indexes.
+func NewTablePlannerOfTable(tblname string, pred *query.Predicate, tx transaction.Transaction, mdm *metadata.MetadataMgr) (*TablePlanner, error) {
+	tp, err := NewTablePlan(tblname, tx, mdm)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := tp.Indexes(mdm)
+	if err != nil {
+		return nil, err
+	}
+	return NewTablePlanner(tp, indexes, pred, tx), nil
+}
+
+// TablePlanner builds the plans that read one table of a query,
+// on its own or joined to the plan of the tables before it.
+type TablePlanner struct {
+	myplan  query.Plan
+	mypred  *query.Predicate
+	indexes map[string]*metadata.IndexInfo
+	tx      transaction.Transaction
+}
+
+// MakeSelectPlan returns a plan that reads the table with every selection term of the predicate applied,
+// using an index when a term equates an indexed field with a constant.
+func (tp *TablePlanner) MakeSelectPlan() query.Plan {
+	p := tp.makeIndexSelect()
+	if p == nil {
+		p = tp.myplan
+	}
+	return tp.addSelectPred(p)
+}
+
+// MakeJoinPlan returns the cheapest plan that joins current with the table,
+// or nil when no term of the predicate links them.
+func (tp *TablePlanner) MakeJoinPlan(current query.Plan) query.Plan {
+	joinpred := tp.mypred.JoinSubPred(tp.myplan.Schema(), current.Schema())
+	if joinpred == nil {
+		return nil
+	}
+
+	var best query.Plan
+	for _, p := range tp.JoinPlans(current) {
+		if best == nil || cheaper(p, best) {
+			best = p
+		}
+	}
+	return best
+}
+
+// JoinPlans returns every way of joining current with the table:
+// through each usable method when a term of the predicate links them, otherwise the product.
+func (tp *TablePlanner) JoinPlans(current query.Plan) []query.Plan {
+	joinpred := tp.mypred.JoinSubPred(tp.myplan.Schema(), current.Schema())
+	if joinpred == nil {
+		return []query.Plan{tp.MakeProductPlan(current)}
+	}
+
+	// on equal costs the earlier method wins
+	plans := make([]query.Plan, 0, 4)
+	if p := tp.makeIndexJoin(current); p != nil {
+		plans = append(plans, p)
+	}
+	if p := tp.makeMergeJoin(current); p != nil {
+		plans = append(plans, p)
+	}
+	if p := tp.makeHashJoin(current); p != nil {
+		plans = append(plans, p)
+	}
+	return append(plans, tp.makeProductJoin(current))
+}
+
+// MakeProductPlan returns the product of current and the table with its selection terms applied,
+// reading the table in chunks of buffers.
+func (tp *TablePlanner) MakeProductPlan(current query.Plan) query.Plan {
+	return NewMultibufferProductPlan(tp.tx, current, tp.MakeSelectPlan())
+}
+
+// makeIndexSelect picks the index expected to return the fewest records
+// among those whose field the predicate equates with a constant.
+func (tp *TablePlanner) makeIndexSelect() query.Plan {
+	table, ok := tp.myplan.(*TablePlan)
+	if !ok {
+		return nil
+	}
+
+	var best *IndexSelectPlan
+	for _, fldname := range slices.Sorted(maps.Keys(tp.indexes)) {
+		// an index can only be searched with a key of its own type
+		val := tp.mypred.EquatesWithConstant(fldname)
+		if val == nil || val.Type() != table.Schema().Type(fldname) {
+			continue
+		}
+		isp := NewIndexSelectPlan(table, tp.indexes[fldname], *val)
+		if best == nil || isp.RecordsOutput() < best.RecordsOutput() {
+			best = isp
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best
+}
+
+// makeIndexJoin picks the cheapest index whose field the predicate equates with a field of current.
+func (tp *TablePlanner) makeIndexJoin(current query.Plan) query.Plan {
+	table, ok := tp.myplan.(*TablePlan)
+	if !ok {
+		return nil
+	}
+
+	var best *IndexJoinPlan
+	for _, fldname := range slices.Sorted(maps.Keys(tp.indexes)) {
+		outerfield := tp.mypred.EquatesWithField(fldname)
+		if outerfield == nil || !current.Schema().HasField(*outerfield) {
+			continue
+		}
+		if current.Schema().Type(*outerfield) != table.Schema().Type(fldname) {
+			continue
+		}
+		ijp := NewIndexJoinPlan(current, table, tp.indexes[fldname], *outerfield)
+		if best == nil || ijp.BlocksAccessed() < best.BlocksAccessed() {
+			best = ijp
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return tp.addJoinPred(tp.addSelectPred(best), current)
+}
+
+// makeHashJoin picks the cheapest hash join on a field of the table the predicate equates with a field of current.
+func (tp *TablePlanner) makeHashJoin(current query.Plan) query.Plan {
+	var best query.Plan
+	for fldname, outerfield := range tp.equiJoins(current) {
+		hjp := NewHashJoinPlan(tp.tx, current, tp.MakeSelectPlan(), outerfield, fldname)
+		if best == nil || hjp.BlocksAccessed() < best.BlocksAccessed() {
+			best = hjp
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return tp.addJoinPred(best, current)
+}
+
+// makeMergeJoin picks the cheapest merge join on a field of the table the predicate equates with a field of current.
+func (tp *TablePlanner) makeMergeJoin(current query.Plan) query.Plan {
+	var best query.Plan
+	for fldname, outerfield := range tp.equiJoins(current) {
+		mjp, err := NewMergeJoinPlan(tp.tx, current, tp.MakeSelectPlan(), outerfield, fldname)
+		if err != nil {
+			continue
+		}
+		if best == nil || mjp.BlocksAccessed() < best.BlocksAccessed() {
+			best = mjp
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return tp.addJoinPred(best, current)
+}
+
+// equiJoins yields every field of the table, in schema order,
+// that the predicate equates with a field of current of the same type.
+func (tp *TablePlanner) equiJoins(current query.Plan) iter.Seq2[string, string] {
+	myschema := tp.myplan.Schema()
+	return func(yield func(string, string) bool) {
+		for _, fldname := range myschema.Fields() {
+			outerfield := tp.mypred.EquatesWithField(fldname)
+			if outerfield == nil || myschema.HasField(*outerfield) || !current.Schema().HasField(*outerfield) {
+				continue
+			}
+			if current.Schema().Type(*outerfield) != myschema.Type(fldname) {
+				continue
+			}
+			if !yield(fldname, *outerfield) {
+				return
+			}
+		}
+	}
+}
+
+func (tp *TablePlanner) makeProductJoin(current query.Plan) query.Plan {
+	return tp.addJoinPred(tp.MakeProductPlan(current), current)
+}
+
+func (tp *TablePlanner) addSelectPred(p query.Plan) query.Plan {
+	selectpred := tp.mypred.SelectSubPred(tp.myplan.Schema())
+	if selectpred == nil {
+		return p
+	}
+	return NewSelectPlan(p, selectpred)
+}
+
+func (tp *TablePlanner) addJoinPred(p query.Plan, current query.Plan) query.Plan {
+	joinpred := tp.mypred.JoinSubPred(current.Schema(), tp.myplan.Schema())
+	if joinpred == nil {
+		return p
+	}
+	return NewSelectPlan(p, joinpred)
+}
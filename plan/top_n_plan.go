@@ -0,0 +1,52 @@
+package plan
+
+import (
+	"fmt"
+
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ query.Plan = (*TopNPlan)(nil)
+
+// NewTopNPlan creates a plan that returns the first n records of p sorted by sortFields.
+// Unlike a SortPlan it holds them in memory, without writing any temporary table.
+func NewTopNPlan(p query.Plan, sortFields []query.SortField, n int) (*TopNPlan, error) {
+	for _, sf := range sortFields {
+		if !p.Schema().HasField(sf.Name) {
+			return nil, fmt.Errorf("sort field %s is not in the schema", sf.Name)
+		}
+	}
+	return &TopNPlan{p: p, comp: query.NewSortFieldComparator(sortFields), n: n}, nil
+}
+
+type TopNPlan struct {
+	p    query.Plan
+	comp *query.RecordComparator
+	n    int
+}
+
+func (tp *TopNPlan) Open() (record.Scan, error) {
+	s, err := tp.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return query.NewTopNScan(s, tp.comp, tp.n, tp.p.Schema().Fields())
+}
+
+// BlocksAccessed is the cost of reading p once.
+func (tp *TopNPlan) BlocksAccessed() int {
+	return tp.p.BlocksAccessed()
+}
+
+func (tp *TopNPlan) RecordsOutput() int {
+	return min(tp.p.RecordsOutput(), tp.n)
+}
+
+func (tp *TopNPlan) DistinctValues(fldname string) int {
+	return min(tp.p.DistinctValues(fldname), max(tp.RecordsOutput(), 1))
+}
+
+func (tp *TopNPlan) Schema() *record.Schema {
+	return tp.p.Schema()
+}
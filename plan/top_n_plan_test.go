@@ -0,0 +1,35 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopNPlan(t *testing.T) {
+	env := setupTest(t, 8)
+
+	env.exec(t, "create table enroll (eid int, grade int)")
+	for i := range 150 {
+		env.exec(t, fmt.Sprintf("insert into enroll (eid, grade) values (%d, %d)", i, fk.IntBetween(0, 20)))
+	}
+
+	// the first records in the order of a full sort
+	sortFields := []query.SortField{{Name: "grade", Desc: true}, {Name: "eid"}}
+	sp, err := NewOrderedSortPlan(env.tx, env.table(t, "enroll"), sortFields)
+	require.NoError(t, err)
+	sorted := readRows(t, sp, "eid", "grade")
+	for _, n := range []int{0, 1, 10, 150, 200} {
+		src := env.table(t, "enroll")
+		tp, err := NewTopNPlan(src, sortFields, n)
+		require.NoError(t, err)
+		require.Equal(t, sorted[:min(n, len(sorted))], readRows(t, tp, "eid", "grade"), "n %d", n)
+		require.Equal(t, min(n, src.RecordsOutput()), tp.RecordsOutput())
+		require.Equal(t, src.BlocksAccessed(), tp.BlocksAccessed())
+	}
+
+	_, err = NewTopNPlan(env.table(t, "enroll"), []query.SortField{{Name: "nosuchfield"}}, 5)
+	require.Error(t, err)
+}
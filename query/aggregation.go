@@ -1,12 +1,60 @@
 package query
 
 import (
+	"fmt"
+
 	"github.com/kanthorlabs/kanthorkv/record"
 )
 
 type AggregationFn interface {
+	// Reset clears the function, its value is then the one of a group without records.
+	Reset()
 	ProcessFirst(scan record.Scan) error
 	ProcessNext(scan record.Scan) error
 	FieldName() string
 	Value() record.Constant
+	// Type returns the type and length of the output field, reading records of schema sch.
+	// It fails when sch lacks the field the function reads.
+	Type(sch *record.Schema) (record.FieldType, int, error)
+}
+
+// NewAggregationFn creates the aggregation function called name on the field fldname,
+// fldname is "*" for COUNT(*).
+func NewAggregationFn(name, fldname string) (AggregationFn, error) {
+	if fldname == "*" && name != "count" {
+		return nil, fmt.Errorf("%s(*) is not an aggregation function", name)
+	}
+	switch name {
+	case "count":
+		return NewCountFn(fldname), nil
+	case "sum":
+		return NewSumFn(fldname), nil
+	case "avg":
+		return NewAvgFn(fldname), nil
+	case "min":
+		return NewMinFn(fldname), nil
+	case "max":
+		return NewMaxFn(fldname), nil
+	}
+	return nil, fmt.Errorf("unknown aggregation function %s", name)
+}
+
+// IsAggregationFn tells whether name is the name of an aggregation function.
+func IsAggregationFn(name string) bool {
+	switch name {
+	case "count", "sum", "avg", "min", "max":
+		return true
+	}
+	return false
+}
+
+// intInput checks that the field fldname of sch is an integer, the only type that can be summed.
+func intInput(sch *record.Schema, fldname string) error {
+	if !sch.HasField(fldname) {
+		return fmt.Errorf("field %s not found", fldname)
+	}
+	if sch.Type(fldname) != record.IntegerField {
+		return fmt.Errorf("field %s is not an integer", fldname)
+	}
+	return nil
 }
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ AggregationFn = (*AvgFn)(nil)
+
+// AvgFn averages the integer field of the records of a group.
+// There are only integers, so the average is rounded toward zero.
+// NULL is skipped, the average of no values is NULL.
+type AvgFn struct {
+	fieldName string
+	sum       int
+	count     int
+}
+
+func NewAvgFn(fieldName string) *AvgFn {
+	return &AvgFn{fieldName: fieldName}
+}
+
+func (af *AvgFn) Reset() {
+	af.sum, af.count = 0, 0
+}
+
+func (af *AvgFn) ProcessFirst(scan record.Scan) error {
+	af.Reset()
+	return af.ProcessNext(scan)
+}
+
+func (af *AvgFn) ProcessNext(scan record.Scan) error {
+	val, err := scan.GetVal(af.fieldName)
+	if err != nil || val.IsNull() {
+		return err
+	}
+	af.sum += val.AsInt()
+	af.count++
+	return nil
+}
+
+func (af *AvgFn) FieldName() string {
+	return fmt.Sprintf("avg(%s)", af.fieldName)
+}
+
+func (af *AvgFn) Value() record.Constant {
+	if af.count == 0 {
+		return record.Constant{}
+	}
+	return record.NewIntConstant(af.sum / af.count)
+}
+
+func (af *AvgFn) Type(sch *record.Schema) (record.FieldType, int, error) {
+	if err := intInput(sch, af.fieldName); err != nil {
+		return 0, 0, err
+	}
+	return record.IntegerField, 0, nil
+}
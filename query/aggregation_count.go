@@ -0,0 +1,55 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ AggregationFn = (*CountFn)(nil)
+
+// CountFn counts the records of a group, COUNT(field) only those where the field is not NULL.
+type CountFn struct {
+	fieldName string
+	count     int
+}
+
+// NewCountFn creates a count of the records, fieldName is "*" for COUNT(*).
+func NewCountFn(fieldName string) *CountFn {
+	return &CountFn{fieldName: fieldName}
+}
+
+func (cf *CountFn) Reset() {
+	cf.count = 0
+}
+
+func (cf *CountFn) ProcessFirst(scan record.Scan) error {
+	cf.Reset()
+	return cf.ProcessNext(scan)
+}
+
+func (cf *CountFn) ProcessNext(scan record.Scan) error {
+	if cf.fieldName != "*" {
+		val, err := scan.GetVal(cf.fieldName)
+		if err != nil || val.IsNull() {
+			return err
+		}
+	}
+	cf.count++
+	return nil
+}
+
+func (cf *CountFn) FieldName() string {
+	return fmt.Sprintf("count(%s)", cf.fieldName)
+}
+
+func (cf *CountFn) Value() record.Constant {
+	return record.NewIntConstant(cf.count)
+}
+
+func (cf *CountFn) Type(sch *record.Schema) (record.FieldType, int, error) {
+	if cf.fieldName != "*" && !sch.HasField(cf.fieldName) {
+		return 0, 0, fmt.Errorf("field %s not found", cf.fieldName)
+	}
+	return record.IntegerField, 0, nil
+}
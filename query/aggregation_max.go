@@ -17,6 +17,11 @@ func NewMaxFn(fieldName string) *MaxFn {
 	return &MaxFn{fieldName: fieldName, val: record.Constant{}}
 }
 
+// Reset makes the maximum NULL, there is no value to take it from.
+func (mf *MaxFn) Reset() {
+	mf.val = record.Constant{}
+}
+
 func (mf *MaxFn) ProcessFirst(scan record.Scan) error {
 	val, err := scan.GetVal(mf.fieldName)
 	if err != nil {
@@ -26,6 +31,7 @@ func (mf *MaxFn) ProcessFirst(scan record.Scan) error {
 	return nil
 }
 
+// ProcessNext keeps any value over NULL, which sorts before every value.
 func (mf *MaxFn) ProcessNext(scan record.Scan) error {
 	val, err := scan.GetVal(mf.fieldName)
 	if err != nil {
@@ -44,3 +50,11 @@ func (mf *MaxFn) FieldName() string {
 func (mf *MaxFn) Value() record.Constant {
 	return mf.val
 }
+
+// Type is the type of the field, the largest value is one of its values.
+func (mf *MaxFn) Type(sch *record.Schema) (record.FieldType, int, error) {
+	if !sch.HasField(mf.fieldName) {
+		return 0, 0, fmt.Errorf("field %s not found", mf.fieldName)
+	}
+	return sch.Type(mf.fieldName), sch.Length(mf.fieldName), nil
+}
@@ -17,6 +17,11 @@ func NewMinFn(fieldName string) *MinFn {
 	return &MinFn{fieldName: fieldName, val: record.Constant{}}
 }
 
+// Reset makes the minimum NULL, there is no value to take it from.
+func (mf *MinFn) Reset() {
+	mf.val = record.Constant{}
+}
+
 func (mf *MinFn) ProcessFirst(scan record.Scan) error {
 	val, err := scan.GetVal(mf.fieldName)
 	if err != nil {
@@ -26,21 +31,30 @@ func (mf *MinFn) ProcessFirst(scan record.Scan) error {
 	return nil
 }
 
+// ProcessNext skips NULL, which sorts before every value.
 func (mf *MinFn) ProcessNext(scan record.Scan) error {
 	val, err := scan.GetVal(mf.fieldName)
 	if err != nil {
 		return err
 	}
-	if val.Compare(mf.val) < 0 {
+	if !val.IsNull() && (mf.val.IsNull() || val.Compare(mf.val) < 0) {
 		mf.val = val
 	}
 	return nil
 }
 
 func (mf *MinFn) FieldName() string {
-	return fmt.Sprintf("max(%s)", mf.fieldName)
+	return fmt.Sprintf("min(%s)", mf.fieldName)
 }
 
 func (mf *MinFn) Value() record.Constant {
 	return mf.val
 }
+
+// Type is the type of the field, the smallest value is one of its values.
+func (mf *MinFn) Type(sch *record.Schema) (record.FieldType, int, error) {
+	if !sch.HasField(mf.fieldName) {
+		return 0, 0, fmt.Errorf("field %s not found", mf.fieldName)
+	}
+	return sch.Type(mf.fieldName), sch.Length(mf.fieldName), nil
+}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ AggregationFn = (*SumFn)(nil)
+
+// SumFn adds up the integer field of the records of a group, skipping NULL.
+// The sum of no values is NULL.
+type SumFn struct {
+	fieldName string
+	sum       int
+	count     int
+}
+
+func NewSumFn(fieldName string) *SumFn {
+	return &SumFn{fieldName: fieldName}
+}
+
+func (sf *SumFn) Reset() {
+	sf.sum, sf.count = 0, 0
+}
+
+func (sf *SumFn) ProcessFirst(scan record.Scan) error {
+	sf.Reset()
+	return sf.ProcessNext(scan)
+}
+
+func (sf *SumFn) ProcessNext(scan record.Scan) error {
+	val, err := scan.GetVal(sf.fieldName)
+	if err != nil || val.IsNull() {
+		return err
+	}
+	sf.sum += val.AsInt()
+	sf.count++
+	return nil
+}
+
+func (sf *SumFn) FieldName() string {
+	return fmt.Sprintf("sum(%s)", sf.fieldName)
+}
+
+func (sf *SumFn) Value() record.Constant {
+	if sf.count == 0 {
+		return record.Constant{}
+	}
+	return record.NewIntConstant(sf.sum)
+}
+
+func (sf *SumFn) Type(sch *record.Schema) (record.FieldType, int, error) {
+	if err := intInput(sch, sf.fieldName); err != nil {
+		return 0, 0, err
+	}
+	return record.IntegerField, 0, nil
+}
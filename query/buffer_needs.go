@@ -0,0 +1,31 @@
+package query
+
+import "math"
+
+// BestRoot returns the highest root of size that fits in the available buffers,
+// the number of runs a sort can merge at once, leaving two buffers to the rest of the query.
+func BestRoot(available, size int) int {
+	avail := available - 2
+	if avail <= 1 {
+		return 1
+	}
+	k := math.MaxInt
+	for i := 1.0; k > avail; i++ {
+		k = int(math.Ceil(math.Pow(float64(size), 1/i)))
+	}
+	return k
+}
+
+// BestFactor returns the biggest factor of size that fits in the available buffers,
+// the number of blocks in a chunk of a multibuffer product, leaving two buffers to the rest of the query.
+func BestFactor(available, size int) int {
+	avail := available - 2
+	if avail <= 1 {
+		return 1
+	}
+	k := size
+	for i := 1.0; k > avail; i++ {
+		k = int(math.Ceil(float64(size) / i))
+	}
+	return k
+}
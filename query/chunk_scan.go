@@ -0,0 +1,94 @@
+package query
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+var _ record.Scan = (*ChunkScan)(nil)
+
+// NewChunkScan creates a scan of the blocks startbnum to endbnum of a table file,
+// all of them pinned until the scan is closed.
+func NewChunkScan(tx transaction.Transaction, filename string, layout *record.Layout, startbnum, endbnum int) (*ChunkScan, error) {
+	cs := &ChunkScan{tx: tx, filename: filename, layout: layout, startbnum: startbnum, endbnum: endbnum}
+	for i := startbnum; i <= endbnum; i++ {
+		cs.rps = append(cs.rps, record.NewRecordPage(tx, file.NewBlockId(filename, i), layout))
+	}
+	if err := cs.BeforeFirst(); err != nil {
+		return nil, errors.Join(err, cs.Close())
+	}
+	return cs, nil
+}
+
+// ChunkScan reads the records of a few blocks of a table that are kept pinned,
+// so scanning them again reads no disk block.
+type ChunkScan struct {
+	tx        transaction.Transaction
+	filename  string
+	layout    *record.Layout
+	rps       []*record.RecordPage
+	startbnum int
+	endbnum   int
+
+	current     int
+	currentslot int
+}
+
+func (cs *ChunkScan) BeforeFirst() error {
+	cs.current = 0
+	cs.currentslot = -1
+	return nil
+}
+
+// Next moves to the next record of the current block, then of the blocks after it.
+func (cs *ChunkScan) Next() bool {
+	for cs.current < len(cs.rps) {
+		cs.currentslot = cs.rps[cs.current].NextAfter(cs.currentslot)
+		if cs.currentslot >= 0 {
+			return true
+		}
+		cs.current++
+		cs.currentslot = -1
+	}
+	return false
+}
+
+func (cs *ChunkScan) GetInt(fldname string) (int, error) {
+	return cs.rps[cs.current].GetInt(cs.currentslot, fldname)
+}
+
+func (cs *ChunkScan) GetString(fldname string) (string, error) {
+	return cs.rps[cs.current].GetString(cs.currentslot, fldname)
+}
+
+func (cs *ChunkScan) GetVal(fldname string) (record.Constant, error) {
+	if cs.layout.Schema().Type(fldname) == record.IntegerField {
+		i, err := cs.GetInt(fldname)
+		if err != nil {
+			return record.Constant{}, err
+		}
+		return record.NewIntConstant(i), nil
+	}
+
+	s, err := cs.GetString(fldname)
+	if err != nil {
+		return record.Constant{}, err
+	}
+	return record.NewStringConstant(s), nil
+}
+
+func (cs *ChunkScan) HasField(fldname string) bool {
+	return cs.layout.Schema().HasField(fldname)
+}
+
+func (cs *ChunkScan) Close() error {
+	var errs []error
+	for _, rp := range cs.rps {
+		errs = append(errs, cs.tx.Unpin(rp.Block()))
+	}
+	cs.rps = nil
+	return errors.Join(errs...)
+}
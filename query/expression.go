@@ -1,9 +1,23 @@
 package query
 
 import (
+	"fmt"
+	"unicode/utf8"
+
 	"github.com/kanthorlabs/kanthorkv/record"
 )
 
+// operators of a computed expression
+const (
+	OP_ADD    = "+"
+	OP_SUB    = "-"
+	OP_MUL    = "*"
+	OP_DIV    = "/"
+	OP_MOD    = "%"
+	OP_CONCAT = "||"
+	OP_NEG    = "neg"
+)
+
 func NewConstantExpression(val *record.Constant) *Expression {
 	return &Expression{val: val}
 }
@@ -12,36 +26,250 @@ func NewFieldExpression(fldname *string) *Expression {
 	return &Expression{fldname: fldname}
 }
 
+// NewOperatorExpression creates the expression lhs op rhs, op is one of the binary OP_ operators.
+func NewOperatorExpression(op string, lhs, rhs *Expression) *Expression {
+	return &Expression{op: op, args: []*Expression{lhs, rhs}}
+}
+
+// NewNegateExpression creates the expression -e.
+func NewNegateExpression(e *Expression) *Expression {
+	return &Expression{op: OP_NEG, args: []*Expression{e}}
+}
+
+// NewFunctionExpression creates the call of the scalar function name on args.
+func NewFunctionExpression(name string, args []*Expression) (*Expression, error) {
+	fn, ok := scalarFns[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments for %s: %d", name, len(args))
+	}
+	return &Expression{op: name, fn: fn, args: args}, nil
+}
+
+// Expression is a constant, a field, or an operator or scalar function applied to expressions.
 type Expression struct {
 	val     *record.Constant // using pointer to represent nullable constant
 	fldname *string          // using pointer to represent nullable string
+	// op is the operator or the function name of a computed expression
+	op   string
+	fn   *scalarFn
+	args []*Expression
 }
 
 func (e *Expression) Evaluate(s record.Scan) (record.Constant, error) {
 	if e.val != nil {
 		return *e.val, nil
 	}
-	return s.GetVal(*e.fldname)
+	if e.fldname != nil {
+		return s.GetVal(*e.fldname)
+	}
+
+	args := make([]record.Constant, 0, len(e.args))
+	for _, arg := range e.args {
+		val, err := arg.Evaluate(s)
+		if err != nil {
+			return record.Constant{}, err
+		}
+		// an operator on NULL gives NULL
+		if val.IsNull() && (e.fn == nil || !e.fn.takesNull) {
+			return record.Constant{}, nil
+		}
+		args = append(args, val)
+	}
+	if e.fn != nil {
+		return e.fn.eval(args)
+	}
+	return evalOperator(e.op, args)
+}
+
+// Type returns the type and length of the values of the expression on records of schema sch.
+// It fails when sch lacks a field the expression reads, or an operator gets values of the wrong type.
+func (e *Expression) Type(sch *record.Schema) (record.FieldType, int, error) {
+	if e.val != nil {
+		if e.val.Type() == record.IntegerField {
+			return record.IntegerField, 0, nil
+		}
+		return record.StringField, utf8.RuneCountInString(e.val.AsString()), nil
+	}
+	if e.fldname != nil {
+		if !sch.HasField(*e.fldname) {
+			return 0, 0, fmt.Errorf("field %s not found", *e.fldname)
+		}
+		return sch.Type(*e.fldname), sch.Length(*e.fldname), nil
+	}
+
+	types := make([]record.FieldType, 0, len(e.args))
+	lengths := make([]int, 0, len(e.args))
+	for _, arg := range e.args {
+		t, length, err := arg.Type(sch)
+		if err != nil {
+			return 0, 0, err
+		}
+		types = append(types, t)
+		lengths = append(lengths, length)
+	}
+	if e.fn != nil {
+		return e.fn.typ(e.op, types, lengths)
+	}
+
+	if e.op == OP_CONCAT {
+		if err := expectTypes(e.op, record.StringField, types); err != nil {
+			return 0, 0, err
+		}
+		return record.StringField, lengths[0] + lengths[1], nil
+	}
+	if err := expectTypes(e.op, record.IntegerField, types); err != nil {
+		return 0, 0, err
+	}
+	return record.IntegerField, 0, nil
 }
 
 func (e *Expression) Constant() *record.Constant {
 	return e.val
 }
 
+// FieldName is the name of the field of a field expression, nil for any other expression.
 func (e *Expression) FieldName() *string {
 	return e.fldname
 }
 
+// isComputed tells whether the expression is neither a constant nor a field.
+func (e *Expression) isComputed() bool {
+	return e.val == nil && e.fldname == nil
+}
+
 func (e *Expression) AppliesTo(sch *record.Schema) bool {
 	if e.val != nil {
 		return true
 	}
-	return sch.HasField(*e.fldname)
+	if e.fldname != nil {
+		return sch.HasField(*e.fldname)
+	}
+	for _, arg := range e.args {
+		if !arg.AppliesTo(sch) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve returns a copy of the expression that reads the field resolve names instead of each of its fields.
+func (e *Expression) Resolve(resolve func(fldname string) (string, error)) (*Expression, error) {
+	if e.val != nil {
+		return e, nil
+	}
+	if e.fldname != nil {
+		fldname, err := resolve(*e.fldname)
+		if err != nil {
+			return nil, err
+		}
+		return NewFieldExpression(&fldname), nil
+	}
+	resolved := &Expression{op: e.op, fn: e.fn, args: make([]*Expression, 0, len(e.args))}
+	for _, arg := range e.args {
+		r, err := arg.Resolve(resolve)
+		if err != nil {
+			return nil, err
+		}
+		resolved.args = append(resolved.args, r)
+	}
+	return resolved, nil
 }
 
+// String returns the expression with the parentheses needed to parse it back.
 func (e *Expression) String() string {
 	if e.val != nil {
 		return e.val.String()
 	}
-	return *e.fldname
+	if e.fldname != nil {
+		return *e.fldname
+	}
+	if e.fn != nil {
+		result := e.op + "("
+		for i, arg := range e.args {
+			if i > 0 {
+				result += ", "
+			}
+			result += arg.String()
+		}
+		return result + ")"
+	}
+	if e.op == OP_NEG {
+		return "-" + e.args[0].operand(precedence(OP_NEG), true)
+	}
+	prec := precedence(e.op)
+	return e.args[0].operand(prec, false) + " " + e.op + " " + e.args[1].operand(prec, true)
+}
+
+// operand returns the string of an operand of an operator of precedence prec.
+// Operators are left-associative, so a right operand of the same precedence needs parentheses too.
+func (e *Expression) operand(prec int, right bool) string {
+	p := precedence(e.op)
+	if !e.isComputed() || p > prec || (p == prec && !right) {
+		return e.String()
+	}
+	return "(" + e.String() + ")"
+}
+
+// precedence orders operators from the loosest binding, function calls and primary expressions bind tightest.
+func precedence(op string) int {
+	switch op {
+	case OP_CONCAT:
+		return 1
+	case OP_ADD, OP_SUB:
+		return 2
+	case OP_MUL, OP_DIV, OP_MOD:
+		return 3
+	case OP_NEG:
+		return 4
+	}
+	return 5
+}
+
+func evalOperator(op string, args []record.Constant) (record.Constant, error) {
+	if op == OP_CONCAT {
+		if args[0].Type() != record.StringField || args[1].Type() != record.StringField {
+			return record.Constant{}, fmt.Errorf("cannot apply %s to %s and %s", op, args[0], args[1])
+		}
+		return record.NewStringConstant(args[0].AsString() + args[1].AsString()), nil
+	}
+	for _, arg := range args {
+		if arg.Type() != record.IntegerField {
+			return record.Constant{}, fmt.Errorf("cannot apply %s to %s", op, arg)
+		}
+	}
+	if op == OP_NEG {
+		return record.NewIntConstant(-args[0].AsInt()), nil
+	}
+
+	lhs, rhs := args[0].AsInt(), args[1].AsInt()
+	switch op {
+	case OP_ADD:
+		return record.NewIntConstant(lhs + rhs), nil
+	case OP_SUB:
+		return record.NewIntConstant(lhs - rhs), nil
+	case OP_MUL:
+		return record.NewIntConstant(lhs * rhs), nil
+	case OP_DIV, OP_MOD:
+		if rhs == 0 {
+			return record.Constant{}, fmt.Errorf("division by zero")
+		}
+		if op == OP_DIV {
+			return record.NewIntConstant(lhs / rhs), nil
+		}
+		return record.NewIntConstant(lhs % rhs), nil
+	}
+	return record.Constant{}, fmt.Errorf("unknown operator %s", op)
+}
+
+// expectTypes checks that every operand of op has type t.
+func expectTypes(op string, t record.FieldType, types []record.FieldType) error {
+	for _, got := range types {
+		if got != t {
+			return fmt.Errorf("%s expects %s operands, got %s", op, t, got)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,161 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+// scalarFn is a built-in function computing one value from the values of its arguments.
+type scalarFn struct {
+	minArgs int
+	// maxArgs is -1 for a function taking any number of arguments
+	maxArgs int
+	// takesNull is set for a function that looks at NULL arguments, any other one is NULL when an argument is
+	takesNull bool
+	// typ checks the types of the arguments and returns the type and length of the result
+	typ  func(name string, types []record.FieldType, lengths []int) (record.FieldType, int, error)
+	eval func(args []record.Constant) (record.Constant, error)
+}
+
+var scalarFns = map[string]*scalarFn{
+	"length": {
+		minArgs: 1, maxArgs: 1,
+		typ: intResult(record.StringField),
+		eval: func(args []record.Constant) (record.Constant, error) {
+			s, err := stringArg("length", args[0])
+			if err != nil {
+				return record.Constant{}, err
+			}
+			return record.NewIntConstant(len([]rune(s))), nil
+		},
+	},
+	"upper": {
+		minArgs: 1, maxArgs: 1,
+		typ: stringResult,
+		eval: func(args []record.Constant) (record.Constant, error) {
+			s, err := stringArg("upper", args[0])
+			if err != nil {
+				return record.Constant{}, err
+			}
+			return record.NewStringConstant(strings.ToUpper(s)), nil
+		},
+	},
+	"lower": {
+		minArgs: 1, maxArgs: 1,
+		typ: stringResult,
+		eval: func(args []record.Constant) (record.Constant, error) {
+			s, err := stringArg("lower", args[0])
+			if err != nil {
+				return record.Constant{}, err
+			}
+			return record.NewStringConstant(strings.ToLower(s)), nil
+		},
+	},
+	"substr": {
+		minArgs: 2, maxArgs: 3,
+		typ:  stringResult,
+		eval: substr,
+	},
+	"abs": {
+		minArgs: 1, maxArgs: 1,
+		typ: intResult(record.IntegerField),
+		eval: func(args []record.Constant) (record.Constant, error) {
+			if args[0].Type() != record.IntegerField {
+				return record.Constant{}, fmt.Errorf("abs expects an integer, got %s", args[0])
+			}
+			return record.NewIntConstant(max(args[0].AsInt(), -args[0].AsInt())), nil
+		},
+	},
+	"coalesce": {
+		minArgs: 1, maxArgs: -1, takesNull: true,
+		typ: func(name string, types []record.FieldType, lengths []int) (record.FieldType, int, error) {
+			if err := expectTypes(name, types[0], types); err != nil {
+				return 0, 0, err
+			}
+			return types[0], maxLength(lengths), nil
+		},
+		eval: func(args []record.Constant) (record.Constant, error) {
+			for _, arg := range args {
+				if !arg.IsNull() {
+					return arg, nil
+				}
+			}
+			return record.Constant{}, nil
+		},
+	},
+}
+
+// IsScalarFn tells whether name is the name of a built-in scalar function.
+func IsScalarFn(name string) bool {
+	_, ok := scalarFns[name]
+	return ok
+}
+
+// SUBSTR(s, start[, count]) counts characters from 1, like SQL.
+// The characters before the first one or after the last one are simply not there.
+func substr(args []record.Constant) (record.Constant, error) {
+	s, err := stringArg("substr", args[0])
+	if err != nil {
+		return record.Constant{}, err
+	}
+	for _, arg := range args[1:] {
+		if arg.Type() != record.IntegerField {
+			return record.Constant{}, fmt.Errorf("substr expects integer positions, got %s", arg)
+		}
+	}
+
+	runes := []rune(s)
+	from, to := args[1].AsInt(), len(runes)+1
+	if len(args) == 3 {
+		count := args[2].AsInt()
+		if count < 0 {
+			return record.Constant{}, fmt.Errorf("substr length %d is negative", count)
+		}
+		to = min(to, from+count)
+	}
+	from = max(from, 1)
+	if from >= to {
+		return record.NewStringConstant(""), nil
+	}
+	return record.NewStringConstant(string(runes[from-1 : to-1])), nil
+}
+
+func stringArg(name string, arg record.Constant) (string, error) {
+	if arg.Type() != record.StringField {
+		return "", fmt.Errorf("%s expects a string, got %s", name, arg)
+	}
+	return arg.AsString(), nil
+}
+
+// intResult types a function of one argument of type t returning an integer.
+func intResult(t record.FieldType) func(string, []record.FieldType, []int) (record.FieldType, int, error) {
+	return func(name string, types []record.FieldType, _ []int) (record.FieldType, int, error) {
+		if err := expectTypes(name, t, types); err != nil {
+			return 0, 0, err
+		}
+		return record.IntegerField, 0, nil
+	}
+}
+
+// stringResult types a function returning part of its first argument, a string,
+// the other arguments are integers.
+func stringResult(name string, types []record.FieldType, lengths []int) (record.FieldType, int, error) {
+	if err := expectTypes(name, record.StringField, types[:1]); err != nil {
+		return 0, 0, err
+	}
+	if err := expectTypes(name, record.IntegerField, types[1:]); err != nil {
+		return 0, 0, err
+	}
+	// case mapping keeps the number of characters, the length of a field counts characters
+	return record.StringField, lengths[0], nil
+}
+
+func maxLength(lengths []int) int {
+	result := 0
+	for _, length := range lengths {
+		result = max(result, length)
+	}
+	return result
+}
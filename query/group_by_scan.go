@@ -9,22 +9,32 @@ import (
 
 var _ record.Scan = (*GroupByScan)(nil)
 
+// GroupByScan returns one record per group of consecutive records of a sorted scan
+// that have the same values in groupFields, with the values of the aggregation functions over the group.
+// Without group fields the whole input is a single group, which an input without records still has,
+// so COUNT gives 0 and the other functions NULL.
 type GroupByScan struct {
 	scan        *SortScan
 	groupFields []string
 	aggFns      []AggregationFn
 	groupValue  *GroupValue
 	hasMore     bool
+	// emptyGroup is the group of an input without records, before Next returns it
+	emptyGroup bool
 }
 
-func NewGroupByScan(scan *SortScan, groupFields []string, aggFns []AggregationFn) *GroupByScan {
-	return &GroupByScan{
+func NewGroupByScan(scan *SortScan, groupFields []string, aggFns []AggregationFn) (*GroupByScan, error) {
+	gs := &GroupByScan{
 		scan:        scan,
 		groupFields: groupFields,
 		aggFns:      aggFns,
 		groupValue:  nil,
 		hasMore:     false,
 	}
+	if err := gs.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	return gs, nil
 }
 
 func (gs *GroupByScan) BeforeFirst() error {
@@ -32,10 +42,19 @@ func (gs *GroupByScan) BeforeFirst() error {
 		return err
 	}
 	gs.hasMore = gs.scan.Next()
+	gs.emptyGroup = !gs.hasMore && len(gs.groupFields) == 0
 	return nil
 }
 
+// Next reads the records of the next group, leaving the sorted scan on the first record of the group after it.
 func (gs *GroupByScan) Next() bool {
+	if gs.emptyGroup {
+		gs.emptyGroup = false
+		for _, fn := range gs.aggFns {
+			fn.Reset()
+		}
+		return true
+	}
 	if !gs.hasMore {
 		return false
 	}
@@ -51,7 +70,7 @@ func (gs *GroupByScan) Next() bool {
 	}
 	gs.groupValue = groupValue
 
-	for gs.hasMore = gs.scan.Next(); gs.hasMore; {
+	for gs.hasMore = gs.scan.Next(); gs.hasMore; gs.hasMore = gs.scan.Next() {
 		gv, err := NewGroupValue(gs.scan, gs.groupFields)
 		if err != nil {
 			panic(err)
@@ -90,6 +109,9 @@ func (gs *GroupByScan) GetInt(fieldName string) (int, error) {
 	if err != nil {
 		return 0, fmt.Errorf("gs.GetVal(%s): %w", fieldName, err)
 	}
+	if val.IsNull() {
+		return 0, nil
+	}
 	return val.AsInt(), nil
 }
 
@@ -98,6 +120,9 @@ func (gs *GroupByScan) GetString(fieldName string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("gs.GetVal(%s): %w", fieldName, err)
 	}
+	if val.IsNull() {
+		return "", nil
+	}
 	return val.AsString(), nil
 }
 
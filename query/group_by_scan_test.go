@@ -0,0 +1,44 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupByScan(t *testing.T) {
+	tx := setupTest(t, 8)
+
+	aggFns := func() []AggregationFn {
+		return []AggregationFn{NewCountFn("*"), NewSumFn("grade"), NewAvgFn("grade"), NewMinFn("grade"), NewMaxFn("grade")}
+	}
+	fields := []string{"count(*)", "sum(grade)", "avg(grade)", "min(grade)", "max(grade)"}
+	grades := testTable(t, tx, []string{"sid", "grade"}, []any{1, 4}, []any{1, 6}, []any{2, 9})
+	empty := testTable(t, tx, []string{"sid", "grade"})
+
+	tests := []struct {
+		name        string
+		tt          *TempTable
+		groupFields []string
+		want        []string
+	}{
+		{name: "groups", tt: grades, groupFields: []string{"sid"}, want: []string{"1 2 10 5 4 6", "2 1 9 9 9 9"}},
+		{name: "single group", tt: grades, want: []string{"3 19 6 4 9"}},
+		{name: "empty groups", tt: empty, groupFields: []string{"sid"}, want: []string{}},
+		// the single group is there without records, COUNT is 0 and the other functions NULL
+		{name: "empty single group", tt: empty, want: []string{"0 NULL NULL NULL NULL"}},
+	}
+	for _, tt := range tests {
+		ss, err := NewSortScan([]*TempTable{tt.tt}, NewRecordComparator(tt.groupFields))
+		require.NoError(t, err)
+		s, err := NewGroupByScan(ss, tt.groupFields, aggFns())
+		require.NoError(t, err)
+
+		// a second pass after BeforeFirst returns the same records
+		for range 2 {
+			require.Equal(t, tt.want, readRows(t, s, append(tt.groupFields, fields...)...), tt.name)
+			require.NoError(t, s.BeforeFirst())
+		}
+		require.NoError(t, s.Close())
+	}
+}
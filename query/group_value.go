@@ -39,7 +39,8 @@ func (gv *GroupValue) Equals(other *GroupValue) bool {
 		if !ok {
 			return false
 		}
-		if !val.Equal(otherVal) {
+		// NULL is not equal to anything, but the records where a field is NULL make one group
+		if val.IsNull() != otherVal.IsNull() || (!val.IsNull() && !val.Equal(otherVal)) {
 			return false
 		}
 	}
@@ -0,0 +1,339 @@
+package query
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+var _ record.Scan = (*HashJoinScan)(nil)
+
+// NewHashJoinScan joins the records of build and probe whose buildfield equals probefield.
+// The build side is read into a hash table when it has at most maxRows records.
+// A bigger one is split, together with the probe side, into temporary tables by hash,
+// and each pair of partitions is joined on its own.
+// buildRows estimates the size of the build side, to choose the number of partitions.
+func NewHashJoinScan(
+	tx transaction.Transaction,
+	build record.Scan, buildsch *record.Schema, buildfield string,
+	probe record.Scan, probesch *record.Schema, probefield string,
+	maxRows, buildRows int,
+) (*HashJoinScan, error) {
+	hjs := &HashJoinScan{
+		tx:         tx,
+		buildsch:   buildsch,
+		probesch:   probesch,
+		buildfield: buildfield,
+		probefield: probefield,
+		fieldpos:   make(map[string]int),
+	}
+	for i, fldname := range buildsch.Fields() {
+		hjs.fieldpos[fldname] = i
+	}
+
+	rows, complete, err := hjs.readRows(build, maxRows)
+	if err != nil {
+		return nil, errors.Join(err, build.Close(), probe.Close())
+	}
+	if complete {
+		hjs.table = hjs.hashRows(rows)
+		hjs.probe = probe
+		return hjs, build.Close()
+	}
+
+	if err := hjs.partition(rows, build, probe, max(buildRows, len(rows))/max(maxRows, 1)+1); err != nil {
+		return nil, errors.Join(err, build.Close(), probe.Close())
+	}
+	if err := errors.Join(build.Close(), probe.Close()); err != nil {
+		return nil, err
+	}
+	if err := hjs.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	return hjs, nil
+}
+
+// HashJoinScan is the scan of a Grace hash join.
+// The fields of the build side come from the matching record of the hash table,
+// the others from the probe scan.
+type HashJoinScan struct {
+	tx         transaction.Transaction
+	buildsch   *record.Schema
+	probesch   *record.Schema
+	buildfield string
+	probefield string
+	fieldpos   map[string]int
+
+	table map[int][][]record.Constant
+	probe record.Scan
+	// partitions is nil when the whole build side fits in the hash table
+	partitions []hashPartition
+	current    int
+
+	matches [][]record.Constant
+	row     []record.Constant
+}
+
+type hashPartition struct {
+	build, probe *TempTable
+}
+
+// BeforeFirst positions the scan before the first joined record.
+func (hjs *HashJoinScan) BeforeFirst() error {
+	hjs.matches = nil
+	if hjs.partitions == nil {
+		return hjs.probe.BeforeFirst()
+	}
+
+	if hjs.probe != nil {
+		if err := hjs.probe.Close(); err != nil {
+			return err
+		}
+		hjs.probe = nil
+	}
+	hjs.current = -1
+	_, err := hjs.nextPartition()
+	return err
+}
+
+// Next moves to the next build record matching the current probe record.
+// Otherwise, it moves to the next probe record, then to the next partition.
+func (hjs *HashJoinScan) Next() bool {
+	for {
+		if len(hjs.matches) > 0 {
+			hjs.row, hjs.matches = hjs.matches[0], hjs.matches[1:]
+			return true
+		}
+
+		if hjs.probe != nil && hjs.probe.Next() {
+			key, err := hjs.probe.GetVal(hjs.probefield)
+			if err != nil {
+				panic(err)
+			}
+			for _, row := range hjs.table[key.Hash()] {
+				if row[hjs.fieldpos[hjs.buildfield]].Equal(key) {
+					hjs.matches = append(hjs.matches, row)
+				}
+			}
+			continue
+		}
+
+		if hjs.partitions == nil {
+			return false
+		}
+		ok, err := hjs.nextPartition()
+		if err != nil {
+			panic(err)
+		}
+		if !ok {
+			return false
+		}
+	}
+}
+
+func (hjs *HashJoinScan) GetInt(fldname string) (int, error) {
+	if i, ok := hjs.fieldpos[fldname]; ok {
+		if hjs.row[i].IsNull() {
+			return 0, nil
+		}
+		return hjs.row[i].AsInt(), nil
+	}
+	return hjs.probe.GetInt(fldname)
+}
+
+func (hjs *HashJoinScan) GetString(fldname string) (string, error) {
+	if i, ok := hjs.fieldpos[fldname]; ok {
+		if hjs.row[i].IsNull() {
+			return "", nil
+		}
+		return hjs.row[i].AsString(), nil
+	}
+	return hjs.probe.GetString(fldname)
+}
+
+func (hjs *HashJoinScan) GetVal(fldname string) (record.Constant, error) {
+	if i, ok := hjs.fieldpos[fldname]; ok {
+		return hjs.row[i], nil
+	}
+	return hjs.probe.GetVal(fldname)
+}
+
+func (hjs *HashJoinScan) HasField(fldname string) bool {
+	_, ok := hjs.fieldpos[fldname]
+	return ok || hjs.probesch.HasField(fldname)
+}
+
+func (hjs *HashJoinScan) Close() error {
+	if hjs.probe == nil {
+		return nil
+	}
+	err := hjs.probe.Close()
+	hjs.probe = nil
+	return err
+}
+
+// readRows reads the records of s until more than maxRows have been read, a negative maxRows reads them all.
+// The returned flag tells whether s has been read completely.
+func (hjs *HashJoinScan) readRows(s record.Scan, maxRows int) ([][]record.Constant, bool, error) {
+	rows := make([][]record.Constant, 0)
+	for maxRows < 0 || len(rows) <= maxRows {
+		if !s.Next() {
+			return rows, true, nil
+		}
+		row, err := readRow(s, hjs.buildsch)
+		if err != nil {
+			return nil, false, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, false, nil
+}
+
+func (hjs *HashJoinScan) hashRows(rows [][]record.Constant) map[int][][]record.Constant {
+	table := make(map[int][][]record.Constant)
+	for _, row := range rows {
+		h := row[hjs.fieldpos[hjs.buildfield]].Hash()
+		table[h] = append(table[h], row)
+	}
+	return table
+}
+
+// partition writes the rows already read, the rest of build and all of probe into n temporary tables each.
+func (hjs *HashJoinScan) partition(rows [][]record.Constant, build, probe record.Scan, n int) error {
+	// writing pins one block per partition, half of the free buffers are left to the rest of the query
+	n = max(2, min(n, hjs.tx.AvailableBuffs()/2))
+	hjs.partitions = make([]hashPartition, n)
+	for i := range hjs.partitions {
+		hjs.partitions[i] = hashPartition{
+			build: NewTempTable(hjs.tx, hjs.buildsch),
+			probe: NewTempTable(hjs.tx, hjs.probesch),
+		}
+	}
+
+	builds, err := openPartitions(hjs.partitions, func(p hashPartition) *TempTable { return p.build })
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := writeRow(builds[partitionOf(row[hjs.fieldpos[hjs.buildfield]], n)], hjs.buildsch, row); err != nil {
+			return errors.Join(err, closePartitions(builds))
+		}
+	}
+	if err := copyPartitioned(build, hjs.buildsch, hjs.buildfield, builds); err != nil {
+		return errors.Join(err, closePartitions(builds))
+	}
+	if err := closePartitions(builds); err != nil {
+		return err
+	}
+
+	probes, err := openPartitions(hjs.partitions, func(p hashPartition) *TempTable { return p.probe })
+	if err != nil {
+		return err
+	}
+	if err := copyPartitioned(probe, hjs.probesch, hjs.probefield, probes); err != nil {
+		return errors.Join(err, closePartitions(probes))
+	}
+	return closePartitions(probes)
+}
+
+// nextPartition loads the hash table of the next partition and opens its probe side.
+func (hjs *HashJoinScan) nextPartition() (bool, error) {
+	if hjs.probe != nil {
+		if err := hjs.probe.Close(); err != nil {
+			return false, err
+		}
+		hjs.probe = nil
+	}
+	hjs.current++
+	if hjs.current >= len(hjs.partitions) {
+		return false, nil
+	}
+
+	build, err := hjs.partitions[hjs.current].build.Open()
+	if err != nil {
+		return false, err
+	}
+	// a partition with a skewed key is loaded whole, there is no smaller one to split it into
+	rows, _, err := hjs.readRows(build, -1)
+	if err != nil {
+		return false, errors.Join(err, build.Close())
+	}
+	if err := build.Close(); err != nil {
+		return false, err
+	}
+	hjs.table = hjs.hashRows(rows)
+
+	probe, err := hjs.partitions[hjs.current].probe.Open()
+	if err != nil {
+		return false, err
+	}
+	hjs.probe = probe
+	return true, nil
+}
+
+func partitionOf(val record.Constant, n int) int {
+	return ((val.Hash() % n) + n) % n
+}
+
+func openPartitions(partitions []hashPartition, table func(hashPartition) *TempTable) ([]*record.TableScan, error) {
+	scans := make([]*record.TableScan, 0, len(partitions))
+	for _, p := range partitions {
+		ts, err := table(p).Open()
+		if err != nil {
+			return nil, errors.Join(err, closePartitions(scans))
+		}
+		scans = append(scans, ts)
+	}
+	return scans, nil
+}
+
+func closePartitions(scans []*record.TableScan) error {
+	var errs []error
+	for _, ts := range scans {
+		errs = append(errs, ts.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// copyPartitioned writes every remaining record of s into the partition of its fldname value.
+func copyPartitioned(s record.Scan, sch *record.Schema, fldname string, partitions []*record.TableScan) error {
+	for s.Next() {
+		row, err := readRow(s, sch)
+		if err != nil {
+			return err
+		}
+		key, err := s.GetVal(fldname)
+		if err != nil {
+			return err
+		}
+		if err := writeRow(partitions[partitionOf(key, len(partitions))], sch, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRow(s record.Scan, sch *record.Schema) ([]record.Constant, error) {
+	row := make([]record.Constant, 0, len(sch.Fields()))
+	for _, fldname := range sch.Fields() {
+		val, err := s.GetVal(fldname)
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, val)
+	}
+	return row, nil
+}
+
+func writeRow(ts *record.TableScan, sch *record.Schema, row []record.Constant) error {
+	if err := ts.Insert(); err != nil {
+		return err
+	}
+	for i, fldname := range sch.Fields() {
+		if err := ts.SetVal(fldname, row[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashJoinScan(t *testing.T) {
+	tx := setupTest(t, 8)
+
+	// departments 30 to 39 have no student, majors 40 to 44 no department
+	depts := make([][]any, 0)
+	for did := range 40 {
+		depts = append(depts, []any{did, fmt.Sprintf("d%d", did)})
+	}
+	students := make([][]any, 0)
+	expected := make([]string, 0)
+	for sid := range 300 {
+		major := fk.IntBetween(0, 44)
+		students = append(students, []any{sid, major})
+		if major < 40 {
+			expected = append(expected, fmt.Sprintf("%d 'd%d'", sid, major))
+		}
+	}
+	dept := testTable(t, tx, []string{"did", "dname"}, depts...)
+	student := testTable(t, tx, []string{"sid", "majorid"}, students...)
+
+	// a build side of more than maxRows records is partitioned into temporary tables
+	for _, maxRows := range []int{100, 7} {
+		s, err := NewHashJoinScan(tx,
+			openTable(t, dept), dept.Layout().Schema(), "did",
+			openTable(t, student), student.Layout().Schema(), "majorid",
+			maxRows, 40,
+		)
+		require.NoError(t, err)
+
+		for range 2 {
+			require.ElementsMatch(t, expected, readRows(t, s, "sid", "dname"), "maxRows %d", maxRows)
+			require.NoError(t, s.BeforeFirst())
+		}
+		require.NoError(t, s.Close())
+	}
+}
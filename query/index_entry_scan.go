@@ -0,0 +1,78 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+// the fields of an index entry, named as in the layout of an index record
+const (
+	INDEX_ENTRY_DATAVAL = "dataval"
+	INDEX_ENTRY_BLOCK   = "block"
+	INDEX_ENTRY_ID      = "id"
+)
+
+var _ record.Scan = (*IndexEntryScan)(nil)
+
+// NewIndexEntryScan creates a scan of the index entries of the records of ts for the field fldname.
+func NewIndexEntryScan(ts *record.TableScan, fldname string) (*IndexEntryScan, error) {
+	s := &IndexEntryScan{ts: ts, fldname: fldname}
+	if err := s.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// IndexEntryScan reads every record of a table as the entry an index on one of its fields holds for it,
+// the value of the field and the rid of the record.
+type IndexEntryScan struct {
+	ts      *record.TableScan
+	fldname string
+}
+
+func (s *IndexEntryScan) BeforeFirst() error {
+	return s.ts.BeforeFirst()
+}
+
+func (s *IndexEntryScan) Next() bool {
+	return s.ts.Next()
+}
+
+func (s *IndexEntryScan) GetInt(fldname string) (int, error) {
+	switch fldname {
+	case INDEX_ENTRY_DATAVAL:
+		return s.ts.GetInt(s.fldname)
+	case INDEX_ENTRY_BLOCK:
+		return s.ts.GetRid().Blknum, nil
+	case INDEX_ENTRY_ID:
+		return s.ts.GetRid().Slot, nil
+	}
+	return 0, fmt.Errorf("field %s not found", fldname)
+}
+
+func (s *IndexEntryScan) GetString(fldname string) (string, error) {
+	if fldname != INDEX_ENTRY_DATAVAL {
+		return "", fmt.Errorf("field %s not found", fldname)
+	}
+	return s.ts.GetString(s.fldname)
+}
+
+func (s *IndexEntryScan) GetVal(fldname string) (record.Constant, error) {
+	if fldname == INDEX_ENTRY_DATAVAL {
+		return s.ts.GetVal(s.fldname)
+	}
+	val, err := s.GetInt(fldname)
+	if err != nil {
+		return record.Constant{}, err
+	}
+	return record.NewIntConstant(val), nil
+}
+
+func (s *IndexEntryScan) HasField(fldname string) bool {
+	return fldname == INDEX_ENTRY_DATAVAL || fldname == INDEX_ENTRY_BLOCK || fldname == INDEX_ENTRY_ID
+}
+
+func (s *IndexEntryScan) Close() error {
+	return s.ts.Close()
+}
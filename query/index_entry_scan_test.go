@@ -0,0 +1,42 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexEntryScan(t *testing.T) {
+	tx := setupTest(t, 8)
+
+	rows := make([][]any, 0)
+	for i := range 40 {
+		rows = append(rows, []any{i, fmt.Sprintf("n%d", i)})
+	}
+	students := testTable(t, tx, []string{"sid", "sname"}, rows...)
+
+	// every entry holds the value of the field and the rid of its record
+	expected := make([]string, 0)
+	ts := openTable(t, students)
+	for ts.Next() {
+		sname, err := ts.GetString("sname")
+		require.NoError(t, err)
+		rid := ts.GetRid()
+		expected = append(expected, fmt.Sprintf("'%s' %d %d", sname, rid.Blknum, rid.Slot))
+	}
+	require.NoError(t, ts.Close())
+	require.Len(t, expected, 40)
+
+	s, err := NewIndexEntryScan(openTable(t, students), "sname")
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, expected, readRows(t, s, INDEX_ENTRY_DATAVAL, INDEX_ENTRY_BLOCK, INDEX_ENTRY_ID))
+	require.NoError(t, s.BeforeFirst())
+	require.Equal(t, expected, readRows(t, s, INDEX_ENTRY_DATAVAL, INDEX_ENTRY_BLOCK, INDEX_ENTRY_ID))
+
+	require.True(t, s.HasField(INDEX_ENTRY_BLOCK))
+	require.False(t, s.HasField("sname"))
+	_, err = s.GetString(INDEX_ENTRY_BLOCK)
+	require.Error(t, err)
+}
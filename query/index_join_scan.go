@@ -0,0 +1,123 @@
+package query
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ record.Scan = (*IndexJoinScan)(nil)
+
+// NewIndexJoinScan creates a scan that joins every record of lhs
+// with the records of rhs whose indexed field equals the joinfield of lhs.
+func NewIndexJoinScan(lhs record.Scan, idx index.Index, joinfield string, rhs *record.TableScan) (*IndexJoinScan, error) {
+	ijs := &IndexJoinScan{lhs: lhs, idx: idx, joinfield: joinfield, rhs: rhs}
+	if err := ijs.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	return ijs, nil
+}
+
+type IndexJoinScan struct {
+	lhs       record.Scan
+	idx       index.Index
+	joinfield string
+	rhs       *record.TableScan
+	// hasLHS tells whether lhs is positioned on a record whose key the index is searching
+	hasLHS bool
+	// err stopped Next, Err and Close return it
+	err error
+}
+
+// BeforeFirst positions the scan before the first record.
+// The LHS scan is positioned at its first record,
+// and the index is positioned before the first record with its join value.
+func (ijs *IndexJoinScan) BeforeFirst() error {
+	ijs.err = nil
+	if err := ijs.lhs.BeforeFirst(); err != nil {
+		return err
+	}
+	ijs.hasLHS = ijs.lhs.Next()
+	if !ijs.hasLHS {
+		return nil
+	}
+	return ijs.resetIndex()
+}
+
+// Next moves to the next index record, if possible.
+// Otherwise, it moves to the next LHS record and the first index record.
+// If there are no more LHS records, or on an error, the method returns false.
+func (ijs *IndexJoinScan) Next() bool {
+	for ijs.hasLHS {
+		if ijs.idx.Next() {
+			rid, err := ijs.idx.GetDataRID()
+			if err != nil {
+				return ijs.stop(err)
+			}
+			if err := ijs.rhs.MoveToRid(*rid); err != nil {
+				return ijs.stop(err)
+			}
+			return true
+		}
+		if err := ijs.idx.Err(); err != nil {
+			return ijs.stop(err)
+		}
+
+		ijs.hasLHS = ijs.lhs.Next()
+		if ijs.hasLHS {
+			if err := ijs.resetIndex(); err != nil {
+				return ijs.stop(err)
+			}
+		}
+	}
+	return false
+}
+
+// Err returns the error that stopped Next, if any.
+func (ijs *IndexJoinScan) Err() error {
+	return ijs.err
+}
+
+func (ijs *IndexJoinScan) GetInt(fldname string) (int, error) {
+	if ijs.rhs.HasField(fldname) {
+		return ijs.rhs.GetInt(fldname)
+	}
+	return ijs.lhs.GetInt(fldname)
+}
+
+func (ijs *IndexJoinScan) GetString(fldname string) (string, error) {
+	if ijs.rhs.HasField(fldname) {
+		return ijs.rhs.GetString(fldname)
+	}
+	return ijs.lhs.GetString(fldname)
+}
+
+func (ijs *IndexJoinScan) GetVal(fldname string) (record.Constant, error) {
+	if ijs.rhs.HasField(fldname) {
+		return ijs.rhs.GetVal(fldname)
+	}
+	return ijs.lhs.GetVal(fldname)
+}
+
+func (ijs *IndexJoinScan) HasField(fldname string) bool {
+	return ijs.rhs.HasField(fldname) || ijs.lhs.HasField(fldname)
+}
+
+func (ijs *IndexJoinScan) Close() error {
+	return errors.Join(ijs.err, ijs.lhs.Close(), ijs.idx.Close(), ijs.rhs.Close())
+}
+
+func (ijs *IndexJoinScan) stop(err error) bool {
+	ijs.hasLHS = false
+	ijs.err = err
+	return false
+}
+
+func (ijs *IndexJoinScan) resetIndex() error {
+	searchkey, err := ijs.lhs.GetVal(ijs.joinfield)
+	if err != nil {
+		return err
+	}
+	return ijs.idx.BeforeFirst(&searchkey)
+}
@@ -0,0 +1,36 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexJoinScan(t *testing.T) {
+	tx := setupTest(t, 8)
+	students := testTable(t, tx, []string{"sid", "majorid"}, []any{1, 10}, []any{2, 20}, []any{3, 30})
+	depts := testTable(t, tx, []string{"did", "dname"}, []any{10, "math"}, []any{20, "art"}, []any{10, "maths"})
+	idx := &fakeIndex{records: indexRecords(t, depts, "did")}
+
+	s, err := NewIndexJoinScan(openTable(t, students), idx, "majorid", openTable(t, depts))
+	require.NoError(t, err)
+	expected := []string{"1 'math'", "1 'maths'", "2 'art'"}
+	require.Equal(t, expected, readRows(t, s, "sid", "dname"))
+	require.NoError(t, s.Err())
+
+	// an error of the index stops the join, even with records of lhs left
+	idx.read, idx.failAt = 0, 3
+	require.NoError(t, s.BeforeFirst())
+	require.Equal(t, expected[:2], readRows(t, s, "sid", "dname"))
+	require.ErrorContains(t, s.Err(), "fake index failure")
+	require.False(t, s.Next())
+
+	require.NoError(t, s.BeforeFirst())
+	require.Equal(t, expected, readRows(t, s, "sid", "dname"))
+	require.NoError(t, s.Err())
+
+	idx.read, idx.failAt = 0, 5
+	require.NoError(t, s.BeforeFirst())
+	require.Equal(t, expected[:3], readRows(t, s, "sid", "dname"))
+	require.ErrorContains(t, s.Close(), "fake index failure")
+}
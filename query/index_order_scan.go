@@ -0,0 +1,76 @@
+package query
+
+import (
+	"errors"
+	"math"
+
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ record.Scan = (*IndexOrderScan)(nil)
+
+// NewIndexOrderScan creates a scan of every record of ts in ascending order of the field indexed by idx,
+// whose values have type fldtype.
+func NewIndexOrderScan(ts *record.TableScan, idx *index.BTreeIndex, fldtype record.FieldType) (*IndexOrderScan, error) {
+	s := &IndexOrderScan{ts: ts, idx: idx, first: record.NewStringConstant("")}
+	// the smallest key of the index directory, searching for less finds no leaf
+	if fldtype == record.IntegerField {
+		s.first = record.NewIntConstant(math.MinInt32)
+	}
+	if err := s.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// IndexOrderScan reads a table by walking the leaves of a B-tree index from the smallest key on.
+type IndexOrderScan struct {
+	ts    *record.TableScan
+	idx   *index.BTreeIndex
+	first record.Constant
+}
+
+// BeforeFirst positions the index before its smallest key.
+func (s *IndexOrderScan) BeforeFirst() error {
+	return s.idx.BeforeFirst(&s.first)
+}
+
+// Next moves the table scan to the record of the next index record.
+func (s *IndexOrderScan) Next() bool {
+	ok, err := s.idx.NextInOrder()
+	if err != nil {
+		panic(err)
+	}
+	if !ok {
+		return false
+	}
+	rid, err := s.idx.GetDataRID()
+	if err != nil {
+		panic(err)
+	}
+	if err := s.ts.MoveToRid(*rid); err != nil {
+		panic(err)
+	}
+	return true
+}
+
+func (s *IndexOrderScan) GetInt(fldname string) (int, error) {
+	return s.ts.GetInt(fldname)
+}
+
+func (s *IndexOrderScan) GetString(fldname string) (string, error) {
+	return s.ts.GetString(fldname)
+}
+
+func (s *IndexOrderScan) GetVal(fldname string) (record.Constant, error) {
+	return s.ts.GetVal(fldname)
+}
+
+func (s *IndexOrderScan) HasField(fldname string) bool {
+	return s.ts.HasField(fldname)
+}
+
+func (s *IndexOrderScan) Close() error {
+	return errors.Join(s.idx.Close(), s.ts.Close())
+}
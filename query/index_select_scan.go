@@ -0,0 +1,80 @@
+package query
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ record.Scan = (*IndexSelectScan)(nil)
+
+// NewIndexSelectScan creates a scan of the records of ts whose indexed field equals val.
+func NewIndexSelectScan(ts *record.TableScan, idx index.Index, val record.Constant) (*IndexSelectScan, error) {
+	s := &IndexSelectScan{ts: ts, idx: idx, val: val}
+	if err := s.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// IndexSelectScan reads the records of a table that the index finds for a search key.
+// An error of the index or the table stops the scan, Err and Close return it.
+type IndexSelectScan struct {
+	ts  *record.TableScan
+	idx index.Index
+	val record.Constant
+	err error
+}
+
+// BeforeFirst positions the scan before the first record with the search key.
+func (s *IndexSelectScan) BeforeFirst() error {
+	s.err = nil
+	return s.idx.BeforeFirst(&s.val)
+}
+
+// Next moves the table scan to the record of the next index record.
+func (s *IndexSelectScan) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	if !s.idx.Next() {
+		s.err = s.idx.Err()
+		return false
+	}
+	rid, err := s.idx.GetDataRID()
+	if err != nil {
+		s.err = err
+		return false
+	}
+	if err := s.ts.MoveToRid(*rid); err != nil {
+		s.err = err
+		return false
+	}
+	return true
+}
+
+// Err returns the error that stopped Next, if any.
+func (s *IndexSelectScan) Err() error {
+	return s.err
+}
+
+func (s *IndexSelectScan) GetInt(fldname string) (int, error) {
+	return s.ts.GetInt(fldname)
+}
+
+func (s *IndexSelectScan) GetString(fldname string) (string, error) {
+	return s.ts.GetString(fldname)
+}
+
+func (s *IndexSelectScan) GetVal(fldname string) (record.Constant, error) {
+	return s.ts.GetVal(fldname)
+}
+
+func (s *IndexSelectScan) HasField(fldname string) bool {
+	return s.ts.HasField(fldname)
+}
+
+func (s *IndexSelectScan) Close() error {
+	return errors.Join(s.err, s.idx.Close(), s.ts.Close())
+}
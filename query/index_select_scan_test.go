@@ -0,0 +1,37 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexSelectScan(t *testing.T) {
+	tx := setupTest(t, 8)
+	students := testTable(t, tx, []string{"sid", "majorid"}, []any{1, 10}, []any{2, 20}, []any{3, 10}, []any{4, 10})
+	idx := &fakeIndex{records: indexRecords(t, students, "majorid")}
+
+	s, err := NewIndexSelectScan(openTable(t, students), idx, record.NewIntConstant(10))
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "3", "4"}, readRows(t, s, "sid"))
+	require.NoError(t, s.Err())
+
+	// an error of the index stops the scan, it is kept until BeforeFirst
+	idx.read, idx.failAt = 0, 2
+	require.NoError(t, s.BeforeFirst())
+	require.Equal(t, []string{"1"}, readRows(t, s, "sid"))
+	require.ErrorContains(t, s.Err(), "fake index failure")
+	require.False(t, s.Next())
+	require.ErrorContains(t, s.Err(), "fake index failure")
+
+	require.NoError(t, s.BeforeFirst())
+	require.Equal(t, []string{"1", "3", "4"}, readRows(t, s, "sid"))
+	require.NoError(t, s.Err())
+
+	// the scans above the index scan learn of the error when they close it
+	idx.read, idx.failAt = 0, 3
+	require.NoError(t, s.BeforeFirst())
+	require.Equal(t, []string{"1", "3"}, readRows(t, s, "sid"))
+	require.ErrorContains(t, s.Close(), "fake index failure")
+}
@@ -0,0 +1,76 @@
+package query
+
+import "github.com/kanthorlabs/kanthorkv/record"
+
+var _ record.Scan = (*LimitScan)(nil)
+
+// NewLimitScan creates a scan that skips the first offset records of s and returns at most limit of the next ones,
+// a negative limit returns all of them.
+func NewLimitScan(s record.Scan, limit, offset int) (*LimitScan, error) {
+	ls := &LimitScan{s: s, limit: limit, offset: offset}
+	if err := ls.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+// LimitScan stops reading s once it has returned limit records.
+type LimitScan struct {
+	s      record.Scan
+	limit  int
+	offset int
+	// returned counts the records returned since BeforeFirst, -1 until the offset is skipped
+	returned int
+	// done is set once s has no more records, calling s.Next again could start over on some scans
+	done bool
+}
+
+func (ls *LimitScan) BeforeFirst() error {
+	ls.returned, ls.done = -1, false
+	return ls.s.BeforeFirst()
+}
+
+// Next skips the offset on its first call, the records are only read when they are asked for.
+func (ls *LimitScan) Next() bool {
+	if ls.done {
+		return false
+	}
+	if ls.returned < 0 {
+		ls.returned = 0
+		for range ls.offset {
+			if !ls.s.Next() {
+				ls.done = true
+				return false
+			}
+		}
+	}
+	if ls.limit >= 0 && ls.returned >= ls.limit {
+		return false
+	}
+	if !ls.s.Next() {
+		ls.done = true
+		return false
+	}
+	ls.returned++
+	return true
+}
+
+func (ls *LimitScan) GetInt(fldname string) (int, error) {
+	return ls.s.GetInt(fldname)
+}
+
+func (ls *LimitScan) GetString(fldname string) (string, error) {
+	return ls.s.GetString(fldname)
+}
+
+func (ls *LimitScan) GetVal(fldname string) (record.Constant, error) {
+	return ls.s.GetVal(fldname)
+}
+
+func (ls *LimitScan) HasField(fldname string) bool {
+	return ls.s.HasField(fldname)
+}
+
+func (ls *LimitScan) Close() error {
+	return ls.s.Close()
+}
@@ -0,0 +1,49 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitScan(t *testing.T) {
+	tx := setupTest(t, 8)
+
+	rows := make([][]any, 0)
+	expected := make([]string, 0)
+	for i := range 10 {
+		rows = append(rows, []any{i})
+		expected = append(expected, fmt.Sprint(i))
+	}
+	nums := testTable(t, tx, []string{"n"}, rows...)
+
+	tests := []struct {
+		limit, offset int
+		want          []string
+		// reads is the number of records the scan pulls from its source
+		reads int
+	}{
+		{limit: 3, offset: 0, want: expected[:3], reads: 3},
+		{limit: 3, offset: 4, want: expected[4:7], reads: 7},
+		{limit: 0, offset: 2, want: []string{}, reads: 2},
+		{limit: 5, offset: 8, want: expected[8:], reads: 10},
+		{limit: 5, offset: 20, want: []string{}, reads: 10},
+		{limit: -1, offset: 6, want: expected[6:], reads: 10},
+	}
+	for _, tt := range tests {
+		src := &countingScan{Scan: openTable(t, nums)}
+		s, err := NewLimitScan(src, tt.limit, tt.offset)
+		require.NoError(t, err)
+
+		// a second pass after BeforeFirst returns the same records
+		for range 2 {
+			src.reads = 0
+			require.Equal(t, tt.want, readRows(t, s, "n"), "limit %d offset %d", tt.limit, tt.offset)
+			require.False(t, s.Next(), "limit %d offset %d", tt.limit, tt.offset)
+			require.Equal(t, tt.reads, src.reads, "limit %d offset %d", tt.limit, tt.offset)
+			require.NoError(t, s.BeforeFirst())
+		}
+		require.NoError(t, s.Close())
+	}
+}
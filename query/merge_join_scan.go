@@ -0,0 +1,117 @@
+package query
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ record.Scan = (*MergeJoinScan)(nil)
+
+// NewMergeJoinScan joins s1 sorted on fldname1 with s2 sorted on fldname2,
+// pairing the records whose join fields are equal.
+func NewMergeJoinScan(s1 record.Scan, s2 *SortScan, fldname1, fldname2 string) (*MergeJoinScan, error) {
+	mjs := &MergeJoinScan{s1: s1, s2: s2, fldname1: fldname1, fldname2: fldname2}
+	if err := mjs.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	return mjs, nil
+}
+
+type MergeJoinScan struct {
+	s1       record.Scan
+	s2       *SortScan
+	fldname1 string
+	fldname2 string
+	// joinval is the join value of the current group of s2 records, nil before the first match
+	joinval *record.Constant
+}
+
+// BeforeFirst positions the scan before the first record,
+// by positioning both underlying scans before their first records.
+func (mjs *MergeJoinScan) BeforeFirst() error {
+	mjs.joinval = nil
+	return errors.Join(mjs.s1.BeforeFirst(), mjs.s2.BeforeFirst())
+}
+
+// Next moves to the next s2 record with the current join value, if possible.
+// Otherwise, a next s1 record with the same join value goes through the saved group of s2 records again.
+// Otherwise, both scans move forward by the smaller join value until they meet on a new one.
+func (mjs *MergeJoinScan) Next() bool {
+	hasMore2 := mjs.s2.Next()
+	if hasMore2 && mjs.joinval != nil && mjs.value(mjs.s2, mjs.fldname2).Equal(*mjs.joinval) {
+		return true
+	}
+
+	hasMore1 := mjs.s1.Next()
+	if hasMore1 && mjs.joinval != nil && mjs.value(mjs.s1, mjs.fldname1).Equal(*mjs.joinval) {
+		if err := mjs.s2.RestorePosition(); err != nil {
+			panic(err)
+		}
+		return true
+	}
+
+	for hasMore1 && hasMore2 {
+		v1 := mjs.value(mjs.s1, mjs.fldname1)
+		v2 := mjs.value(mjs.s2, mjs.fldname2)
+		// NULL joins with nothing, it sorts first so both scans move past it
+		if v1.IsNull() {
+			hasMore1 = mjs.s1.Next()
+			continue
+		}
+		if v2.IsNull() {
+			hasMore2 = mjs.s2.Next()
+			continue
+		}
+		switch v1.Compare(v2) {
+		case -1:
+			hasMore1 = mjs.s1.Next()
+		case 1:
+			hasMore2 = mjs.s2.Next()
+		default:
+			if err := mjs.s2.SavePosition(); err != nil {
+				panic(err)
+			}
+			mjs.joinval = &v2
+			return true
+		}
+	}
+	return false
+}
+
+func (mjs *MergeJoinScan) GetInt(fldname string) (int, error) {
+	if mjs.s1.HasField(fldname) {
+		return mjs.s1.GetInt(fldname)
+	}
+	return mjs.s2.GetInt(fldname)
+}
+
+func (mjs *MergeJoinScan) GetString(fldname string) (string, error) {
+	if mjs.s1.HasField(fldname) {
+		return mjs.s1.GetString(fldname)
+	}
+	return mjs.s2.GetString(fldname)
+}
+
+func (mjs *MergeJoinScan) GetVal(fldname string) (record.Constant, error) {
+	if mjs.s1.HasField(fldname) {
+		return mjs.s1.GetVal(fldname)
+	}
+	return mjs.s2.GetVal(fldname)
+}
+
+func (mjs *MergeJoinScan) HasField(fldname string) bool {
+	return mjs.s1.HasField(fldname) || mjs.s2.HasField(fldname)
+}
+
+func (mjs *MergeJoinScan) Close() error {
+	return errors.Join(mjs.s1.Close(), mjs.s2.Close())
+}
+
+func (mjs *MergeJoinScan) value(s record.Scan, fldname string) record.Constant {
+	val, err := s.GetVal(fldname)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
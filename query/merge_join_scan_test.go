@@ -0,0 +1,61 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeJoinScan(t *testing.T) {
+	tx := setupTest(t, 8)
+
+	// both sides repeat their join values, s2 comes in two sorted runs
+	majors := make([]int, 0)
+	for range 60 {
+		majors = append(majors, fk.IntBetween(0, 12))
+	}
+	slices.Sort(majors)
+	students := make([][]any, 0)
+	for sid, major := range majors {
+		students = append(students, []any{sid, major})
+	}
+	type dept struct {
+		did   int
+		dname string
+	}
+	depts := make([]dept, 0)
+	runs := make([]*TempTable, 0)
+	for r := range 2 {
+		rows := make([][]any, 0)
+		for did := range 11 {
+			if fk.Bool() {
+				d := dept{did, fmt.Sprintf("d%d-%d", did, r)}
+				depts = append(depts, d)
+				rows = append(rows, []any{d.did, d.dname})
+			}
+		}
+		runs = append(runs, testTable(t, tx, []string{"did", "dname"}, rows...))
+	}
+
+	expected := make([]string, 0)
+	for sid, major := range majors {
+		for _, d := range depts {
+			if d.did == major {
+				expected = append(expected, fmt.Sprintf("%d '%s'", sid, d.dname))
+			}
+		}
+	}
+
+	s2, err := NewSortScan(runs, NewRecordComparator([]string{"did"}))
+	require.NoError(t, err)
+	s, err := NewMergeJoinScan(openTable(t, testTable(t, tx, []string{"sid", "majorid"}, students...)), s2, "majorid", "did")
+	require.NoError(t, err)
+
+	for range 2 {
+		require.ElementsMatch(t, expected, readRows(t, s, "sid", "dname"))
+		require.NoError(t, s.BeforeFirst())
+	}
+	require.NoError(t, s.Close())
+}
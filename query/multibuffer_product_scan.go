@@ -0,0 +1,124 @@
+package query
+
+import (
+	"errors"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+var _ record.Scan = (*MultibufferProductScan)(nil)
+
+// NewMultibufferProductScan creates the product of lhs and the table rhs,
+// reading rhs in chunks of as many blocks as the free buffers allow.
+func NewMultibufferProductScan(tx transaction.Transaction, lhs record.Scan, tblname string, layout *record.Layout) (*MultibufferProductScan, error) {
+	mps := &MultibufferProductScan{tx: tx, lhs: lhs, filename: tblname + ".tbl", layout: layout}
+	size, err := tx.Size(mps.filename)
+	if err != nil {
+		return nil, errors.Join(err, lhs.Close())
+	}
+	mps.filesize = size
+	mps.chunksize = BestFactor(tx.AvailableBuffs(), size)
+	if err := mps.BeforeFirst(); err != nil {
+		return nil, errors.Join(err, lhs.Close())
+	}
+	return mps, nil
+}
+
+// MultibufferProductScan is the product of a scan with a table, one chunk of the table at a time.
+// The left side is scanned once per chunk instead of the right side once per record.
+type MultibufferProductScan struct {
+	tx        transaction.Transaction
+	lhs       record.Scan
+	filename  string
+	layout    *record.Layout
+	filesize  int
+	chunksize int
+
+	prodscan *ProductScan
+	nextblk  int
+}
+
+// BeforeFirst positions the scan before the first record of the product with the first chunk.
+func (mps *MultibufferProductScan) BeforeFirst() error {
+	if err := mps.closeChunk(); err != nil {
+		return err
+	}
+	mps.nextblk = 0
+	_, err := mps.useNextChunk()
+	return err
+}
+
+// Next moves to the next record of the product with the current chunk,
+// then to the product with the next chunk.
+func (mps *MultibufferProductScan) Next() bool {
+	for mps.prodscan != nil {
+		if mps.prodscan.Next() {
+			return true
+		}
+		ok, err := mps.useNextChunk()
+		if err != nil {
+			panic(err)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return false
+}
+
+func (mps *MultibufferProductScan) GetInt(fldname string) (int, error) {
+	return mps.prodscan.GetInt(fldname)
+}
+
+func (mps *MultibufferProductScan) GetString(fldname string) (string, error) {
+	return mps.prodscan.GetString(fldname)
+}
+
+func (mps *MultibufferProductScan) GetVal(fldname string) (record.Constant, error) {
+	return mps.prodscan.GetVal(fldname)
+}
+
+func (mps *MultibufferProductScan) HasField(fldname string) bool {
+	return mps.lhs.HasField(fldname) || mps.layout.Schema().HasField(fldname)
+}
+
+func (mps *MultibufferProductScan) Close() error {
+	return errors.Join(mps.closeChunk(), mps.lhs.Close())
+}
+
+// useNextChunk pins the next chunk of the table, returning false once every chunk is used.
+func (mps *MultibufferProductScan) useNextChunk() (bool, error) {
+	if err := mps.closeChunk(); err != nil {
+		return false, err
+	}
+	if mps.nextblk >= mps.filesize {
+		return false, nil
+	}
+
+	end := min(mps.nextblk+mps.chunksize, mps.filesize) - 1
+	rhs, err := NewChunkScan(mps.tx, mps.filename, mps.layout, mps.nextblk, end)
+	if err != nil {
+		return false, err
+	}
+	mps.nextblk = end + 1
+
+	if err := mps.lhs.BeforeFirst(); err != nil {
+		return false, errors.Join(err, rhs.Close())
+	}
+	// the product closes the chunk, lhs stays open for the next one
+	mps.prodscan, err = NewProductScan(mps.lhs, rhs)
+	if err != nil {
+		return false, errors.Join(err, rhs.Close())
+	}
+	return true, nil
+}
+
+func (mps *MultibufferProductScan) closeChunk() error {
+	if mps.prodscan == nil {
+		return nil
+	}
+	err := mps.prodscan.s2.Close()
+	mps.prodscan = nil
+	return err
+}
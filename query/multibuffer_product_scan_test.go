@@ -0,0 +1,40 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultibufferProductScan(t *testing.T) {
+	// the right side takes several chunks of the few free buffers
+	tx := setupTest(t, 6)
+
+	students := make([][]any, 0)
+	for sid := range 15 {
+		students = append(students, []any{sid})
+	}
+	courses := make([][]any, 0)
+	for cid := range 100 {
+		courses = append(courses, []any{cid, fmt.Sprintf("c%d", cid)})
+	}
+	student := testTable(t, tx, []string{"sid"}, students...)
+	course := testTable(t, tx, []string{"cid", "title"}, courses...)
+
+	expected := make([]string, 0)
+	for sid := range 15 {
+		for cid := range 100 {
+			expected = append(expected, fmt.Sprintf("%d 'c%d'", sid, cid))
+		}
+	}
+
+	s, err := NewMultibufferProductScan(tx, openTable(t, student), course.TableName, course.Layout())
+	require.NoError(t, err)
+	require.Greater(t, s.filesize, s.chunksize)
+	for range 2 {
+		require.ElementsMatch(t, expected, readRows(t, s, "sid", "title"))
+		require.NoError(t, s.BeforeFirst())
+	}
+	require.NoError(t, s.Close())
+}
@@ -0,0 +1,116 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ record.Scan = (*OuterJoinScan)(nil)
+
+// NewOuterJoinScan creates the left outer join of s1 with s2: every pair of records that satisfies pred,
+// and every record of s1 that is in no such pair, once, with the fields of s2 NULL.
+func NewOuterJoinScan(s1, s2 record.Scan, pred *Predicate) (*OuterJoinScan, error) {
+	ojs := &OuterJoinScan{s1: s1, s2: s2, pred: pred}
+	if err := ojs.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	return ojs, nil
+}
+
+// OuterJoinScan goes through s2 for each record of s1, like ProductScan,
+// keeping the pairs that satisfy the predicate.
+type OuterJoinScan struct {
+	s1, s2 record.Scan
+	pred   *Predicate
+	// hasLHS tells whether s1 is positioned on a record
+	hasLHS bool
+	// matched tells whether the current record of s1 is in a pair already
+	matched bool
+	// padded is set while the current record is the record of s1 with the fields of s2 NULL
+	padded bool
+}
+
+func (ojs *OuterJoinScan) BeforeFirst() error {
+	if err := ojs.s1.BeforeFirst(); err != nil {
+		return err
+	}
+	ojs.hasLHS = ojs.s1.Next()
+	ojs.matched, ojs.padded = false, false
+	return ojs.s2.BeforeFirst()
+}
+
+// Next moves to the next record of s2 that pairs with the current record of s1.
+// When there is none and there was none before, the record of s1 comes padded with NULLs,
+// then the scan moves to the next record of s1.
+func (ojs *OuterJoinScan) Next() bool {
+	for ojs.hasLHS {
+		if !ojs.padded {
+			for ojs.s2.Next() {
+				// like SelectScan, a pair the predicate fails on is not kept
+				if ok, err := ojs.pred.IsSatisfied(ojs); err == nil && ok {
+					ojs.matched = true
+					return true
+				}
+			}
+			if !ojs.matched {
+				ojs.padded = true
+				return true
+			}
+		}
+
+		ojs.matched, ojs.padded = false, false
+		if err := ojs.s2.BeforeFirst(); err != nil {
+			panic(err)
+		}
+		ojs.hasLHS = ojs.s1.Next()
+	}
+	return false
+}
+
+func (ojs *OuterJoinScan) GetInt(fldname string) (int, error) {
+	if ojs.s1.HasField(fldname) {
+		return ojs.s1.GetInt(fldname)
+	}
+	if ojs.padded {
+		return 0, ojs.checkField(fldname)
+	}
+	return ojs.s2.GetInt(fldname)
+}
+
+func (ojs *OuterJoinScan) GetString(fldname string) (string, error) {
+	if ojs.s1.HasField(fldname) {
+		return ojs.s1.GetString(fldname)
+	}
+	if ojs.padded {
+		return "", ojs.checkField(fldname)
+	}
+	return ojs.s2.GetString(fldname)
+}
+
+func (ojs *OuterJoinScan) GetVal(fldname string) (record.Constant, error) {
+	if ojs.s1.HasField(fldname) {
+		return ojs.s1.GetVal(fldname)
+	}
+	if ojs.padded {
+		return record.Constant{}, ojs.checkField(fldname)
+	}
+	return ojs.s2.GetVal(fldname)
+}
+
+func (ojs *OuterJoinScan) HasField(fldname string) bool {
+	return ojs.s1.HasField(fldname) || ojs.s2.HasField(fldname)
+}
+
+func (ojs *OuterJoinScan) Close() error {
+	return errors.Join(ojs.s1.Close(), ojs.s2.Close())
+}
+
+// checkField makes sure a NULL padded field is a field of s2.
+func (ojs *OuterJoinScan) checkField(fldname string) error {
+	if !ojs.s2.HasField(fldname) {
+		return fmt.Errorf("field %s not found", fldname)
+	}
+	return nil
+}
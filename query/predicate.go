@@ -1,86 +1,254 @@
 package query
 
 import (
+	"math"
 	"strings"
 
 	"github.com/kanthorlabs/kanthorkv/record"
 )
 
+// kinds of predicate node
+const (
+	PRED_AND  = "AND"
+	PRED_OR   = "OR"
+	PRED_NOT  = "NOT"
+	PRED_TERM = "TERM"
+)
+
+// truth is a value of the three-valued logic of SQL, in which a comparison with NULL is unknown.
+// Its values are ordered, so AND takes the smallest value of its operands and OR the largest.
+type truth int
+
+const (
+	TRUTH_FALSE truth = iota
+	TRUTH_UNKNOWN
+	TRUTH_TRUE
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return TRUTH_TRUE
+	}
+	return TRUTH_FALSE
+}
+
+// NewPredicate creates the conjunction of terms, a predicate without terms is always satisfied.
 func NewPredicate(terms ...*Term) *Predicate {
-	return &Predicate{terms: terms}
+	p := &Predicate{kind: PRED_AND, preds: make([]*Predicate, 0, len(terms))}
+	for _, t := range terms {
+		p.preds = append(p.preds, &Predicate{kind: PRED_TERM, term: t})
+	}
+	return p
+}
+
+// NewOrPredicate creates the disjunction of preds.
+func NewOrPredicate(preds ...*Predicate) *Predicate {
+	p := &Predicate{kind: PRED_OR}
+	for _, pred := range preds {
+		// OR is associative, nested disjunctions are flattened
+		if pred.kind == PRED_OR {
+			p.preds = append(p.preds, pred.preds...)
+		} else {
+			p.preds = append(p.preds, pred)
+		}
+	}
+	return p
+}
+
+// NewNotPredicate creates the negation of pred.
+func NewNotPredicate(pred *Predicate) *Predicate {
+	return &Predicate{kind: PRED_NOT, preds: []*Predicate{pred}}
 }
 
+// Predicate is a tree of boolean operators over terms.
+// The root is a conjunction, so the planner can push each of its operands down on its own.
 type Predicate struct {
-	terms []*Term
+	kind string
+	// term is the comparison of a PRED_TERM leaf
+	term *Term
+	// preds are the operands of PRED_AND and PRED_OR, or the single operand of PRED_NOT
+	preds []*Predicate
 }
 
+// ConjoinWith adds the operands of predicate to the conjunction.
 func (p *Predicate) ConjoinWith(predicate *Predicate) {
-	p.terms = append(p.terms, predicate.terms...)
+	p.preds = append(p.preds, predicate.conjuncts()...)
+}
+
+// conjuncts returns the operands of a conjunction, or the predicate itself for any other node.
+func (p *Predicate) conjuncts() []*Predicate {
+	if p.kind == PRED_AND {
+		return p.preds
+	}
+	return []*Predicate{p}
 }
 
+// IsSatisfied tells whether the predicate is true, a record for which it is unknown is not satisfied either.
 func (p *Predicate) IsSatisfied(s record.Scan) (bool, error) {
-	for _, t := range p.terms {
-		satisfied, err := t.IsSatisfied(s)
-		if err != nil || !satisfied {
-			return false, err
+	result, err := p.evaluate(s)
+	return result == TRUTH_TRUE, err
+}
+
+func (p *Predicate) evaluate(s record.Scan) (truth, error) {
+	switch p.kind {
+	case PRED_TERM:
+		return p.term.evaluate(s)
+	case PRED_NOT:
+		result, err := p.preds[0].evaluate(s)
+		if err != nil {
+			return TRUTH_FALSE, err
 		}
+		return TRUTH_TRUE - result, nil
+	case PRED_OR:
+		result := TRUTH_FALSE
+		for _, pred := range p.preds {
+			r, err := pred.evaluate(s)
+			if err != nil {
+				return TRUTH_FALSE, err
+			}
+			if result = max(result, r); result == TRUTH_TRUE {
+				break
+			}
+		}
+		return result, nil
 	}
-	return true, nil
+	result := TRUTH_TRUE
+	for _, pred := range p.preds {
+		r, err := pred.evaluate(s)
+		if err != nil {
+			return TRUTH_FALSE, err
+		}
+		if result = min(result, r); result == TRUTH_FALSE {
+			break
+		}
+	}
+	return result, nil
 }
 
+// ReductionFactor estimates how many records of plan there are for each one that satisfies the predicate.
 func (p *Predicate) ReductionFactor(plan Plan) (int, error) {
-	factor := 1
-	for _, t := range p.terms {
-		f, err := t.ReductionFactor(plan)
+	sel, err := p.selectivity(plan)
+	if err != nil {
+		return 0, err
+	}
+	// a predicate nothing satisfies still leaves the planner a factor to divide by
+	return int(math.Round(1 / max(sel, 1e-9))), nil
+}
+
+// selectivity is the share of the records of plan that satisfy the predicate,
+// assuming its operands are independent.
+func (p *Predicate) selectivity(plan Plan) (float64, error) {
+	switch p.kind {
+	case PRED_TERM:
+		factor, err := p.term.ReductionFactor(plan)
 		if err != nil {
 			return 0, err
 		}
-		factor *= f
+		return 1 / float64(max(factor, 1)), nil
+	case PRED_NOT:
+		sel, err := p.preds[0].selectivity(plan)
+		return 1 - sel, err
+	case PRED_OR:
+		// a record fails a disjunction when it fails every operand
+		fail := 1.0
+		for _, pred := range p.preds {
+			sel, err := pred.selectivity(plan)
+			if err != nil {
+				return 0, err
+			}
+			fail *= 1 - sel
+		}
+		return 1 - fail, nil
 	}
-
-	return factor, nil
+	sel := 1.0
+	for _, pred := range p.preds {
+		s, err := pred.selectivity(plan)
+		if err != nil {
+			return 0, err
+		}
+		sel *= s
+	}
+	return sel, nil
 }
 
 // SelectSubPred returns the subpredicate that applies to the specified schema,
 // or nil if the predicate does not apply to the schema.
+// Only whole operands of the conjunction are taken, an OR or NOT applies when all its terms do.
 func (p *Predicate) SelectSubPred(sch *record.Schema) *Predicate {
 	result := NewPredicate()
-	for _, term := range p.terms {
-		if term.AppliesTo(sch) {
-			result.terms = append(result.terms, term)
+	for _, pred := range p.conjuncts() {
+		if pred.AppliesTo(sch) {
+			result.preds = append(result.preds, pred)
 		}
 	}
-	if len(result.terms) == 0 {
+	if len(result.preds) == 0 {
 		return nil
 	}
 	return result
 }
 
-// JoinSubPred returns the subpredicate consisting of terms that apply to
-// the union of the two specified schemas, but not to either
+// JoinSubPred returns the subpredicate consisting of the operands of the conjunction
+// that apply to the union of the two specified schemas, but not to either
 // schema separately.
 func (p *Predicate) JoinSubPred(sch1 *record.Schema, sch2 *record.Schema) *Predicate {
 	result := NewPredicate()
 	newsch := record.NewSchema()
 	newsch.AddAll(sch1)
 	newsch.AddAll(sch2)
-	for _, t := range p.terms {
-		if !t.AppliesTo(sch1) && !t.AppliesTo(sch2) && t.AppliesTo(newsch) {
-			result.terms = append(result.terms, t)
+	for _, pred := range p.conjuncts() {
+		if !pred.AppliesTo(sch1) && !pred.AppliesTo(sch2) && pred.AppliesTo(newsch) {
+			result.preds = append(result.preds, pred)
 		}
 	}
-	if len(result.terms) == 0 {
+	if len(result.preds) == 0 {
 		return nil
 	}
 	return result
 }
 
+// AppliesTo tells whether every term of the predicate can be evaluated on records of the schema.
+func (p *Predicate) AppliesTo(sch *record.Schema) bool {
+	if p.kind == PRED_TERM {
+		return p.term.AppliesTo(sch)
+	}
+	for _, pred := range p.preds {
+		if !pred.AppliesTo(sch) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve returns a copy of the predicate whose terms read the fields resolve names.
+func (p *Predicate) Resolve(resolve func(fldname string) (string, error)) (*Predicate, error) {
+	resolved := &Predicate{kind: p.kind, preds: make([]*Predicate, 0, len(p.preds))}
+	if p.kind == PRED_TERM {
+		term, err := p.term.Resolve(resolve)
+		if err != nil {
+			return nil, err
+		}
+		resolved.term = term
+	}
+	for _, pred := range p.preds {
+		r, err := pred.Resolve(resolve)
+		if err != nil {
+			return nil, err
+		}
+		resolved.preds = append(resolved.preds, r)
+	}
+	return resolved, nil
+}
+
 // EquatesWithConstant returns true if the predicate has a term of the form
 // "F=c" where F is a field name and c is a constant.
 // If so, the method returns the constant, otherwise it returns nil.
+// Only terms every satisfying record satisfies count, those that are operands of the conjunction.
 func (p *Predicate) EquatesWithConstant(fldname string) *record.Constant {
-	for _, t := range p.terms {
-		if c := t.EquatesWithConstant(fldname); c != nil {
+	for _, pred := range p.conjuncts() {
+		if pred.kind != PRED_TERM {
+			continue
+		}
+		if c := pred.term.EquatesWithConstant(fldname); c != nil {
 			return c
 		}
 	}
@@ -90,20 +258,42 @@ func (p *Predicate) EquatesWithConstant(fldname string) *record.Constant {
 // EquatesWithField returns true if the predicate has a term of the form
 // "F1=F2" where F1 is a field name and F2 is some other field name.
 // If so, the method returns the field name F2, otherwise it returns nil.
+// Like EquatesWithConstant, it only looks at the operands of the conjunction.
 func (p *Predicate) EquatesWithField(fldname string) *string {
-	for _, t := range p.terms {
-		if f := t.EquatesWithField(fldname); f != nil {
+	for _, pred := range p.conjuncts() {
+		if pred.kind != PRED_TERM {
+			continue
+		}
+		if f := pred.term.EquatesWithField(fldname); f != nil {
 			return f
 		}
 	}
 	return nil
 }
 
-// String returns a string representation of this predicate.
+// String returns a string representation of this predicate,
+// with the parentheses needed to parse it back: NOT binds tighter than AND, and AND than OR.
 func (p *Predicate) String() string {
-	terms := make([]string, len(p.terms))
-	for i, term := range p.terms {
-		terms[i] = term.String()
+	switch p.kind {
+	case PRED_TERM:
+		return p.term.String()
+	case PRED_NOT:
+		operand := p.preds[0]
+		for operand.kind == PRED_AND && len(operand.preds) == 1 {
+			operand = operand.preds[0]
+		}
+		if operand.kind == PRED_TERM || operand.kind == PRED_NOT {
+			return "NOT " + operand.String()
+		}
+		return "NOT (" + operand.String() + ")"
+	}
+
+	operands := make([]string, len(p.preds))
+	for i, pred := range p.preds {
+		operands[i] = pred.String()
+		if p.kind == PRED_AND && pred.kind == PRED_OR && len(p.preds) > 1 {
+			operands[i] = "(" + operands[i] + ")"
+		}
 	}
-	return strings.Join(terms, " AND ")
+	return strings.Join(operands, " "+p.kind+" ")
 }
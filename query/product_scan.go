@@ -10,7 +10,7 @@ import (
 var _ record.Scan = (*ProductScan)(nil)
 
 func NewProductScan(s1, s2 record.Scan) (*ProductScan, error) {
-	ps := &ProductScan{s1, s2}
+	ps := &ProductScan{s1: s1, s2: s2}
 	if err := ps.BeforeFirst(); err != nil {
 		return nil, err
 	}
@@ -19,6 +19,8 @@ func NewProductScan(s1, s2 record.Scan) (*ProductScan, error) {
 
 type ProductScan struct {
 	s1, s2 record.Scan
+	// hasLHS tells whether s1 is positioned on a record, an empty s1 makes an empty product
+	hasLHS bool
 }
 
 // BeforeFirst positions the scan before its first record.
@@ -29,7 +31,7 @@ func (ps *ProductScan) BeforeFirst() error {
 		return err
 	}
 
-	ps.s1.Next()
+	ps.hasLHS = ps.s1.Next()
 
 	if err := ps.s2.BeforeFirst(); err != nil {
 		return err
@@ -42,13 +44,17 @@ func (ps *ProductScan) BeforeFirst() error {
 // Otherwise, it moves to the next LHS record and the first RHS record.
 // If there are no more LHS records, the method returns false.
 func (ps *ProductScan) Next() bool {
+	if !ps.hasLHS {
+		return false
+	}
 	if ps.s2.Next() {
 		return true
 	}
 	if err := ps.s2.BeforeFirst(); err != nil {
 		return false
 	}
-	return ps.s2.Next() && ps.s1.Next()
+	ps.hasLHS = ps.s2.Next() && ps.s1.Next()
+	return ps.hasLHS
 }
 
 func (ps *ProductScan) GetInt(fldname string) (int, error) {
@@ -13,9 +13,26 @@ func NewProjectScan(s record.Scan, fields []string) (*ProjectScan, error) {
 	return &ProjectScan{s: s, fields: fields}, nil
 }
 
+// NewComputedProjectScan creates a scan whose field fields[i] is the value of exprs[i] on the records of s.
+func NewComputedProjectScan(s record.Scan, fields []string, exprs []*Expression) (*ProjectScan, error) {
+	if len(fields) != len(exprs) {
+		return nil, fmt.Errorf("%d fields but %d expressions", len(fields), len(exprs))
+	}
+	ps := &ProjectScan{s: s, fields: fields, exprs: make(map[string]*Expression)}
+	for i, expr := range exprs {
+		// a field read under its own name needs no evaluation
+		if expr.FieldName() == nil || *expr.FieldName() != fields[i] {
+			ps.exprs[fields[i]] = expr
+		}
+	}
+	return ps, nil
+}
+
 type ProjectScan struct {
 	s      record.Scan
 	fields []string
+	// exprs computes the fields that are not read from s as they are
+	exprs map[string]*Expression
 }
 
 func (ps *ProjectScan) BeforeFirst() error {
@@ -27,6 +44,13 @@ func (ps *ProjectScan) Next() bool {
 }
 
 func (ps *ProjectScan) GetInt(fldname string) (int, error) {
+	if expr, ok := ps.exprs[fldname]; ok {
+		val, err := expr.Evaluate(ps.s)
+		if err != nil || val.IsNull() {
+			return 0, err
+		}
+		return val.AsInt(), nil
+	}
 	if !ps.HasField(fldname) {
 		return 0, fmt.Errorf("field %s not found", fldname)
 	}
@@ -34,6 +58,13 @@ func (ps *ProjectScan) GetInt(fldname string) (int, error) {
 }
 
 func (ps *ProjectScan) GetString(fldname string) (string, error) {
+	if expr, ok := ps.exprs[fldname]; ok {
+		val, err := expr.Evaluate(ps.s)
+		if err != nil || val.IsNull() {
+			return "", err
+		}
+		return val.AsString(), nil
+	}
 	if !ps.HasField(fldname) {
 		return "", fmt.Errorf("field %s not found", fldname)
 	}
@@ -41,6 +72,9 @@ func (ps *ProjectScan) GetString(fldname string) (string, error) {
 }
 
 func (ps *ProjectScan) GetVal(fldname string) (record.Constant, error) {
+	if expr, ok := ps.exprs[fldname]; ok {
+		return expr.Evaluate(ps.s)
+	}
 	if !ps.HasField(fldname) {
 		return record.Constant{}, fmt.Errorf("field %s not found", fldname)
 	}
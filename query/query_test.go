@@ -0,0 +1,198 @@
+package query
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaswdr/faker/v2"
+	"github.com/kanthorlabs/kanthorkv/buffer"
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/index"
+	"github.com/kanthorlabs/kanthorkv/log"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx"
+	"github.com/kanthorlabs/kanthorkv/tx/concurrency"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBlockSize = 400
+	testMaxTime   = time.Second * 10
+	testLogFile   = "testlog"
+)
+
+var (
+	fk     faker.Faker
+	fkOnce sync.Once
+)
+
+func init() {
+	fkOnce.Do(func() {
+		fk = faker.New()
+	})
+}
+
+func testdir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "kanthorkv-test-")
+	require.NoError(t, err)
+	return dir
+}
+
+// setupTest starts a transaction on a fresh directory with small blocks and numbuffs buffers,
+// it is committed and the directory removed when the test ends
+func setupTest(t *testing.T, numbuffs int) transaction.Transaction {
+	dir := testdir(t)
+
+	fm, err := file.NewFileManager(dir, testBlockSize)
+	require.NoError(t, err)
+	lm, err := log.NewLogManager(fm, testLogFile)
+	require.NoError(t, err)
+	bm, err := buffer.NewBufferManager(fm, lm, numbuffs, testMaxTime)
+	require.NoError(t, err)
+	tx, err := tx.NewTransaction(fm, lm, bm, concurrency.NewLockTable())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, tx.Commit())
+		fm.Close()
+		os.RemoveAll(dir)
+	})
+	return tx
+}
+
+// testTable fills a temporary table with rows, whose values are ints or strings in the order of fields
+func testTable(t *testing.T, tx transaction.Transaction, fields []string, rows ...[]any) *TempTable {
+	sch := record.NewSchema()
+	for i, fldname := range fields {
+		if len(rows) > 0 {
+			if _, ok := rows[0][i].(string); ok {
+				sch.AddStringField(fldname, 10)
+				continue
+			}
+		}
+		sch.AddIntField(fldname)
+	}
+
+	tt := NewTempTable(tx, sch)
+	s, err := tt.Open()
+	require.NoError(t, err)
+	defer s.Close()
+	for _, row := range rows {
+		require.NoError(t, s.Insert())
+		for i, fldname := range fields {
+			switch v := row[i].(type) {
+			case int:
+				require.NoError(t, s.SetVal(fldname, record.NewIntConstant(v)))
+			case string:
+				require.NoError(t, s.SetVal(fldname, record.NewStringConstant(v)))
+			}
+		}
+	}
+	return tt
+}
+
+// openTable opens a scan of tt positioned before its first record
+func openTable(t *testing.T, tt *TempTable) *record.TableScan {
+	s, err := tt.Open()
+	require.NoError(t, err)
+	return s
+}
+
+// readRows reads the rest of s, a row holds the values of fields separated by spaces
+func readRows(t *testing.T, s record.Scan, fields ...string) []string {
+	rows := make([]string, 0)
+	for s.Next() {
+		row := make([]string, 0, len(fields))
+		for _, fldname := range fields {
+			val, err := s.GetVal(fldname)
+			require.NoError(t, err)
+			row = append(row, val.String())
+		}
+		rows = append(rows, strings.Join(row, " "))
+	}
+	return rows
+}
+
+// countingScan counts the records read from its scan
+type countingScan struct {
+	record.Scan
+	reads int
+}
+
+func (cs *countingScan) Next() bool {
+	if !cs.Scan.Next() {
+		return false
+	}
+	cs.reads++
+	return true
+}
+
+// fakeIndex finds the rids of its records for a search key, and fails at the record numbered failAt
+type fakeIndex struct {
+	records map[string][]record.RID
+	failAt  int
+	err     error
+
+	rids []record.RID
+	pos  int
+	read int
+}
+
+var _ index.Index = (*fakeIndex)(nil)
+
+func (fi *fakeIndex) SearchCost(numblocks, rpb int) int {
+	return 1
+}
+
+func (fi *fakeIndex) BeforeFirst(searchkey *record.Constant) error {
+	fi.rids, fi.pos, fi.err = fi.records[searchkey.String()], -1, nil
+	return nil
+}
+
+func (fi *fakeIndex) Next() bool {
+	fi.read++
+	if fi.read == fi.failAt {
+		fi.err = errors.New("fake index failure")
+		return false
+	}
+	fi.pos++
+	return fi.pos < len(fi.rids)
+}
+
+func (fi *fakeIndex) Err() error {
+	return fi.err
+}
+
+func (fi *fakeIndex) GetDataRID() (*record.RID, error) {
+	return &fi.rids[fi.pos], nil
+}
+
+func (fi *fakeIndex) Insert(dataval *record.Constant, datarid *record.RID) error {
+	return nil
+}
+
+func (fi *fakeIndex) Delete(dataval *record.Constant, datarid *record.RID) error {
+	return nil
+}
+
+func (fi *fakeIndex) Close() error {
+	return nil
+}
+
+// indexRecords maps the values of fldname in tt to the rids of their records, as an index on the field would
+func indexRecords(t *testing.T, tt *TempTable, fldname string) map[string][]record.RID {
+	records := make(map[string][]record.RID)
+	ts := openTable(t, tt)
+	defer ts.Close()
+	for ts.Next() {
+		val, err := ts.GetVal(fldname)
+		require.NoError(t, err)
+		records[val.String()] = append(records[val.String()], ts.GetRid())
+	}
+	return records
+}
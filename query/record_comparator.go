@@ -6,16 +6,36 @@ import (
 	"github.com/kanthorlabs/kanthorkv/record"
 )
 
+// SortField is a field records are sorted on, in ascending order unless Desc is set.
+type SortField struct {
+	Name string
+	Desc bool
+}
+
 type RecordComparator struct {
 	Fields []string
+	// Desc tells for each of Fields whether it is sorted in descending order
+	Desc []bool
 }
 
+// NewRecordComparator creates a comparator that sorts on fields in ascending order.
 func NewRecordComparator(fields []string) *RecordComparator {
-	return &RecordComparator{Fields: fields}
+	return &RecordComparator{Fields: fields, Desc: make([]bool, len(fields))}
+}
+
+// NewSortFieldComparator creates a comparator that sorts on each field in its own direction.
+func NewSortFieldComparator(sortFields []SortField) *RecordComparator {
+	rc := &RecordComparator{Fields: make([]string, 0, len(sortFields)), Desc: make([]bool, 0, len(sortFields))}
+	for _, sf := range sortFields {
+		rc.Fields = append(rc.Fields, sf.Name)
+		rc.Desc = append(rc.Desc, sf.Desc)
+	}
+	return rc
 }
 
+// Compare returns a negative number when the record of scan1 comes first, a positive one when it comes last.
 func (rc *RecordComparator) Compare(scan1, scan2 record.Scan) (int, error) {
-	for _, fieldName := range rc.Fields {
+	for i, fieldName := range rc.Fields {
 		val1, err := scan1.GetVal(fieldName)
 		if err != nil {
 			return 0, fmt.Errorf("scan1.GetVal(%s): %w", fieldName, err)
@@ -25,8 +45,8 @@ func (rc *RecordComparator) Compare(scan1, scan2 record.Scan) (int, error) {
 			return 0, fmt.Errorf("scan2.GetVal(%s): %w", fieldName, err)
 		}
 
-		if !val1.Equal(val2) {
-			return val1.Compare(val2), nil
+		if cmp := val1.Compare(val2); cmp != 0 {
+			return rc.direct(i, cmp), nil
 		}
 	}
 
@@ -34,7 +54,7 @@ func (rc *RecordComparator) Compare(scan1, scan2 record.Scan) (int, error) {
 }
 
 func (rc *RecordComparator) CompareMap(vals1, vals2 map[string]record.Constant) (int, error) {
-	for _, fieldName := range rc.Fields {
+	for i, fieldName := range rc.Fields {
 		val1, ok := vals1[fieldName]
 		if !ok {
 			return 0, fmt.Errorf("vals1 has no key %s", fieldName)
@@ -44,10 +64,18 @@ func (rc *RecordComparator) CompareMap(vals1, vals2 map[string]record.Constant)
 			return 0, fmt.Errorf("vals2 has no key %s", fieldName)
 		}
 
-		if !val1.Equal(val2) {
-			return val1.Compare(val2), nil
+		if cmp := val1.Compare(val2); cmp != 0 {
+			return rc.direct(i, cmp), nil
 		}
 	}
 
 	return 0, nil
 }
+
+// direct turns the comparison of the values of the i-th field into the order of their records.
+func (rc *RecordComparator) direct(i, cmp int) int {
+	if i < len(rc.Desc) && rc.Desc[i] {
+		return -cmp
+	}
+	return cmp
+}
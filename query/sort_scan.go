@@ -1,143 +1,178 @@
 package query
 
 import (
+	"container/heap"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/kanthorlabs/kanthorkv/record"
 )
 
+// NewSortScan creates a scan that merges sorted runs into a single sorted sequence.
+// Every run stays open for the life of the scan, so it pins one buffer per run.
 func NewSortScan(runs []*TempTable, comp *RecordComparator) (*SortScan, error) {
-	if !(len(runs) == 1 || len(runs) == 2) {
-		return nil, fmt.Errorf("runs must have 1 or 2 elements, but got %d", len(runs))
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("runs must have at least 1 element")
 	}
 
-	s1, err := runs[0].Open()
-	if err != nil {
-		return nil, err
-	}
-
-	var s2 record.UpdateScan
-	if len(runs) > 1 {
-		s2, err = runs[1].Open()
+	ss := &SortScan{comp: comp, scans: make([]record.UpdateScan, 0, len(runs))}
+	for _, run := range runs {
+		s, err := run.Open()
 		if err != nil {
-			return nil, err
+			return nil, errors.Join(err, ss.Close())
 		}
+		ss.scans = append(ss.scans, s)
 	}
+	ss.hasMore = make([]bool, len(ss.scans))
 
-	return &SortScan{
-		s1:          s1,
-		s2:          s2,
-		currentScan: nil,
-		comp:        comp,
-		hasMore1:    s1.Next(),
-		hasMore2:    s2.Next(),
-	}, nil
+	if err := ss.BeforeFirst(); err != nil {
+		return nil, errors.Join(err, ss.Close())
+	}
+	return ss, nil
 }
 
 var _ record.Scan = (*SortScan)(nil)
 
+// SortScan is a k-way merge of sorted runs.
+// The heap holds the index of every run positioned on a record that has not been returned yet,
+// smallest record first.
 type SortScan struct {
-	s1, s2             record.UpdateScan
-	currentScan        record.UpdateScan
-	comp               *RecordComparator
-	hasMore1, hasMore2 bool
-	savedPosition      []record.RID
+	scans   []record.UpdateScan
+	comp    *RecordComparator
+	hasMore []bool
+	heap    runHeap
+	// current is the run of the current record, -1 before the first one
+	current int
+	saved   *sortPosition
 }
 
-func (ss *SortScan) BeforeFirst() error {
-	var err error
-	if err = ss.s1.BeforeFirst(); err != nil {
-		return err
-	}
-	ss.hasMore1 = ss.s1.Next()
+// sortPosition is everything Next depends on, so restoring it repeats the same records
+type sortPosition struct {
+	rids    []record.RID
+	hasMore []bool
+	heap    []int
+	current int
+}
 
-	if ss.s2 != nil {
-		if err = ss.s2.BeforeFirst(); err != nil {
+// BeforeFirst positions every run at its first record, Next picks the smallest one.
+func (ss *SortScan) BeforeFirst() error {
+	ss.current = -1
+	ss.heap = runHeap{ss: ss, runs: make([]int, 0, len(ss.scans))}
+	for i, s := range ss.scans {
+		if err := s.BeforeFirst(); err != nil {
 			return err
 		}
-		ss.hasMore2 = ss.s2.Next()
+		ss.hasMore[i] = s.Next()
+		if ss.hasMore[i] {
+			ss.heap.runs = append(ss.heap.runs, i)
+		}
 	}
-
+	heap.Init(&ss.heap)
 	return nil
 }
 
+// Next moves the run of the current record forward, then picks the run with the smallest record.
+// Equal records come from the earlier run first.
 func (ss *SortScan) Next() bool {
-	if ss.currentScan == ss.s1 {
-		ss.hasMore1 = ss.s1.Next()
-	} else if ss.s2 != nil && ss.currentScan == ss.s2 {
-		ss.hasMore2 = ss.s2.Next()
+	if ss.current >= 0 {
+		ss.hasMore[ss.current] = ss.scans[ss.current].Next()
+		if ss.hasMore[ss.current] {
+			heap.Push(&ss.heap, ss.current)
+		}
 	}
 
-	if !ss.hasMore1 && !ss.hasMore2 {
+	if ss.heap.Len() == 0 {
+		ss.current = -1
 		return false
-	} else if ss.hasMore1 && ss.hasMore2 {
-		cmp, err := ss.comp.Compare(ss.s1, ss.s2)
-		if err != nil {
-			return false
-		}
-		if cmp < 0 {
-			ss.currentScan = ss.s1
-		} else {
-			ss.currentScan = ss.s2
-		}
-	} else if ss.hasMore1 {
-		ss.currentScan = ss.s1
-	} else if ss.hasMore2 {
-		ss.currentScan = ss.s2
 	}
-
+	ss.current = heap.Pop(&ss.heap).(int)
 	return true
 }
 
 func (ss *SortScan) Close() error {
-	err := ss.s1.Close()
-	if ss.s2 == nil {
-		return err
+	var errs []error
+	for _, s := range ss.scans {
+		errs = append(errs, s.Close())
 	}
-	return errors.Join(err, ss.s2.Close())
+	return errors.Join(errs...)
 }
 
 func (ss *SortScan) GetVal(fieldName string) (record.Constant, error) {
-	return ss.currentScan.GetVal(fieldName)
+	return ss.scans[ss.current].GetVal(fieldName)
 }
 
 func (ss *SortScan) GetInt(fieldName string) (int, error) {
-	return ss.currentScan.GetInt(fieldName)
+	return ss.scans[ss.current].GetInt(fieldName)
 }
 
 func (ss *SortScan) GetString(fieldName string) (string, error) {
-	return ss.currentScan.GetString(fieldName)
+	return ss.scans[ss.current].GetString(fieldName)
 }
 
+// HasField looks at the first run, every run has the same schema.
 func (ss *SortScan) HasField(fieldName string) bool {
-	return ss.currentScan.HasField(fieldName)
+	return ss.scans[0].HasField(fieldName)
 }
 
+// SavePosition remembers the current record, for RestorePosition to come back to.
 func (ss *SortScan) SavePosition() error {
-	rid1 := ss.s1.GetRid()
-	if ss.s2 != nil {
-		rid2 := ss.s2.GetRid()
-		ss.savedPosition = []record.RID{rid1, rid2}
-	} else {
-		ss.savedPosition = []record.RID{rid1}
+	ss.saved = &sortPosition{
+		rids:    make([]record.RID, 0, len(ss.scans)),
+		hasMore: slices.Clone(ss.hasMore),
+		heap:    slices.Clone(ss.heap.runs),
+		current: ss.current,
+	}
+	for _, s := range ss.scans {
+		ss.saved.rids = append(ss.saved.rids, s.GetRid())
 	}
-
 	return nil
 }
 
+// RestorePosition moves the scan back to the record of the last SavePosition.
 func (ss *SortScan) RestorePosition() error {
-	rid1 := ss.savedPosition[0]
-	if err := ss.s1.MoveToRid(rid1); err != nil {
-		return err
+	if ss.saved == nil {
+		return fmt.Errorf("no saved position")
 	}
-
-	if len(ss.savedPosition) > 1 {
-		rid2 := ss.savedPosition[1]
-		if err := ss.s2.MoveToRid(rid2); err != nil {
+	for i, s := range ss.scans {
+		if err := s.MoveToRid(ss.saved.rids[i]); err != nil {
 			return err
 		}
 	}
 
+	// the heap order only depends on the records the runs are positioned on, which are restored too
+	ss.hasMore = slices.Clone(ss.saved.hasMore)
+	ss.heap.runs = slices.Clone(ss.saved.heap)
+	ss.current = ss.saved.current
 	return nil
 }
+
+// runHeap orders runs by their current record.
+type runHeap struct {
+	ss   *SortScan
+	runs []int
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+
+func (h *runHeap) Less(i, j int) bool {
+	s1, s2 := h.ss.scans[h.runs[i]], h.ss.scans[h.runs[j]]
+	cmp, err := h.ss.comp.Compare(s1, s2)
+	if err != nil {
+		panic(err)
+	}
+	if cmp != 0 {
+		return cmp < 0
+	}
+	return h.runs[i] < h.runs[j]
+}
+
+func (h *runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+
+func (h *runHeap) Push(x any) { h.runs = append(h.runs, x.(int)) }
+
+func (h *runHeap) Pop() any {
+	last := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return last
+}
@@ -0,0 +1,61 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortScan(t *testing.T) {
+	tx := setupTest(t, 8)
+
+	// every run is sorted on grade descending, then sid
+	type rec struct{ sid, grade int }
+	recs := make([]rec, 0)
+	runs := make([]*TempTable, 0)
+	sid := 0
+	for _, size := range []int{40, 0, 1, 75} {
+		run := make([]rec, 0, size)
+		for range size {
+			run = append(run, rec{sid, fk.IntBetween(0, 9)})
+			sid++
+		}
+		slices.SortFunc(run, func(a, b rec) int {
+			if a.grade != b.grade {
+				return b.grade - a.grade
+			}
+			return a.sid - b.sid
+		})
+		rows := make([][]any, 0, size)
+		for _, r := range run {
+			rows = append(rows, []any{r.sid, r.grade})
+		}
+		runs = append(runs, testTable(t, tx, []string{"sid", "grade"}, rows...))
+		recs = append(recs, run...)
+	}
+
+	slices.SortFunc(recs, func(a, b rec) int {
+		if a.grade != b.grade {
+			return b.grade - a.grade
+		}
+		return a.sid - b.sid
+	})
+	expected := make([]string, 0)
+	for _, r := range recs {
+		expected = append(expected, fmt.Sprintf("%d %d", r.sid, r.grade))
+	}
+
+	comp := NewSortFieldComparator([]SortField{{Name: "grade", Desc: true}, {Name: "sid"}})
+	s, err := NewSortScan(runs, comp)
+	require.NoError(t, err)
+	for range 2 {
+		require.Equal(t, expected, readRows(t, s, "sid", "grade"))
+		require.NoError(t, s.BeforeFirst())
+	}
+	require.NoError(t, s.Close())
+
+	_, err = NewSortScan(nil, comp)
+	require.Error(t, err)
+}
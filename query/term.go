@@ -6,60 +6,133 @@ import (
 	"github.com/kanthorlabs/kanthorkv/record"
 )
 
+// comparison operators of a term
+const (
+	OP_EQ = "="
+	OP_NE = "<>"
+	OP_LT = "<"
+	OP_LE = "<="
+	OP_GT = ">"
+	OP_GE = ">="
+)
+
+// RANGE_REDUCTION_FACTOR is the share of records, one in three, assumed to satisfy a range comparison
+const RANGE_REDUCTION_FACTOR = 3
+
+// NewTerm creates a term that compares lhs and rhs for equality.
 func NewTerm(lhs *Expression, rhs *Expression) *Term {
-	return &Term{lhs: lhs, rhs: rhs}
+	return NewCompareTerm(lhs, OP_EQ, rhs)
+}
+
+// NewCompareTerm creates a term that compares lhs and rhs with one of the OP_ operators.
+func NewCompareTerm(lhs *Expression, op string, rhs *Expression) *Term {
+	return &Term{lhs: lhs, op: op, rhs: rhs}
 }
 
 type Term struct {
 	lhs *Expression
+	op  string
 	rhs *Expression
 }
 
+// IsSatisfied compares the values of both sides.
+// Values of different types are never equal, and cannot be ordered.
 func (t *Term) IsSatisfied(s record.Scan) (bool, error) {
+	result, err := t.evaluate(s)
+	return result == TRUTH_TRUE, err
+}
+
+// evaluate compares the values of both sides, the comparison of NULL with anything is unknown.
+func (t *Term) evaluate(s record.Scan) (truth, error) {
 	lhsval, err := t.lhs.Evaluate(s)
 	if err != nil {
-		return false, err
+		return TRUTH_FALSE, err
 	}
 	rhsval, err := t.rhs.Evaluate(s)
 	if err != nil {
-		return false, err
+		return TRUTH_FALSE, err
+	}
+	if lhsval.IsNull() || rhsval.IsNull() {
+		return TRUTH_UNKNOWN, nil
 	}
 
-	return lhsval.Equal(rhsval), nil
-
+	switch t.op {
+	case OP_EQ:
+		return truthOf(lhsval.Equal(rhsval)), nil
+	case OP_NE:
+		return truthOf(!lhsval.Equal(rhsval)), nil
+	}
+	if lhsval.Type() != rhsval.Type() {
+		return TRUTH_FALSE, fmt.Errorf("cannot compare %s and %s", lhsval, rhsval)
+	}
+	cmp := lhsval.Compare(rhsval)
+	switch t.op {
+	case OP_LT:
+		return truthOf(cmp < 0), nil
+	case OP_LE:
+		return truthOf(cmp <= 0), nil
+	case OP_GT:
+		return truthOf(cmp > 0), nil
+	case OP_GE:
+		return truthOf(cmp >= 0), nil
+	}
+	return TRUTH_FALSE, fmt.Errorf("unknown operator %s", t.op)
 }
 
+// ReductionFactor estimates how many records there are for each one that satisfies the term.
+// An equality keeps one value of the field out of its distinct values,
+// an inequality all of them but one, and a range RANGE_REDUCTION_FACTOR of them.
+// Nothing is known about the values of a computed expression, so comparing one is taken as a range.
 func (t *Term) ReductionFactor(p Plan) (int, error) {
-	if t.lhs.FieldName() != nil && t.rhs.FieldName() != nil {
-		lhsname := *t.lhs.FieldName()
-		rhsname := *t.rhs.FieldName()
-		return max(p.DistinctValues(lhsname), p.DistinctValues(rhsname)), nil
+	if t.AppliesTo(record.NewSchema()) {
+		// both sides are constant, the term keeps all records or none
+		if ok, err := t.IsSatisfied(nil); err == nil && ok {
+			return 1, nil
+		}
+		return 0, fmt.Errorf("cannot calculate reduction factor for term %s", t.String())
 	}
-
-	if t.lhs.FieldName() != nil {
-		lhsname := *t.lhs.FieldName()
-		return p.DistinctValues(lhsname), nil
+	if t.lhs.isComputed() || t.rhs.isComputed() {
+		if t.op == OP_NE {
+			return 1, nil
+		}
+		return RANGE_REDUCTION_FACTOR, nil
 	}
 
-	if t.rhs.FieldName() != nil {
-		rhsname := *t.rhs.FieldName()
-		return p.DistinctValues(rhsname), nil
-	}
-	if t.lhs.Constant() != nil && t.rhs.Constant() != nil {
-		if t.lhs.Constant().Equal(*t.rhs.Constant()) {
-			return 1, nil
+	switch t.op {
+	case OP_EQ:
+		return t.distinctValues(p), nil
+	case OP_NE:
+		// every value but one passes, which only makes a difference for a field of two values
+		if t.distinctValues(p) == 2 {
+			return 2, nil
 		}
+		return 1, nil
 	}
+	return RANGE_REDUCTION_FACTOR, nil
+}
 
-	return 0, fmt.Errorf("cannot calculate reduction factor for term %s", t.String())
+// distinctValues is the number of values the fields of the term can take,
+// the larger of both when it compares two fields.
+func (t *Term) distinctValues(p Plan) int {
+	dv := 1
+	if t.lhs.FieldName() != nil {
+		dv = max(dv, p.DistinctValues(*t.lhs.FieldName()))
+	}
+	if t.rhs.FieldName() != nil {
+		dv = max(dv, p.DistinctValues(*t.rhs.FieldName()))
+	}
+	return dv
 }
 
 func (t *Term) EquatesWithConstant(fldname string) *record.Constant {
-	if t.lhs.FieldName() != nil && *t.lhs.FieldName() == fldname && t.rhs.FieldName() != nil {
+	if t.op != OP_EQ {
+		return nil
+	}
+	if t.lhs.FieldName() != nil && *t.lhs.FieldName() == fldname && t.rhs.Constant() != nil {
 		return t.rhs.Constant()
 	}
 
-	if t.rhs.FieldName() != nil && *t.rhs.FieldName() == fldname && t.lhs.FieldName() != nil {
+	if t.rhs.FieldName() != nil && *t.rhs.FieldName() == fldname && t.lhs.Constant() != nil {
 		return t.lhs.Constant()
 	}
 
@@ -67,6 +140,9 @@ func (t *Term) EquatesWithConstant(fldname string) *record.Constant {
 }
 
 func (t *Term) EquatesWithField(fldname string) *string {
+	if t.op != OP_EQ {
+		return nil
+	}
 	if t.lhs.FieldName() != nil && *t.lhs.FieldName() == fldname && t.rhs.FieldName() != nil {
 		return t.rhs.FieldName()
 	}
@@ -82,6 +158,19 @@ func (t *Term) AppliesTo(sch *record.Schema) bool {
 	return t.lhs.AppliesTo(sch) && t.rhs.AppliesTo(sch)
 }
 
+// Resolve returns a copy of the term whose sides read the fields resolve names.
+func (t *Term) Resolve(resolve func(fldname string) (string, error)) (*Term, error) {
+	lhs, err := t.lhs.Resolve(resolve)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := t.rhs.Resolve(resolve)
+	if err != nil {
+		return nil, err
+	}
+	return NewCompareTerm(lhs, t.op, rhs), nil
+}
+
 func (t *Term) String() string {
-	return fmt.Sprintf("%s = %s", t.lhs.String(), t.rhs.String())
+	return fmt.Sprintf("%s %s %s", t.lhs.String(), t.op, t.rhs.String())
 }
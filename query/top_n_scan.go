@@ -0,0 +1,157 @@
+package query
+
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+
+	"github.com/kanthorlabs/kanthorkv/record"
+)
+
+var _ record.Scan = (*TopNScan)(nil)
+
+// NewTopNScan creates a scan of the first n records of s in the order of comp, with the values of fields.
+// It reads s once and closes it, holding no more than n records in memory at any time.
+func NewTopNScan(s record.Scan, comp *RecordComparator, n int, fields []string) (*TopNScan, error) {
+	records, err := selectTopN(s, comp, n, fields)
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &TopNScan{records: records, fields: fields}
+	if err := ts.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// TopNScan returns records kept in memory.
+type TopNScan struct {
+	records []topNRecord
+	fields  []string
+	// current is the position of the current record, -1 before the first one
+	current int
+}
+
+func (ts *TopNScan) BeforeFirst() error {
+	ts.current = -1
+	return nil
+}
+
+func (ts *TopNScan) Next() bool {
+	if ts.current < len(ts.records) {
+		ts.current++
+	}
+	return ts.current < len(ts.records)
+}
+
+func (ts *TopNScan) GetInt(fldname string) (int, error) {
+	val, err := ts.GetVal(fldname)
+	if err != nil || val.IsNull() {
+		return 0, err
+	}
+	return val.AsInt(), nil
+}
+
+func (ts *TopNScan) GetString(fldname string) (string, error) {
+	val, err := ts.GetVal(fldname)
+	if err != nil || val.IsNull() {
+		return "", err
+	}
+	return val.AsString(), nil
+}
+
+func (ts *TopNScan) GetVal(fldname string) (record.Constant, error) {
+	val, ok := ts.records[ts.current].vals[fldname]
+	if !ok {
+		return record.Constant{}, fmt.Errorf("field %s not found", fldname)
+	}
+	return val, nil
+}
+
+func (ts *TopNScan) HasField(fldname string) bool {
+	return slices.Contains(ts.fields, fldname)
+}
+
+// Close has nothing to release, the source is closed once its records are read.
+func (ts *TopNScan) Close() error {
+	return nil
+}
+
+// selectTopN reads every record of s and returns the first n in the order of comp.
+// The heap holds the best records read so far with the one that comes last on top,
+// a new record replaces it when it comes before it.
+func selectTopN(s record.Scan, comp *RecordComparator, n int, fields []string) ([]topNRecord, error) {
+	h := &topNHeap{comp: comp, records: make([]topNRecord, 0, n)}
+	if err := s.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	for seq := 0; n > 0 && s.Next(); seq++ {
+		rec := topNRecord{seq: seq, vals: make(map[string]record.Constant, len(fields))}
+		for _, fldname := range fields {
+			val, err := s.GetVal(fldname)
+			if err != nil {
+				return nil, err
+			}
+			rec.vals[fldname] = val
+		}
+
+		if h.Len() < n {
+			heap.Push(h, rec)
+		} else if h.after(h.records[0], rec) {
+			h.records[0] = rec
+			heap.Fix(h, 0)
+		}
+	}
+
+	records := h.records
+	slices.SortFunc(records, func(r1, r2 topNRecord) int {
+		if h.after(r1, r2) {
+			return 1
+		}
+		return -1
+	})
+	return records, nil
+}
+
+// topNRecord is a record held in memory, seq is its position in the source.
+type topNRecord struct {
+	seq  int
+	vals map[string]record.Constant
+}
+
+// topNHeap is a max-heap of records, the record that comes last is on top.
+type topNHeap struct {
+	comp    *RecordComparator
+	records []topNRecord
+}
+
+// after tells whether r1 comes after r2, of equal records the one read first comes first.
+func (h *topNHeap) after(r1, r2 topNRecord) bool {
+	// the plan checked that every sort field is in the schema
+	cmp, err := h.comp.CompareMap(r1.vals, r2.vals)
+	if err != nil {
+		panic(err)
+	}
+	if cmp != 0 {
+		return cmp > 0
+	}
+	return r1.seq > r2.seq
+}
+
+func (h *topNHeap) Len() int { return len(h.records) }
+
+func (h *topNHeap) Less(i, j int) bool { return h.after(h.records[i], h.records[j]) }
+
+func (h *topNHeap) Swap(i, j int) { h.records[i], h.records[j] = h.records[j], h.records[i] }
+
+func (h *topNHeap) Push(x any) { h.records = append(h.records, x.(topNRecord)) }
+
+func (h *topNHeap) Pop() any {
+	last := h.records[len(h.records)-1]
+	h.records = h.records[:len(h.records)-1]
+	return last
+}
@@ -0,0 +1,45 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopNScan(t *testing.T) {
+	tx := setupTest(t, 8)
+
+	// few distinct values, so most records tie with others
+	rows := make([][]any, 0)
+	type rec struct{ seq, v int }
+	recs := make([]rec, 0)
+	for seq := range 200 {
+		v := fk.IntBetween(0, 20)
+		rows = append(rows, []any{seq, v})
+		recs = append(recs, rec{seq, v})
+	}
+	enroll := testTable(t, tx, []string{"seq", "v"}, rows...)
+
+	// of equal records, the one read first comes first
+	slices.SortStableFunc(recs, func(a, b rec) int { return b.v - a.v })
+	expected := make([]string, 0)
+	for _, r := range recs {
+		expected = append(expected, fmt.Sprintf("%d %d", r.seq, r.v))
+	}
+
+	comp := NewSortFieldComparator([]SortField{{Name: "v", Desc: true}})
+	for _, n := range []int{0, 1, 17, 200, 300} {
+		s, err := NewTopNScan(openTable(t, enroll), comp, n, []string{"seq", "v"})
+		require.NoError(t, err)
+
+		for range 2 {
+			require.Equal(t, expected[:min(n, len(expected))], readRows(t, s, "seq", "v"), "n %d", n)
+			require.NoError(t, s.BeforeFirst())
+		}
+		require.True(t, s.HasField("v"))
+		require.False(t, s.HasField("w"))
+		require.NoError(t, s.Close())
+	}
+}
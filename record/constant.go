@@ -3,6 +3,7 @@ package record
 import (
 	"fmt"
 	"hash/fnv"
+	"strings"
 )
 
 func NewIntConstant(val int) Constant {
@@ -14,6 +15,7 @@ func NewStringConstant(val string) Constant {
 }
 
 // Constant represents a value in the database.
+// The zero Constant is NULL, the value of the fields of the missing side of an outer join.
 type Constant struct {
 	ival *int    // using pointer to represent nullable integer
 	sval *string // using pointer to represent nullable string
@@ -35,16 +37,32 @@ func (c Constant) AsString() string {
 	return *c.sval
 }
 
+// IsNull reports whether the constant is NULL.
+func (c Constant) IsNull() bool {
+	return c.ival == nil && c.sval == nil
+}
+
+// Type returns the type of the field the constant belongs to.
+// NULL belongs to fields of any type, it is reported as a string.
+func (c Constant) Type() FieldType {
+	if c.ival != nil {
+		return IntegerField
+	}
+	return StringField
+}
+
+// String returns the constant as SQL reads it, a string is quoted with its quotes written twice.
 func (c Constant) String() string {
 	if c.ival != nil {
 		return fmt.Sprintf("%d", *c.ival)
 	}
 	if c.sval != nil {
-		return fmt.Sprintf("'%s'", *c.sval)
+		return fmt.Sprintf("'%s'", strings.ReplaceAll(*c.sval, "'", "''"))
 	}
-	panic("Constant does not contain a value")
+	return "NULL"
 }
 
+// Equal reports whether both constants hold the same value, NULL is not equal to anything.
 func (c Constant) Equal(other Constant) bool {
 	if c.ival != nil && other.ival != nil {
 		return *c.ival == *other.ival
@@ -56,7 +74,18 @@ func (c Constant) Equal(other Constant) bool {
 }
 
 // Returns -1 if c < other, 0 if c == other, and 1 if c > other
+// NULL comes before every value, so it sorts first.
 func (c Constant) Compare(other Constant) int {
+	if c.IsNull() || other.IsNull() {
+		switch {
+		case !c.IsNull():
+			return 1
+		case !other.IsNull():
+			return -1
+		}
+		return 0
+	}
+
 	if c.ival != nil && other.ival != nil {
 		if *c.ival < *other.ival {
 			return -1
@@ -89,5 +118,6 @@ func (c Constant) Hash() int {
 		return int(h.Sum32())
 	}
 
-	panic("Constant does not contain a value")
+	// NULL never matches, any bucket will do
+	return 0
 }
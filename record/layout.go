@@ -5,17 +5,19 @@ import (
 )
 
 func NewLayout(sch *Schema, offsets map[string]int, slotsize int) *Layout {
-	return &Layout{sch, offsets, slotsize}
+	return &Layout{sch, offsets, slotsize, nullBits(sch)}
 }
 
 func NewLayoutOfSchema(sch *Schema) *Layout {
-	l := &Layout{sch, make(map[string]int), 0}
+	l := &Layout{sch, make(map[string]int), 0, nullBits(sch)}
 
-	pos := file.BLOCK_SIZE
+	// leave room for the empty/inuse flag at the beginning of each slot
+	pos := file.INT_SIZE
 	for _, fldname := range sch.Fields() {
 		l.offsets[fldname] = pos
 		pos += l.LengthInBytes(fldname)
 	}
+	l.slotsize = pos
 
 	return l
 }
@@ -25,6 +27,8 @@ type Layout struct {
 	sch      *Schema
 	offsets  map[string]int
 	slotsize int
+	// nullbits are the bits of the slot flag that mark each field NULL
+	nullbits map[string]int
 }
 
 func (l *Layout) Schema() *Schema {
@@ -47,3 +51,41 @@ func (l *Layout) LengthInBytes(fldname string) int {
 
 	return file.MaxLength(l.sch.Length(fldname))
 }
+
+// Rename returns the layout of the same records, with each field of renames read under its new name.
+// A table listed twice in a query reads a record under a different name for each listing.
+func (l *Layout) Rename(renames map[string]string) *Layout {
+	sch := NewSchema()
+	renamed := &Layout{sch, make(map[string]int), l.slotsize, make(map[string]int)}
+	for _, fldname := range l.sch.Fields() {
+		newname, ok := renames[fldname]
+		if !ok {
+			newname = fldname
+		}
+		sch.AddField(newname, l.sch.Type(fldname), l.sch.Length(fldname))
+		renamed.offsets[newname] = l.offsets[fldname]
+		if bit, ok := l.nullbits[fldname]; ok {
+			renamed.nullbits[newname] = bit
+		}
+	}
+	return renamed
+}
+
+// NullBit returns the bit of the slot flag that is set when the field is NULL,
+// false when the field is not among the first NULLABLE_FIELDS fields and cannot be NULL.
+func (l *Layout) NullBit(fldname string) (int, bool) {
+	bit, ok := l.nullbits[fldname]
+	return bit, ok
+}
+
+// the lowest bit of the slot flag tells whether the slot is used, the fields take the next ones
+func nullBits(sch *Schema) map[string]int {
+	bits := make(map[string]int)
+	for i, fldname := range sch.Fields() {
+		if i == NULLABLE_FIELDS {
+			break
+		}
+		bits[fldname] = 1 << (i + 1)
+	}
+	return bits
+}
@@ -1,6 +1,8 @@
 package record
 
 import (
+	"fmt"
+
 	"github.com/kanthorlabs/kanthorkv/file"
 	"github.com/kanthorlabs/kanthorkv/tx/transaction"
 )
@@ -12,6 +14,10 @@ const (
 	RecordUsed
 )
 
+// NULLABLE_FIELDS is the number of fields of a record that can be NULL, they are the first fields of the schema.
+// The flag of a slot is an integer, its lowest bit is the RecordFlag and the next ones mark those fields NULL.
+const NULLABLE_FIELDS = 30
+
 func NewRecordPage(tx transaction.Transaction, blk *file.BlockId, layout *Layout) *RecordPage {
 	tx.Pin(blk)
 	return &RecordPage{
@@ -38,6 +44,9 @@ func (rp *RecordPage) GetInt(slot int, fldname string) (int, error) {
 }
 
 func (rp *RecordPage) SetInt(slot int, fldname string, val int) error {
+	if err := rp.setNullBit(slot, fldname, false); err != nil {
+		return err
+	}
 	fldpos := rp.offset(slot) + rp.layout.Offset(fldname)
 	return rp.tx.SetInt(rp.blk, fldpos, val, true)
 }
@@ -48,10 +57,56 @@ func (rp *RecordPage) GetString(slot int, fldname string) (string, error) {
 }
 
 func (rp *RecordPage) SetString(slot int, fldname string, val string) error {
+	if err := rp.setNullBit(slot, fldname, false); err != nil {
+		return err
+	}
 	fldpos := rp.offset(slot) + rp.layout.Offset(fldname)
 	return rp.tx.SetString(rp.blk, fldpos, val, true)
 }
 
+// IsNull tells whether the field of the record in slot is NULL.
+func (rp *RecordPage) IsNull(slot int, fldname string) (bool, error) {
+	bit, ok := rp.layout.NullBit(fldname)
+	if !ok {
+		return false, nil
+	}
+	flag, err := rp.tx.GetInt(rp.blk, rp.offset(slot))
+	return flag&bit != 0, err
+}
+
+// SetNull makes the field of the record in slot NULL, reading it as an integer or a string gives the zero value.
+func (rp *RecordPage) SetNull(slot int, fldname string) error {
+	if _, ok := rp.layout.NullBit(fldname); !ok {
+		return fmt.Errorf("field %s cannot be NULL, only the first %d fields of a record can", fldname, NULLABLE_FIELDS)
+	}
+	var err error
+	if rp.layout.sch.Type(fldname) == IntegerField {
+		err = rp.SetInt(slot, fldname, 0)
+	} else {
+		err = rp.SetString(slot, fldname, "")
+	}
+	if err != nil {
+		return err
+	}
+	return rp.setNullBit(slot, fldname, true)
+}
+
+// setNullBit marks the field of the record in slot NULL or not, the flag is only written when it changes.
+func (rp *RecordPage) setNullBit(slot int, fldname string, null bool) error {
+	bit, ok := rp.layout.NullBit(fldname)
+	if !ok {
+		return nil
+	}
+	flag, err := rp.tx.GetInt(rp.blk, rp.offset(slot))
+	if err != nil {
+		return err
+	}
+	if (flag&bit != 0) == null {
+		return nil
+	}
+	return rp.tx.SetInt(rp.blk, rp.offset(slot), flag^bit, true)
+}
+
 func (rp *RecordPage) Delete(slot int) error {
 	return rp.setFlag(slot, RecordEmpty)
 }
@@ -77,8 +132,8 @@ func (rp *RecordPage) NextAfter(slot int) int {
 }
 
 func (rp *RecordPage) InsertAfter(slot int) int {
-	newslot := rp.SearchAfter(slot, RecordUsed)
-	if newslot > 0 {
+	newslot := rp.SearchAfter(slot, RecordEmpty)
+	if newslot >= 0 {
 		rp.setFlag(newslot, RecordUsed)
 	}
 	return newslot
@@ -92,7 +147,7 @@ func (rp *RecordPage) SearchAfter(slot int, flag RecordFlag) int {
 			panic(err)
 		}
 
-		if slotflag == int(flag) {
+		if slotflag&int(RecordUsed) == int(flag) {
 			return slot
 		}
 
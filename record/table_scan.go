@@ -13,11 +13,12 @@ type Scan interface {
 	// Next moves the scan to the next record.
 	// Returns false if there is no next record.
 	Next() bool
-	// GetInt returns the value of the specified integer field in the current record.
+	// GetInt returns the value of the specified integer field in the current record, 0 when it is NULL.
 	GetInt(fldname string) (int, error)
-	// GetString returns the value of the specified string field in the current record.
+	// GetString returns the value of the specified string field in the current record, "" when it is NULL.
 	GetString(fldname string) (string, error)
-	// GetVal returns the value of the specified field in the current record as a Constant.
+	// GetVal returns the value of the specified field in the current record as a Constant,
+	// the only one of the getters that tells NULL apart.
 	GetVal(fldname string) (Constant, error)
 	// HasField returns true if the scan has a field with the specified name.
 	HasField(fldname string) bool
@@ -56,9 +57,12 @@ func NewTableScan(tx transaction.Transaction, tblname string, layout *Layout) (*
 		return nil, err
 	}
 	if size == 0 {
-		ts.moveToNewBlock()
+		err = ts.moveToNewBlock()
 	} else {
-		ts.moveToBlock(0)
+		err = ts.moveToBlock(0)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	return ts, nil
@@ -80,7 +84,8 @@ func (ts *TableScan) BeforeFirst() error {
 
 func (ts *TableScan) Next() bool {
 	ts.currentslot = ts.rp.NextAfter(ts.currentslot)
-	if ts.currentslot < 0 {
+	// keep moving forward because blocks in the middle might be empty
+	for ts.currentslot < 0 {
 		if ts.atLastBlock() {
 			return false
 		}
@@ -104,6 +109,10 @@ func (ts *TableScan) GetString(fldname string) (string, error) {
 }
 
 func (ts *TableScan) GetVal(fldname string) (Constant, error) {
+	null, err := ts.rp.IsNull(ts.currentslot, fldname)
+	if err != nil || null {
+		return Constant{}, err
+	}
 	if ts.layout.Schema().Type(fldname) == IntegerField {
 		i, err := ts.GetInt(fldname)
 		if err != nil {
@@ -124,7 +133,13 @@ func (ts *TableScan) HasField(fldname string) bool {
 }
 
 func (ts *TableScan) Close() error {
-	return ts.tx.Unpin(ts.rp.Block())
+	if ts.rp == nil {
+		return nil
+	}
+	// a second Close must not release a pin that belongs to another scan of the block
+	blk := ts.rp.Block()
+	ts.rp = nil
+	return ts.tx.Unpin(blk)
 }
 
 func (ts *TableScan) SetInt(fldname string, val int) error {
@@ -136,6 +151,9 @@ func (ts *TableScan) SetString(fldname string, val string) error {
 }
 
 func (ts *TableScan) SetVal(fldname string, val Constant) error {
+	if val.IsNull() {
+		return ts.rp.SetNull(ts.currentslot, fldname)
+	}
 	if ts.layout.Schema().Type(fldname) == IntegerField {
 		return ts.SetInt(fldname, val.AsInt())
 	}
@@ -146,10 +164,14 @@ func (ts *TableScan) SetVal(fldname string, val Constant) error {
 func (ts *TableScan) Insert() error {
 	ts.currentslot = ts.rp.InsertAfter(ts.currentslot)
 	for ts.currentslot < 0 {
+		var err error
 		if ts.atLastBlock() {
-			ts.moveToNewBlock()
+			err = ts.moveToNewBlock()
 		} else {
-			ts.moveToBlock(ts.rp.Block().Number() + 1)
+			err = ts.moveToBlock(ts.rp.Block().Number() + 1)
+		}
+		if err != nil {
+			return err
 		}
 		ts.currentslot = ts.rp.InsertAfter(ts.currentslot)
 	}
@@ -167,7 +189,7 @@ func (ts *TableScan) MoveToRid(rid RID) error {
 
 	blk := file.NewBlockId(ts.filename, rid.BlockNumber())
 	ts.rp = NewRecordPage(ts.tx, blk, ts.layout)
-	ts.currentslot = -1
+	ts.currentslot = rid.Slot
 	return nil
 }
 
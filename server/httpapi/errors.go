@@ -0,0 +1,79 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/tx/concurrency"
+)
+
+var basename = "KANTHORKV.HTTPAPI"
+
+func Errf(err string, args ...string) error {
+	return fmt.Errorf("%s.%s: %s", basename, err, strings.Join(args, " | "))
+}
+
+// Wrapf describes a sentinel error with args, errors.Is still finds the sentinel.
+func Wrapf(sentinel error, args ...string) error {
+	return fmt.Errorf("%w: %s", sentinel, strings.Join(args, " | "))
+}
+
+var (
+	// ErrRequestInvalid is a request body that is not a statement to run.
+	ErrRequestInvalid = errors.New(basename + ".REQUEST.BAD")
+	// ErrTxNotExist is a transaction id the server does not know, or no longer knows.
+	ErrTxNotExist = errors.New(basename + ".TX.NOT_FOUND")
+)
+
+func ErrBadRequest(reason string) error {
+	args := []string{
+		fmt.Sprintf("reason=%s", reason),
+	}
+	return Wrapf(ErrRequestInvalid, args...)
+}
+
+func ErrTxNotFound(id string) error {
+	args := []string{
+		fmt.Sprintf("tx=%s", id),
+	}
+	return Wrapf(ErrTxNotExist, args...)
+}
+
+func ErrPanic(v any) error {
+	if err, ok := v.(error); ok {
+		return err
+	}
+	return Errf("STATEMENT.PANIC", fmt.Sprintf("panic=%v", v))
+}
+
+// codes of the error object of a response
+const (
+	CODE_BAD_REQUEST     = "BAD_REQUEST"
+	CODE_SYNTAX_ERROR    = "SYNTAX_ERROR"
+	CODE_LOCK_ABORT      = "LOCK_ABORT"
+	CODE_TABLE_NOT_FOUND = "TABLE_NOT_FOUND"
+	CODE_TX_NOT_FOUND    = "TX_NOT_FOUND"
+	CODE_INTERNAL        = "INTERNAL"
+)
+
+// classify maps an error to its response code and HTTP status.
+func classify(err error) (string, int) {
+	var syntaxErr *parser.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return CODE_SYNTAX_ERROR, http.StatusBadRequest
+	case errors.Is(err, concurrency.ErrLockAborted):
+		return CODE_LOCK_ABORT, http.StatusConflict
+	case errors.Is(err, metadata.ErrTableNotExist):
+		return CODE_TABLE_NOT_FOUND, http.StatusNotFound
+	case errors.Is(err, ErrTxNotExist):
+		return CODE_TX_NOT_FOUND, http.StatusNotFound
+	case errors.Is(err, ErrRequestInvalid):
+		return CODE_BAD_REQUEST, http.StatusBadRequest
+	}
+	return CODE_INTERNAL, http.StatusInternalServerError
+}
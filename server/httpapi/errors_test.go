@@ -0,0 +1,36 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/tx/concurrency"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	blk := file.NewBlockId("student.tbl", 0)
+	tests := []struct {
+		err    error
+		code   string
+		status int
+	}{
+		{concurrency.ErrLockAbort(blk), CODE_LOCK_ABORT, http.StatusConflict},
+		{errors.Join(errors.New("rollback"), fmt.Errorf("select: %w", concurrency.ErrLockAbort(blk))), CODE_LOCK_ABORT, http.StatusConflict},
+		{metadata.ErrTableNotFound("student"), CODE_TABLE_NOT_FOUND, http.StatusNotFound},
+		{ErrTxNotFound("abc"), CODE_TX_NOT_FOUND, http.StatusNotFound},
+		{ErrBadRequest("sql is required"), CODE_BAD_REQUEST, http.StatusBadRequest},
+		// the text of an error is not its kind, a value that looks like a code stays internal
+		{errors.New(metadata.ErrTableNotExist.Error()), CODE_INTERNAL, http.StatusInternalServerError},
+		{ErrBadRequest(concurrency.ErrLockAborted.Error()), CODE_BAD_REQUEST, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		code, status := classify(tt.err)
+		require.Equal(t, tt.code, code, tt.err.Error())
+		require.Equal(t, tt.status, status, tt.err.Error())
+	}
+}
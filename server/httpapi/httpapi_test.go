@@ -0,0 +1,69 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaswdr/faker/v2"
+	"github.com/kanthorlabs/kanthorkv"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fk     faker.Faker
+	fkOnce sync.Once
+)
+
+func init() {
+	fkOnce.Do(func() {
+		fk = faker.New()
+	})
+}
+
+func testdir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "kanthorkv-test-")
+	require.NoError(t, err)
+	return dir
+}
+
+// testserver serves a fresh database over loopback HTTP until the test ends
+func testserver(t *testing.T, idleTimeout time.Duration) string {
+	_, _, url := testserverOf(t, idleTimeout)
+	return url
+}
+
+// testserverOf is testserver that also returns the database and the server, for the tests that use them directly
+func testserverOf(t *testing.T, idleTimeout time.Duration) (*kanthorkv.DB, *Server, string) {
+	dir := testdir(t)
+	opts := kanthorkv.DefaultOptions()
+	opts.LockTimeout = 500 * time.Millisecond
+	db, err := kanthorkv.Open(dir, opts)
+	require.NoError(t, err)
+
+	srv := NewServer(db, idleTimeout)
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		ts.Close()
+		require.NoError(t, srv.Close())
+		require.NoError(t, db.Close())
+		os.RemoveAll(dir)
+	})
+	return db, srv, ts.URL
+}
+
+// post sends body as JSON and decodes the response into out, returning the status code
+func post(t *testing.T, url string, body any, out any) int {
+	raw, err := json.Marshal(body)
+	require.NoError(t, err)
+	res, err := http.Post(url, "application/json", bytes.NewReader(raw))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+	return res.StatusCode
+}
@@ -0,0 +1,356 @@
+// Package httpapi serves a KanthorKV database as JSON over HTTP.
+//
+//	POST /sql                {"sql": "select ...", "tx": "<optional id>"}
+//	POST /tx                 starts a transaction and returns its id
+//	POST /tx/{id}/commit
+//	POST /tx/{id}/rollback
+//
+// A statement without a tx id runs in a transaction of its own.
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kanthorlabs/kanthorkv"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// MAX_BODY_SIZE caps the size of a request body
+const MAX_BODY_SIZE = 1 << 20
+
+// DEFAULT_TX_IDLE_TIMEOUT is how long an explicit transaction may go unused before it is rolled back,
+// a client that disappears must not keep its locks forever
+const DEFAULT_TX_IDLE_TIMEOUT = time.Minute
+
+// NewServer creates the handler of the API for db.
+func NewServer(db *kanthorkv.DB, idleTimeout time.Duration) *Server {
+	if idleTimeout <= 0 {
+		idleTimeout = DEFAULT_TX_IDLE_TIMEOUT
+	}
+	srv := &Server{
+		db:          db,
+		idleTimeout: idleTimeout,
+		txs:         make(map[string]*txn),
+		mux:         http.NewServeMux(),
+	}
+	srv.mux.HandleFunc("POST /sql", srv.handleSQL)
+	srv.mux.HandleFunc("POST /tx", srv.handleBegin)
+	srv.mux.HandleFunc("POST /tx/{id}/commit", srv.handleCommit)
+	srv.mux.HandleFunc("POST /tx/{id}/rollback", srv.handleRollback)
+	return srv
+}
+
+var _ http.Handler = (*Server)(nil)
+
+// Server is an http.Handler, it keeps the explicit transactions of every client.
+type Server struct {
+	db          *kanthorkv.DB
+	idleTimeout time.Duration
+	mux         *http.ServeMux
+
+	mu  sync.Mutex
+	txs map[string]*txn
+}
+
+// txn is an explicit transaction, mu serializes the requests that use it
+type txn struct {
+	mu    sync.Mutex
+	tx    transaction.Transaction
+	timer *time.Timer
+	done  bool
+}
+
+// SQLRequest is the body of POST /sql.
+type SQLRequest struct {
+	SQL string `json:"sql"`
+	Tx  string `json:"tx,omitempty"`
+}
+
+// SQLResponse holds the rows of a query, keyed by the fields of its schema,
+// or the number of records an update statement affected.
+type SQLResponse struct {
+	Fields   []Field          `json:"fields,omitempty"`
+	Rows     []map[string]any `json:"rows,omitempty"`
+	Affected *int             `json:"affected,omitempty"`
+}
+
+// Field describes a field of the schema of a query.
+type Field struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Length int    `json:"length,omitempty"`
+}
+
+// TxResponse is the body returned by the transaction endpoints.
+type TxResponse struct {
+	Tx     string `json:"tx"`
+	Status string `json:"status"`
+}
+
+// ErrorResponse is the body of every failed request.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mux.ServeHTTP(w, r)
+}
+
+// Close rolls back every transaction that is still open.
+func (srv *Server) Close() error {
+	srv.mu.Lock()
+	txs := srv.txs
+	srv.txs = make(map[string]*txn)
+	srv.mu.Unlock()
+
+	var errs []error
+	for _, t := range txs {
+		errs = append(errs, t.finish(false))
+	}
+	return errors.Join(errs...)
+}
+
+func (srv *Server) handleSQL(w http.ResponseWriter, r *http.Request) {
+	var req SQLRequest
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_BODY_SIZE)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, ErrBadRequest(err.Error()))
+		return
+	}
+	if strings.TrimSpace(req.SQL) == "" {
+		writeError(w, ErrBadRequest("sql is required"))
+		return
+	}
+
+	if req.Tx == "" {
+		res, err := srv.autocommit(req.SQL)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+
+	t, err := srv.lookup(req.Tx)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		writeError(w, ErrTxNotFound(req.Tx))
+		return
+	}
+
+	// a statement that runs longer than the idle timeout must not be rolled back under the client
+	if !t.timer.Stop() {
+		// the timer fired before the statement came in, it is rolling the transaction back
+		writeError(w, ErrTxNotFound(req.Tx))
+		return
+	}
+	res, err := srv.run(t.tx, req.SQL)
+	if err != nil {
+		// the transaction cannot be trusted after a failure, let go of its locks right away
+		srv.forget(req.Tx)
+		writeError(w, errors.Join(err, t.finishLocked(false)))
+		return
+	}
+	t.timer.Reset(srv.idleTimeout)
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (srv *Server) handleBegin(w http.ResponseWriter, r *http.Request) {
+	tx, err := srv.db.NewTx()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	id := rand.Text()
+
+	// the transaction is registered before its timer is armed, so a timer that fires right away forgets it,
+	// and no one finishes it before it has a timer
+	t := &txn{tx: tx}
+	t.mu.Lock()
+	srv.mu.Lock()
+	srv.txs[id] = t
+	srv.mu.Unlock()
+	t.timer = time.AfterFunc(srv.idleTimeout, func() {
+		srv.forget(id)
+		t.finish(false)
+	})
+	t.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, TxResponse{Tx: id, Status: "active"})
+}
+
+func (srv *Server) handleCommit(w http.ResponseWriter, r *http.Request) {
+	srv.end(w, r.PathValue("id"), true)
+}
+
+func (srv *Server) handleRollback(w http.ResponseWriter, r *http.Request) {
+	srv.end(w, r.PathValue("id"), false)
+}
+
+func (srv *Server) end(w http.ResponseWriter, id string, commit bool) {
+	t, err := srv.lookup(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	srv.forget(id)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		writeError(w, ErrTxNotFound(id))
+		return
+	}
+	if err := t.finishLocked(commit); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	status := "rolled_back"
+	if commit {
+		status = "committed"
+	}
+	writeJSON(w, http.StatusOK, TxResponse{Tx: id, Status: status})
+}
+
+func (srv *Server) lookup(id string) (*txn, error) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t, ok := srv.txs[id]
+	if !ok {
+		return nil, ErrTxNotFound(id)
+	}
+	return t, nil
+}
+
+func (srv *Server) forget(id string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	delete(srv.txs, id)
+}
+
+func (srv *Server) autocommit(sql string) (*SQLResponse, error) {
+	t, err := srv.db.NewTx()
+	if err != nil {
+		return nil, err
+	}
+	res, err := srv.run(t, sql)
+	if err != nil {
+		return nil, errors.Join(err, t.Rollback())
+	}
+	return res, t.Commit()
+}
+
+// run executes a statement, a panic of the engine is reported as an error.
+func (srv *Server) run(t transaction.Transaction, sql string) (res *SQLResponse, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = ErrPanic(v)
+		}
+	}()
+
+	if !parser.IsQuery(sql) {
+		affected, err := srv.db.Exec(t, sql)
+		if err != nil {
+			return nil, err
+		}
+		return &SQLResponse{Affected: &affected}, nil
+	}
+
+	p, err := srv.db.Planner().CreateQueryPlan(sql, t)
+	if err != nil {
+		return nil, err
+	}
+	s, err := p.Open()
+	if err != nil {
+		return nil, err
+	}
+	// a scan that stopped on an error returns it when closed
+	defer func() {
+		err = errors.Join(err, s.Close())
+	}()
+
+	sch := p.Schema()
+	res = &SQLResponse{Fields: fields(sch), Rows: make([]map[string]any, 0)}
+	for s.Next() {
+		row := make(map[string]any, len(sch.Fields()))
+		for _, fldname := range sch.Fields() {
+			val, err := s.GetVal(fldname)
+			if err != nil {
+				return nil, err
+			}
+			// NULL is encoded as null
+			switch {
+			case val.IsNull():
+				row[fldname] = nil
+			case sch.Type(fldname) == record.IntegerField:
+				row[fldname] = val.AsInt()
+			default:
+				row[fldname] = val.AsString()
+			}
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	return res, nil
+}
+
+func (t *txn) finish(commit bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return nil
+	}
+	return t.finishLocked(commit)
+}
+
+func (t *txn) finishLocked(commit bool) error {
+	t.done = true
+	t.timer.Stop()
+	if commit {
+		return t.tx.Commit()
+	}
+	return t.tx.Rollback()
+}
+
+func fields(sch *record.Schema) []Field {
+	fields := make([]Field, 0, len(sch.Fields()))
+	for _, fldname := range sch.Fields() {
+		f := Field{Name: fldname, Type: sch.Type(fldname).String()}
+		if sch.Type(fldname) == record.StringField {
+			f.Length = sch.Length(fldname)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code, status := classify(err)
+	writeJSON(w, status, ErrorResponse{Error: ErrorBody{Code: code, Message: err.Error()}})
+}
@@ -0,0 +1,155 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_SQL(t *testing.T) {
+	url := testserver(t, 0)
+
+	var res SQLResponse
+	require.Equal(t, http.StatusOK, post(t, url+"/sql", SQLRequest{SQL: "create table student (sid int, sname varchar(10))"}, &res))
+
+	names := make([]string, 10)
+	for i := range names {
+		names[i] = fk.RandomStringWithLength(8)
+		res = SQLResponse{}
+		sql := fmt.Sprintf("insert into student (sid, sname) values (%d, '%s')", i, names[i])
+		require.Equal(t, http.StatusOK, post(t, url+"/sql", SQLRequest{SQL: sql}, &res))
+		require.Equal(t, 1, *res.Affected)
+	}
+
+	res = SQLResponse{}
+	require.Equal(t, http.StatusOK, post(t, url+"/sql", SQLRequest{SQL: "select sid, sname from student"}, &res))
+	require.Equal(t, []Field{{Name: "sid", Type: "INT"}, {Name: "sname", Type: "VARCHAR", Length: 10}}, res.Fields)
+	require.Len(t, res.Rows, len(names))
+	for _, row := range res.Rows {
+		// JSON numbers come back as float64
+		require.Equal(t, names[int(row["sid"].(float64))], row["sname"])
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	url := testserver(t, 0)
+
+	var res ErrorResponse
+	require.Equal(t, http.StatusBadRequest, post(t, url+"/sql", SQLRequest{SQL: "select from where"}, &res))
+	require.Equal(t, CODE_SYNTAX_ERROR, res.Error.Code)
+
+	res = ErrorResponse{}
+	require.Equal(t, http.StatusNotFound, post(t, url+"/sql", SQLRequest{SQL: "select a from missing"}, &res))
+	require.Equal(t, CODE_TABLE_NOT_FOUND, res.Error.Code)
+
+	res = ErrorResponse{}
+	require.Equal(t, http.StatusBadRequest, post(t, url+"/sql", map[string]any{"sql": 1}, &res))
+	require.Equal(t, CODE_BAD_REQUEST, res.Error.Code)
+
+	res = ErrorResponse{}
+	require.Equal(t, http.StatusNotFound, post(t, url+"/sql", SQLRequest{SQL: "select a from t", Tx: "nope"}, &res))
+	require.Equal(t, CODE_TX_NOT_FOUND, res.Error.Code)
+
+	res = ErrorResponse{}
+	require.Equal(t, http.StatusNotFound, post(t, url+"/tx/nope/commit", nil, &res))
+	require.Equal(t, CODE_TX_NOT_FOUND, res.Error.Code)
+}
+
+func TestServer_Transaction(t *testing.T) {
+	url := testserver(t, 0)
+
+	var res SQLResponse
+	require.Equal(t, http.StatusOK, post(t, url+"/sql", SQLRequest{SQL: "create table t (a int)"}, &res))
+
+	var tx TxResponse
+	require.Equal(t, http.StatusOK, post(t, url+"/tx", nil, &tx))
+	require.NotEmpty(t, tx.Tx)
+	require.Equal(t, http.StatusOK, post(t, url+"/sql", SQLRequest{SQL: "insert into t (a) values (1)", Tx: tx.Tx}, &res))
+
+	// the uncommitted insert holds an exclusive lock other transactions give up on
+	var errres ErrorResponse
+	require.Equal(t, http.StatusConflict, post(t, url+"/sql", SQLRequest{SQL: "select a from t"}, &errres))
+	require.Equal(t, CODE_LOCK_ABORT, errres.Error.Code)
+
+	require.Equal(t, http.StatusOK, post(t, url+"/tx/"+tx.Tx+"/rollback", nil, &tx))
+	require.Equal(t, "rolled_back", tx.Status)
+
+	res = SQLResponse{}
+	require.Equal(t, http.StatusOK, post(t, url+"/sql", SQLRequest{SQL: "select a from t"}, &res))
+	require.Empty(t, res.Rows)
+
+	require.Equal(t, http.StatusOK, post(t, url+"/tx", nil, &tx))
+	require.Equal(t, http.StatusOK, post(t, url+"/sql", SQLRequest{SQL: "insert into t (a) values (2)", Tx: tx.Tx}, &res))
+	require.Equal(t, http.StatusOK, post(t, url+"/tx/"+tx.Tx+"/commit", nil, &tx))
+	require.Equal(t, "committed", tx.Status)
+
+	res = SQLResponse{}
+	require.Equal(t, http.StatusOK, post(t, url+"/sql", SQLRequest{SQL: "select a from t"}, &res))
+	require.Equal(t, []map[string]any{{"a": float64(2)}}, res.Rows)
+
+	// a failed statement ends its transaction
+	require.Equal(t, http.StatusOK, post(t, url+"/tx", nil, &tx))
+	errres = ErrorResponse{}
+	require.Equal(t, http.StatusBadRequest, post(t, url+"/sql", SQLRequest{SQL: "select from", Tx: tx.Tx}, &errres))
+	errres = ErrorResponse{}
+	require.Equal(t, http.StatusNotFound, post(t, url+"/tx/"+tx.Tx+"/commit", nil, &errres))
+	require.True(t, strings.Contains(errres.Error.Message, tx.Tx))
+}
+
+func TestServer_IdleTimeout(t *testing.T) {
+	url := testserver(t, 100*time.Millisecond)
+
+	var tx TxResponse
+	require.Equal(t, http.StatusOK, post(t, url+"/tx", nil, &tx))
+	time.Sleep(300 * time.Millisecond)
+
+	var res ErrorResponse
+	require.Equal(t, http.StatusNotFound, post(t, url+"/tx/"+tx.Tx+"/commit", nil, &res))
+	require.Equal(t, CODE_TX_NOT_FOUND, res.Error.Code)
+}
+
+func TestServer_IdleTimeoutOnBegin(t *testing.T) {
+	// the timer fires as soon as it is armed, the rolled back transaction must not stay registered
+	_, srv, url := testserverOf(t, time.Nanosecond)
+
+	for range 20 {
+		var tx TxResponse
+		require.Equal(t, http.StatusOK, post(t, url+"/tx", nil, &tx))
+	}
+	require.Eventually(t, func() bool {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		return len(srv.txs) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServer_IdleTimeoutDuringStatement(t *testing.T) {
+	db, _, url := testserverOf(t, 100*time.Millisecond)
+
+	var res SQLResponse
+	require.Equal(t, http.StatusOK, post(t, url+"/sql", SQLRequest{SQL: "create table t (a int)"}, &res))
+
+	// another transaction holds the lock of the table longer than the idle timeout
+	other, err := db.NewTx()
+	require.NoError(t, err)
+	_, err = db.Exec(other, "insert into t (a) values (1)")
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		other.Commit()
+	}()
+
+	var tx TxResponse
+	require.Equal(t, http.StatusOK, post(t, url+"/tx", nil, &tx))
+	require.Equal(t, http.StatusOK, post(t, url+"/sql", SQLRequest{SQL: "select a from t", Tx: tx.Tx}, &res))
+	require.Len(t, res.Rows, 1)
+
+	// the statement kept the transaction alive while it waited
+	var end TxResponse
+	require.Equal(t, http.StatusOK, post(t, url+"/tx/"+tx.Tx+"/commit", nil, &end))
+	require.Equal(t, "committed", end.Status)
+}
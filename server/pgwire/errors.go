@@ -0,0 +1,101 @@
+package pgwire
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/tx/concurrency"
+)
+
+var basename = "KANTHORKV.PGWIRE"
+
+func Errf(err string, args ...string) error {
+	return fmt.Errorf("%s.%s: %s", basename, err, strings.Join(args, " | "))
+}
+
+// Wrapf describes a sentinel error with args, errors.Is still finds the sentinel.
+func Wrapf(sentinel error, args ...string) error {
+	return fmt.Errorf("%w: %s", sentinel, strings.Join(args, " | "))
+}
+
+var (
+	// ErrMessageUnsupported is a frontend message the session does not serve.
+	ErrMessageUnsupported = errors.New(basename + ".SESSION.UNSUPPORTED_MESSAGE")
+	// ErrTxActive is a BEGIN inside a transaction block.
+	ErrTxActive = errors.New(basename + ".SESSION.TX_IN_PROGRESS")
+	// ErrTxFailed is a statement of a transaction block that an error aborted.
+	ErrTxFailed = errors.New(basename + ".SESSION.TX_ABORTED")
+)
+
+func ErrServerClosed() error {
+	return Errf("SERVER.CLOSED")
+}
+
+func ErrUnsupportedProtocol(version int) error {
+	args := []string{
+		fmt.Sprintf("version=%d", version),
+	}
+	return Errf("STARTUP.UNSUPPORTED_PROTOCOL", args...)
+}
+
+func ErrUnsupportedMessage(typ byte) error {
+	args := []string{
+		fmt.Sprintf("type=%c", typ),
+	}
+	return Wrapf(ErrMessageUnsupported, args...)
+}
+
+func ErrMessageTooLarge(length int) error {
+	args := []string{
+		fmt.Sprintf("length=%d", length),
+	}
+	return Errf("SESSION.MESSAGE_TOO_LARGE", args...)
+}
+
+func ErrTxInProgress() error {
+	return Wrapf(ErrTxActive)
+}
+
+func ErrTxAborted() error {
+	return Wrapf(ErrTxFailed, "commands ignored until end of transaction block")
+}
+
+func ErrPanic(v any) error {
+	if err, ok := v.(error); ok {
+		return err
+	}
+	return Errf("SESSION.PANIC", fmt.Sprintf("panic=%v", v))
+}
+
+// SQLSTATE codes sent back in an ErrorResponse
+const (
+	codeSyntaxError       = "42601"
+	codeUndefinedTable    = "42P01"
+	codeLockNotAvailable  = "55P03"
+	codeInFailedTx        = "25P02"
+	codeActiveTx          = "25001"
+	codeProtocolViolation = "08P01"
+	codeInternalError     = "XX000"
+)
+
+func sqlstate(err error) string {
+	var syntaxErr *parser.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return codeSyntaxError
+	case errors.Is(err, concurrency.ErrLockAborted):
+		return codeLockNotAvailable
+	case errors.Is(err, metadata.ErrTableNotExist):
+		return codeUndefinedTable
+	case errors.Is(err, ErrTxFailed):
+		return codeInFailedTx
+	case errors.Is(err, ErrTxActive):
+		return codeActiveTx
+	case errors.Is(err, ErrMessageUnsupported):
+		return codeProtocolViolation
+	}
+	return codeInternalError
+}
@@ -0,0 +1,30 @@
+package pgwire
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kanthorlabs/kanthorkv/file"
+	"github.com/kanthorlabs/kanthorkv/metadata"
+	"github.com/kanthorlabs/kanthorkv/tx/concurrency"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLState(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{concurrency.ErrLockAbort(file.NewBlockId("student.tbl", 0)), codeLockNotAvailable},
+		{fmt.Errorf("select: %w", metadata.ErrTableNotFound("student")), codeUndefinedTable},
+		{ErrTxAborted(), codeInFailedTx},
+		{errors.Join(ErrTxInProgress(), errors.New("rollback")), codeActiveTx},
+		{ErrUnsupportedMessage('P'), codeProtocolViolation},
+		// the text of an error is not its kind
+		{errors.New(ErrTxFailed.Error()), codeInternalError},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.code, sqlstate(tt.err), tt.err.Error())
+	}
+}
@@ -0,0 +1,153 @@
+package pgwire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// protocol codes sent in place of a version by the first message of a connection
+const (
+	PROTOCOL_VERSION = 196608 // 3.0
+	SSL_REQUEST      = 80877103
+	GSSENC_REQUEST   = 80877104
+	CANCEL_REQUEST   = 80877102
+)
+
+// MAX_MESSAGE_SIZE caps the body of a frontend message so a broken client cannot make us allocate gigabytes
+const MAX_MESSAGE_SIZE = 1 << 24
+
+// type OIDs of pg_type that the field types of a schema are reported as
+const (
+	OID_INT4    = 23
+	OID_VARCHAR = 1043
+)
+
+// transaction status reported by ReadyForQuery
+const (
+	TX_IDLE   = 'I'
+	TX_ACTIVE = 'T'
+	TX_FAILED = 'E'
+)
+
+// message is a backend message being built, the length is filled in when it is sent
+type message struct {
+	typ  byte
+	body []byte
+}
+
+func newMessage(typ byte) *message {
+	return &message{typ: typ, body: make([]byte, 0, 64)}
+}
+
+func (m *message) byte(b byte) *message {
+	m.body = append(m.body, b)
+	return m
+}
+
+func (m *message) int16(v int) *message {
+	m.body = binary.BigEndian.AppendUint16(m.body, uint16(v))
+	return m
+}
+
+func (m *message) int32(v int) *message {
+	m.body = binary.BigEndian.AppendUint32(m.body, uint32(v))
+	return m
+}
+
+func (m *message) cstring(s string) *message {
+	m.body = append(m.body, s...)
+	m.body = append(m.body, 0)
+	return m
+}
+
+// bytes writes a length prefixed value, nil is sent as SQL NULL
+func (m *message) bytes(b []byte) *message {
+	if b == nil {
+		return m.int32(-1)
+	}
+	m.int32(len(b))
+	m.body = append(m.body, b...)
+	return m
+}
+
+func (m *message) writeTo(w *bufio.Writer) error {
+	if err := w.WriteByte(m.typ); err != nil {
+		return err
+	}
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(m.body)+4))
+	if _, err := w.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(m.body)
+	return err
+}
+
+// readStartup reads the untyped first message of a connection.
+func readStartup(r *bufio.Reader) (int, []byte, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(body) < 4 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	return int(binary.BigEndian.Uint32(body)), body[4:], nil
+}
+
+// readMessage reads a typed frontend message.
+func readMessage(r *bufio.Reader) (byte, []byte, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	body, err := readBody(r)
+	return typ, body, err
+}
+
+func readBody(r *bufio.Reader) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	n := int(int32(binary.BigEndian.Uint32(length[:]))) - 4
+	if n < 0 || n > MAX_MESSAGE_SIZE {
+		return nil, ErrMessageTooLarge(n)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// parseParams decodes the name/value pairs of a startup message.
+func parseParams(body []byte) map[string]string {
+	params := make(map[string]string)
+	fields := make([]string, 0)
+	start := 0
+	for i, b := range body {
+		if b == 0 {
+			if i == start {
+				break
+			}
+			fields = append(fields, string(body[start:i]))
+			start = i + 1
+		}
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		params[fields[i]] = fields[i+1]
+	}
+	return params
+}
+
+// cstring returns the body of a message holding a single null terminated string.
+func cstring(body []byte) string {
+	for i, b := range body {
+		if b == 0 {
+			return string(body[:i])
+		}
+	}
+	return string(body)
+}
@@ -0,0 +1,145 @@
+package pgwire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/jaswdr/faker/v2"
+	"github.com/kanthorlabs/kanthorkv"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fk     faker.Faker
+	fkOnce sync.Once
+)
+
+func init() {
+	fkOnce.Do(func() {
+		fk = faker.New()
+	})
+}
+
+func testdir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "kanthorkv-test-")
+	require.NoError(t, err)
+	return dir
+}
+
+// testserver serves a fresh database on a loopback port until the test ends
+func testserver(t *testing.T) string {
+	dir := testdir(t)
+	db, err := kanthorkv.Open(dir, kanthorkv.DefaultOptions())
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := NewServer(db)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l) }()
+
+	t.Cleanup(func() {
+		require.NoError(t, srv.Close())
+		require.Error(t, <-done)
+		require.NoError(t, db.Close())
+		os.RemoveAll(dir)
+	})
+	return l.Addr().String()
+}
+
+// result is everything the server answered to one simple query
+type result struct {
+	columns []string
+	oids    []int
+	rows    [][]string
+	tags    []string
+	codes   []string
+	status  byte
+}
+
+// testclient is a bare bones frontend that only knows the simple query flow
+type testclient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func testconnect(t *testing.T, addr string) *testclient {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	c := &testclient{conn: conn, r: bufio.NewReader(conn)}
+
+	body := binary.BigEndian.AppendUint32(nil, PROTOCOL_VERSION)
+	body = append(body, "user\x00kanthorkv\x00database\x00test\x00\x00"...)
+	msg := binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))
+	_, err = conn.Write(append(msg, body...))
+	require.NoError(t, err)
+
+	res := c.receive(t)
+	require.Empty(t, res.codes)
+	require.Equal(t, byte(TX_IDLE), res.status)
+	return c
+}
+
+func (c *testclient) send(t *testing.T, typ byte, body []byte) {
+	msg := []byte{typ}
+	msg = binary.BigEndian.AppendUint32(msg, uint32(len(body)+4))
+	_, err := c.conn.Write(append(msg, body...))
+	require.NoError(t, err)
+}
+
+func (c *testclient) query(t *testing.T, sql string) *result {
+	c.send(t, 'Q', append([]byte(sql), 0))
+	return c.receive(t)
+}
+
+// receive reads messages up to the next ReadyForQuery.
+func (c *testclient) receive(t *testing.T) *result {
+	res := &result{}
+	for {
+		typ, body, err := readMessage(c.r)
+		require.NoError(t, err)
+
+		switch typ {
+		case 'T':
+			n := int(binary.BigEndian.Uint16(body))
+			body = body[2:]
+			for i := 0; i < n; i++ {
+				name := cstring(body)
+				body = body[len(name)+1:]
+				res.columns = append(res.columns, name)
+				res.oids = append(res.oids, int(binary.BigEndian.Uint32(body[6:])))
+				body = body[18:]
+			}
+		case 'D':
+			n := int(binary.BigEndian.Uint16(body))
+			body = body[2:]
+			row := make([]string, n)
+			for i := range row {
+				size := int(binary.BigEndian.Uint32(body))
+				row[i] = string(body[4 : 4+size])
+				body = body[4+size:]
+			}
+			res.rows = append(res.rows, row)
+		case 'C':
+			res.tags = append(res.tags, cstring(body))
+		case 'E':
+			for len(body) > 0 && body[0] != 0 {
+				field := cstring(body[1:])
+				if body[0] == 'C' {
+					res.codes = append(res.codes, field)
+				}
+				body = body[len(field)+2:]
+			}
+		case 'Z':
+			res.status = body[0]
+			return res
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package pgwire serves a KanthorKV database over a subset of the PostgreSQL v3 protocol,
+// enough for psql and client libraries that stick to the simple query flow.
+package pgwire
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kanthorlabs/kanthorkv"
+)
+
+// NewServer creates a server for db, it does not listen until Serve is called.
+func NewServer(db *kanthorkv.DB) *Server {
+	return &Server{
+		db:        db,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// Server accepts PostgreSQL clients, every connection is served by its own session.
+type Server struct {
+	db *kanthorkv.DB
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+
+	// pid identifies a session in BackendKeyData
+	pid atomic.Int32
+}
+
+// ListenAndServe listens on the TCP address addr and serves it until the server is closed.
+func (srv *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(l)
+}
+
+// Serve accepts connections on l until the server is closed.
+// It returns ErrServerClosed after Close.
+func (srv *Server) Serve(l net.Listener) error {
+	if !srv.track(l) {
+		l.Close()
+		return ErrServerClosed()
+	}
+	defer srv.untrack(l)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if srv.isClosed() {
+				return ErrServerClosed()
+			}
+			return err
+		}
+		if !srv.trackConn(conn) {
+			conn.Close()
+			return ErrServerClosed()
+		}
+
+		go func() {
+			defer srv.wg.Done()
+			defer srv.untrackConn(conn)
+
+			sess := newSession(srv.db, conn, int(srv.pid.Add(1)))
+			sess.serve()
+		}()
+	}
+}
+
+// Close stops the listeners, disconnects every client and waits for their sessions to end.
+// Open transactions of the clients are rolled back.
+func (srv *Server) Close() error {
+	srv.mu.Lock()
+	srv.closed = true
+	var errs []error
+	for l := range srv.listeners {
+		errs = append(errs, l.Close())
+	}
+	for conn := range srv.conns {
+		errs = append(errs, conn.Close())
+	}
+	srv.mu.Unlock()
+
+	srv.wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (srv *Server) isClosed() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.closed
+}
+
+func (srv *Server) track(l net.Listener) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.closed {
+		return false
+	}
+	srv.listeners[l] = struct{}{}
+	return true
+}
+
+func (srv *Server) untrack(l net.Listener) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	delete(srv.listeners, l)
+}
+
+func (srv *Server) trackConn(conn net.Conn) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.closed {
+		return false
+	}
+	srv.conns[conn] = struct{}{}
+	srv.wg.Add(1)
+	return true
+}
+
+func (srv *Server) untrackConn(conn net.Conn) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	delete(srv.conns, conn)
+	conn.Close()
+}
@@ -0,0 +1,102 @@
+package pgwire
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_SimpleQuery(t *testing.T) {
+	c := testconnect(t, testserver(t))
+
+	res := c.query(t, "create table student (sid int, sname varchar(10))")
+	require.Empty(t, res.codes)
+	require.Equal(t, []string{"CREATE TABLE"}, res.tags)
+
+	names := make([]string, 10)
+	for i := range names {
+		names[i] = fk.RandomStringWithLength(8)
+		res := c.query(t, fmt.Sprintf("insert into student (sid, sname) values (%d, '%s')", i, names[i]))
+		require.Empty(t, res.codes)
+		require.Equal(t, []string{"INSERT 0 1"}, res.tags)
+	}
+
+	res = c.query(t, "select sid, sname from student")
+	require.Empty(t, res.codes)
+	require.Equal(t, []string{"sid", "sname"}, res.columns)
+	require.Equal(t, []int{OID_INT4, OID_VARCHAR}, res.oids)
+	require.Len(t, res.rows, len(names))
+	for _, row := range res.rows {
+		sid, err := strconv.Atoi(row[0])
+		require.NoError(t, err)
+		require.Equal(t, names[sid], row[1])
+	}
+	require.Equal(t, []string{"SELECT 10"}, res.tags)
+	require.Equal(t, byte(TX_IDLE), res.status)
+
+	res = c.query(t, "update student set sname = 'x' where sid = 1; delete from student where sid = 2")
+	require.Empty(t, res.codes)
+	require.Equal(t, []string{"UPDATE 1", "DELETE 1"}, res.tags)
+}
+
+func TestServer_Errors(t *testing.T) {
+	c := testconnect(t, testserver(t))
+
+	res := c.query(t, "select from where")
+	require.Equal(t, []string{codeSyntaxError}, res.codes)
+	require.Equal(t, byte(TX_IDLE), res.status)
+
+	res = c.query(t, "select a from missing")
+	require.Equal(t, []string{codeUndefinedTable}, res.codes)
+
+	// the connection is still usable after an error
+	res = c.query(t, "create table t (a int)")
+	require.Empty(t, res.codes)
+
+	// extended query messages are rejected until the next Sync
+	c.send(t, 'P', []byte("\x00select a from t\x00\x00\x00"))
+	c.send(t, 'B', []byte("\x00\x00\x00\x00\x00\x00\x00\x00"))
+	c.send(t, 'S', nil)
+	res = c.receive(t)
+	require.Equal(t, []string{codeProtocolViolation}, res.codes)
+	require.Equal(t, byte(TX_IDLE), res.status)
+}
+
+func TestServer_Transaction(t *testing.T) {
+	addr := testserver(t)
+	c := testconnect(t, addr)
+
+	require.Empty(t, c.query(t, "create table t (a int)").codes)
+
+	res := c.query(t, "begin")
+	require.Equal(t, []string{"BEGIN"}, res.tags)
+	require.Equal(t, byte(TX_ACTIVE), res.status)
+	require.Empty(t, c.query(t, "insert into t (a) values (1)").codes)
+	res = c.query(t, "rollback")
+	require.Equal(t, []string{"ROLLBACK"}, res.tags)
+	require.Equal(t, byte(TX_IDLE), res.status)
+	require.Empty(t, c.query(t, "select a from t").rows)
+
+	require.Empty(t, c.query(t, "begin; insert into t (a) values (2); commit").codes)
+
+	// a failed statement aborts the whole block
+	require.Empty(t, c.query(t, "begin").codes)
+	res = c.query(t, "insert into t (a) values (3)")
+	require.Empty(t, res.codes)
+	res = c.query(t, "select nope from")
+	require.Equal(t, []string{codeSyntaxError}, res.codes)
+	require.Equal(t, byte(TX_FAILED), res.status)
+	res = c.query(t, "select a from t")
+	require.Equal(t, []string{codeInFailedTx}, res.codes)
+	res = c.query(t, "commit")
+	require.Equal(t, []string{"ROLLBACK"}, res.tags)
+	require.Equal(t, byte(TX_IDLE), res.status)
+
+	// every connection has a transaction of its own
+	other := testconnect(t, addr)
+	res = other.query(t, "select a from t")
+	require.Empty(t, res.codes)
+	require.Equal(t, [][]string{{"2"}}, res.rows)
+}
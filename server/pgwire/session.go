@@ -0,0 +1,355 @@
+package pgwire
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/kanthorlabs/kanthorkv"
+	"github.com/kanthorlabs/kanthorkv/parser"
+	"github.com/kanthorlabs/kanthorkv/record"
+	"github.com/kanthorlabs/kanthorkv/tx/transaction"
+)
+
+// SERVER_VERSION is what clients are told they are talking to, psql adapts its queries to it
+const SERVER_VERSION = "14.0 (KanthorKV)"
+
+func newSession(db *kanthorkv.DB, conn net.Conn, pid int) *session {
+	return &session{
+		db:   db,
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+		pid:  pid,
+	}
+}
+
+// session is one client connection.
+// Statements outside of BEGIN ... COMMIT run in a transaction of their own,
+// inside a block they share the transaction of the session.
+type session struct {
+	db   *kanthorkv.DB
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	pid  int
+
+	tx transaction.Transaction
+	// failed is set when a statement of an explicit transaction fails,
+	// the transaction is already rolled back but the client has not ended the block yet
+	failed bool
+}
+
+func (sess *session) serve() {
+	// an interrupted client must not keep its locks
+	defer sess.rollback()
+
+	if err := sess.startup(); err != nil {
+		return
+	}
+
+	// after an error in an extended query, messages are discarded until the next Sync
+	skipping := false
+	for {
+		typ, body, err := readMessage(sess.r)
+		if err != nil {
+			return
+		}
+
+		switch typ {
+		case 'X':
+			return
+		case 'Q':
+			sess.simpleQuery(cstring(body))
+			err = sess.ready()
+		case 'S':
+			skipping = false
+			err = sess.ready()
+		case 'H':
+			err = sess.w.Flush()
+		default:
+			if skipping {
+				continue
+			}
+			skipping = true
+			err = sess.sendError(ErrUnsupportedMessage(typ))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (sess *session) startup() error {
+	for {
+		version, body, err := readStartup(sess.r)
+		if err != nil {
+			return err
+		}
+
+		switch version {
+		case SSL_REQUEST, GSSENC_REQUEST:
+			// encryption is not supported, the client may go on in plain text
+			if _, err := sess.conn.Write([]byte{'N'}); err != nil {
+				return err
+			}
+			continue
+		case CANCEL_REQUEST:
+			return errors.New("cancel request")
+		case PROTOCOL_VERSION:
+		default:
+			err := ErrUnsupportedProtocol(version)
+			return errors.Join(err, sess.sendError(err))
+		}
+
+		params := parseParams(body)
+		if err := newMessage('R').int32(0).writeTo(sess.w); err != nil {
+			return err
+		}
+		status := [][2]string{
+			{"server_version", SERVER_VERSION},
+			{"server_encoding", "UTF8"},
+			{"client_encoding", "UTF8"},
+			{"DateStyle", "ISO, MDY"},
+			{"integer_datetimes", "on"},
+			{"standard_conforming_strings", "on"},
+			{"application_name", params["application_name"]},
+		}
+		for _, kv := range status {
+			if err := newMessage('S').cstring(kv[0]).cstring(kv[1]).writeTo(sess.w); err != nil {
+				return err
+			}
+		}
+		if err := newMessage('K').int32(sess.pid).int32(0).writeTo(sess.w); err != nil {
+			return err
+		}
+		return sess.ready()
+	}
+}
+
+// simpleQuery runs every statement of a simple query, the first failure skips the rest.
+func (sess *session) simpleQuery(text string) {
+	stmts, rest := parser.SplitStatements(text)
+	if rest := strings.TrimSpace(rest); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	if len(stmts) == 0 {
+		newMessage('I').writeTo(sess.w)
+		return
+	}
+
+	for _, stmt := range stmts {
+		if err := sess.execute(stmt); err != nil {
+			sess.sendError(err)
+			return
+		}
+	}
+}
+
+func (sess *session) execute(sql string) error {
+	switch command(sql) {
+	case "BEGIN", "START":
+		if sess.tx != nil || sess.failed {
+			return ErrTxInProgress()
+		}
+		t, err := sess.db.NewTx()
+		if err != nil {
+			return err
+		}
+		sess.tx = t
+		return sess.complete("BEGIN")
+	case "COMMIT", "END":
+		if sess.failed {
+			sess.failed = false
+			return sess.complete("ROLLBACK")
+		}
+		if sess.tx != nil {
+			t := sess.tx
+			sess.tx = nil
+			if err := t.Commit(); err != nil {
+				return err
+			}
+		}
+		return sess.complete("COMMIT")
+	case "ROLLBACK", "ABORT":
+		sess.failed = false
+		if err := sess.rollback(); err != nil {
+			return err
+		}
+		return sess.complete("ROLLBACK")
+	}
+
+	if sess.failed {
+		return ErrTxAborted()
+	}
+
+	if sess.tx != nil {
+		if err := sess.run(sess.tx, sql); err != nil {
+			// the transaction cannot be trusted after a failure, let go of its locks right away
+			sess.failed = true
+			return errors.Join(err, sess.rollback())
+		}
+		return nil
+	}
+
+	t, err := sess.db.NewTx()
+	if err != nil {
+		return err
+	}
+	if err := sess.run(t, sql); err != nil {
+		return errors.Join(err, t.Rollback())
+	}
+	return t.Commit()
+}
+
+// run executes a statement and sends its result, a panic of the engine is reported as an error.
+func (sess *session) run(t transaction.Transaction, sql string) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = ErrPanic(v)
+		}
+	}()
+
+	if parser.IsQuery(sql) {
+		return sess.query(t, sql)
+	}
+
+	affected, err := sess.db.Exec(t, sql)
+	if err != nil {
+		return err
+	}
+	return sess.complete(tag(sql, affected))
+}
+
+func (sess *session) query(t transaction.Transaction, sql string) error {
+	p, err := sess.db.Planner().CreateQueryPlan(sql, t)
+	if err != nil {
+		return err
+	}
+	s, err := p.Open()
+	if err != nil {
+		return err
+	}
+
+	// rows are collected before anything is sent so a failing scan ends up as a clean ErrorResponse
+	sch := p.Schema()
+	rows := make([]*message, 0)
+	for s.Next() {
+		row := newMessage('D').int16(len(sch.Fields()))
+		for _, fldname := range sch.Fields() {
+			val, err := s.GetVal(fldname)
+			if err != nil {
+				return errors.Join(err, s.Close())
+			}
+			row.bytes(text(val, sch.Type(fldname)))
+		}
+		rows = append(rows, row)
+	}
+	// a scan that stopped on an error returns it when closed
+	if err := s.Close(); err != nil {
+		return err
+	}
+
+	if err := describe(sch).writeTo(sess.w); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := row.writeTo(sess.w); err != nil {
+			return err
+		}
+	}
+	return sess.complete(fmt.Sprintf("SELECT %d", len(rows)))
+}
+
+func (sess *session) rollback() error {
+	if sess.tx == nil {
+		return nil
+	}
+	t := sess.tx
+	sess.tx = nil
+	return t.Rollback()
+}
+
+func (sess *session) complete(tag string) error {
+	return newMessage('C').cstring(tag).writeTo(sess.w)
+}
+
+func (sess *session) ready() error {
+	status := byte(TX_IDLE)
+	if sess.failed {
+		status = TX_FAILED
+	} else if sess.tx != nil {
+		status = TX_ACTIVE
+	}
+	if err := newMessage('Z').byte(status).writeTo(sess.w); err != nil {
+		return err
+	}
+	return sess.w.Flush()
+}
+
+func (sess *session) sendError(err error) error {
+	msg := newMessage('E').
+		byte('S').cstring("ERROR").
+		byte('V').cstring("ERROR").
+		byte('C').cstring(sqlstate(err)).
+		byte('M').cstring(err.Error()).
+		byte(0)
+	if err := msg.writeTo(sess.w); err != nil {
+		return err
+	}
+	return sess.w.Flush()
+}
+
+// describe builds the RowDescription of a schema, every field is sent in text format.
+func describe(sch *record.Schema) *message {
+	msg := newMessage('T').int16(len(sch.Fields()))
+	for _, fldname := range sch.Fields() {
+		msg.cstring(fldname).int32(0).int16(0)
+		if sch.Type(fldname) == record.IntegerField {
+			msg.int32(OID_INT4).int16(4).int32(-1)
+		} else {
+			// the type modifier of varchar(n) is n plus the size of the length header
+			msg.int32(OID_VARCHAR).int16(-1).int32(sch.Length(fldname) + 4)
+		}
+		msg.int16(0)
+	}
+	return msg
+}
+
+func text(val record.Constant, typ record.FieldType) []byte {
+	if val.IsNull() {
+		return nil
+	}
+	if typ == record.IntegerField {
+		return []byte(strconv.Itoa(val.AsInt()))
+	}
+	return []byte(val.AsString())
+}
+
+// command returns the upper cased first keyword of a statement.
+func command(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// tag is the CommandComplete tag of an update statement.
+func tag(sql string, affected int) string {
+	fields := strings.Fields(strings.ToUpper(sql))
+	switch fields[0] {
+	case "INSERT":
+		return fmt.Sprintf("INSERT 0 %d", affected)
+	case "UPDATE", "DELETE":
+		return fmt.Sprintf("%s %d", fields[0], affected)
+	case "CREATE":
+		if len(fields) > 1 {
+			return "CREATE " + fields[1]
+		}
+	}
+	return fields[0]
+}
@@ -7,21 +7,23 @@ import (
 
 func NewBufferList(bm buffer.BufferManager) *BufferList {
 	return &BufferList{
-		buffers: make(map[*file.BlockId]*buffer.Buffer),
-		pins:    make(map[*file.BlockId]int),
+		buffers: make(map[file.BlockId]*buffer.Buffer),
+		pins:    make(map[file.BlockId]int),
 		bm:      bm,
 	}
 }
 
+// BufferList keys blocks by value, so callers may use different *file.BlockId
+// for the same block when they pin, read and unpin it.
 type BufferList struct {
-	buffers map[*file.BlockId]*buffer.Buffer
+	buffers map[file.BlockId]*buffer.Buffer
 	// keep track of how many times each block has been pinned
-	pins map[*file.BlockId]int
+	pins map[file.BlockId]int
 	bm   buffer.BufferManager
 }
 
 func (bl *BufferList) Get(blk *file.BlockId) (*buffer.Buffer, bool) {
-	buf, exists := bl.buffers[blk]
+	buf, exists := bl.buffers[*blk]
 	return buf, exists
 }
 
@@ -31,29 +33,34 @@ func (bl *BufferList) Pin(blk *file.BlockId) error {
 		return err
 	}
 
-	bl.buffers[blk] = b
-	bl.pins[blk]++
+	bl.buffers[*blk] = b
+	bl.pins[*blk]++
 	return nil
 }
 
+// Unpin releases one pin of the block, the buffer stays reachable while other pins remain.
 func (bl *BufferList) Unpin(blk *file.BlockId) error {
-	b, exists := bl.buffers[blk]
+	b, exists := bl.buffers[*blk]
 	if !exists {
 		return nil
 	}
 	bl.bm.Unpin(b)
-	delete(bl.pins, blk)
-	// remove pins
-	delete(bl.buffers, blk)
+	bl.pins[*blk]--
+	if bl.pins[*blk] <= 0 {
+		delete(bl.pins, *blk)
+		delete(bl.buffers, *blk)
+	}
 	return nil
 }
 
 func (bl *BufferList) UnpinAll() {
-	for blk := range bl.pins {
+	for blk, count := range bl.pins {
 		b := bl.buffers[blk]
-		bl.bm.Unpin(b)
+		for range count {
+			bl.bm.Unpin(b)
+		}
 	}
 
-	bl.buffers = make(map[*file.BlockId]*buffer.Buffer)
-	bl.pins = make(map[*file.BlockId]int)
+	bl.buffers = make(map[file.BlockId]*buffer.Buffer)
+	bl.pins = make(map[file.BlockId]int)
 }
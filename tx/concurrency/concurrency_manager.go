@@ -14,46 +14,48 @@ const (
 func NewConcurrencyManager(lt *LockTable) *ConcurrencyManager {
 	return &ConcurrencyManager{
 		lt:    lt,
-		locks: make(map[*file.BlockId]LockType),
+		locks: make(map[file.BlockId]LockType),
 	}
 }
 
 type ConcurrencyManager struct {
 	lt    *LockTable
-	locks map[*file.BlockId]LockType
+	locks map[file.BlockId]LockType
 }
 
 func (cm *ConcurrencyManager) SLock(blk *file.BlockId) error {
-	if _, exist := cm.locks[blk]; exist {
+	if _, exist := cm.locks[*blk]; exist {
 		return nil
 	}
 
 	if err := cm.lt.SLock(blk); err != nil {
 		return err
 	}
-	cm.locks[blk] = SharedLock
+	cm.locks[*blk] = SharedLock
 	return nil
 }
 
 func (cm *ConcurrencyManager) XLock(blk *file.BlockId) error {
-	if lock, exist := cm.locks[blk]; exist && lock == ExclusiveLock {
+	if lock, exist := cm.locks[*blk]; exist && lock == ExclusiveLock {
 		return nil
 	}
 
-	// Obtain shared lock first
-	if err := cm.lt.SLock(blk); err != nil {
+	// Obtain shared lock first, unless this transaction already holds it;
+	// otherwise the lock table sees our own shared lock as another reader
+	if err := cm.SLock(blk); err != nil {
 		return err
 	}
 	// Upgrade to exclusive lock
 	if err := cm.lt.XLock(blk); err != nil {
 		return err
 	}
-	cm.locks[blk] = ExclusiveLock
+	cm.locks[*blk] = ExclusiveLock
 	return nil
 }
 
 func (cm *ConcurrencyManager) Release() {
 	for blk := range cm.locks {
-		cm.lt.Unlock(blk)
+		cm.lt.Unlock(&blk)
 	}
+	cm.locks = make(map[file.BlockId]LockType)
 }
@@ -51,3 +51,18 @@ func TestConcurrencyManager_XLock(t *testing.T) {
 		cm.Release()
 	})
 }
+
+func TestConcurrencyManager_SameBlock(t *testing.T) {
+	lt := NewLockTable()
+	cm := NewConcurrencyManager(lt)
+
+	dir := testdir(t)
+
+	// two instances of a block share its lock, the upgrade reuses the shared lock taken through the first one
+	require.NoError(t, cm.SLock(file.NewBlockId(dir+"/0", 0)))
+	require.NoError(t, cm.XLock(file.NewBlockId(dir+"/0", 0)))
+	require.Equal(t, -1, lt.locks[*file.NewBlockId(dir+"/0", 0)])
+
+	cm.Release()
+	require.Empty(t, lt.locks)
+}
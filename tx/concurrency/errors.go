@@ -1,6 +1,7 @@
 package concurrency
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -13,9 +14,17 @@ func Errf(err string, args ...string) error {
 	return fmt.Errorf("%s.%s: %s", basename, err, strings.Join(args, " | "))
 }
 
+// Wrapf describes a sentinel error with args, errors.Is still finds the sentinel.
+func Wrapf(sentinel error, args ...string) error {
+	return fmt.Errorf("%w: %s", sentinel, strings.Join(args, " | "))
+}
+
+// ErrLockAborted is a lock request that was aborted after waiting too long.
+var ErrLockAborted = errors.New(basename + ".LOCK.ABORT")
+
 func ErrLockAbort(blk *file.BlockId) error {
 	args := []string{
 		fmt.Sprintf("blk=%s", blk.String()),
 	}
-	return Errf("LOCK.ABORT: ", args...)
+	return Wrapf(ErrLockAborted, args...)
 }
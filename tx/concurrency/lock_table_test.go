@@ -77,7 +77,7 @@ func TestLockTable_SLock(t *testing.T) {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				require.ErrorContains(t, lt.SLock(blk), "LOCK.ABORT")
+				require.ErrorIs(t, lt.SLock(blk), ErrLockAborted)
 			}()
 		}
 		wg.Wait()
@@ -128,6 +128,6 @@ func TestLockTable_XLock(t *testing.T) {
 		// acquire 2 SLocks and then XLock
 		require.NoError(t, lt.SLock(blk))
 		require.NoError(t, lt.SLock(blk))
-		require.ErrorContains(t, lt.XLock(blk), "LOCK.ABORT")
+		require.ErrorIs(t, lt.XLock(blk), ErrLockAborted)
 	})
 }
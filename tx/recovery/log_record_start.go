@@ -37,9 +37,10 @@ func (lr *LogRecordStart) String() string {
 	return fmt.Sprintf("<START %d>", lr.txnum)
 }
 
-func WriteStartLogRecord(lm log.LogManager) (int, error) {
-	rec := make([]byte, file.INT_SIZE)
+func WriteStartLogRecord(lm log.LogManager, txnum int) (int, error) {
+	rec := make([]byte, file.INT_SIZE*2)
 	p := file.NewPageWithBuffer(rec)
 	p.SetInt(0, int(OpStart))
+	p.SetInt(file.INT_SIZE, txnum)
 	return lm.Append(rec)
 }
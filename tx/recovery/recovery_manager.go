@@ -43,6 +43,9 @@ func (rm *localrm) Rollback() error {
 	if err := rm.rollback(); err != nil {
 		return err
 	}
+	if err := rm.bm.FlushAll(rm.txnum); err != nil {
+		return err
+	}
 	lsn, err := WriteRollbackLogRecord(rm.lm, rm.txnum)
 	if err != nil {
 		return err
@@ -81,6 +84,10 @@ func (rm *localrm) Recover() error {
 	if err := rm.recover(); err != nil {
 		return err
 	}
+	// the undone values must reach the disk before the checkpoint says so
+	if err := rm.bm.FlushAll(rm.txnum); err != nil {
+		return err
+	}
 	lsn, err := WriteCheckpointLogRecord(rm.lm, rm.txnum)
 	if err != nil {
 		return err
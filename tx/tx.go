@@ -23,13 +23,14 @@ func NewTransaction(fm file.FileManager, lm log.LogManager, bm buffer.BufferMana
 		bl:    NewBufferList(bm),
 	}
 	tx.rm = recovery.NewRecoveryManager(lm, bm, &tx, tx.txnum)
+	// the start record bounds how far a rollback has to read the log
+	if _, err := recovery.WriteStartLogRecord(lm, tx.txnum); err != nil {
+		return nil, err
+	}
 	return &tx, nil
 }
 
-var (
-	nextTxNum atomic.Uint32
-	endofFile = -1
-)
+var nextTxNum atomic.Uint32
 
 type txn struct {
 	fm    file.FileManager
@@ -158,7 +159,7 @@ func (tx *txn) AvailableBuffs() int {
 // file manager
 
 func (tx *txn) Size(filename string) (int, error) {
-	dummy := file.NewBlockId(filename, endofFile)
+	dummy := file.NewBlockId(filename, file.END_OF_FILE)
 	if err := tx.cm.SLock(dummy); err != nil {
 		return 0, err
 	}
@@ -166,7 +167,7 @@ func (tx *txn) Size(filename string) (int, error) {
 }
 
 func (tx *txn) Append(filename string) (*file.BlockId, error) {
-	dummy := file.NewBlockId(filename, endofFile)
+	dummy := file.NewBlockId(filename, file.END_OF_FILE)
 	if err := tx.cm.XLock(dummy); err != nil {
 		return nil, err
 	}